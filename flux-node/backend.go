@@ -18,6 +18,16 @@ type (
 		GetResponseCodecFunc() BackendResponseCodecFunc
 	}
 
+	// BackendServiceEventListener 接收后端服务的变更事件；用于维护与服务定义相关的资源，例如RPC引用
+	BackendServiceEventListener interface {
+		OnBackendServiceEvent(event BackendServiceEvent)
+	}
+
+	// DiscoveryReadyListener 接收Discovery初始元数据已分发完成的通知；用于在初始服务事件处理完成前报告未就绪
+	DiscoveryReadyListener interface {
+		OnDiscoveryReady()
+	}
+
 	// BackendResponse 后端服务返回统一响应数据结构
 	BackendResponse struct {
 		// Http状态码
@@ -34,6 +34,28 @@ func (b BodyValues) ReadBodyValue(bodyKey string) interface{} {
 	}
 }
 
+// hasAnyKey 判断是否包含任意一个指定的字段
+func (b BodyValues) hasAnyKey(keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := b[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutKeys 返回移除指定字段后的副本
+func (b BodyValues) withoutKeys(keys ...string) BodyValues {
+	out := make(BodyValues, len(b))
+	for k, v := range b {
+		out[k] = v
+	}
+	for _, key := range keys {
+		delete(out, key)
+	}
+	return out
+}
+
 func (b BodyValues) ReadStatusValue(statusKey string) (int, error) {
 	if status, ok := b[statusKey]; ok {
 		if code, err := cast.ToIntE(status); nil != err {
@@ -79,6 +101,10 @@ func _addToHeader(headers http.Header, key string, v interface{}) {
 		for _, iv := range sa {
 			headers.Add(key, iv)
 		}
+	} else if ia, ok := v.([]interface{}); ok {
+		for _, iv := range ia {
+			headers.Add(key, cast.ToString(iv))
+		}
 	} else {
 		headers.Add(key, cast.ToString(v))
 	}
@@ -3,15 +3,22 @@ package dubbo
 import (
 	"github.com/apache/dubbo-go/protocol"
 	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/spf13/cast"
 	"net/http"
+	"strings"
 )
 
 const (
 	ResponseKeyStatusCode = "@net.bytepowered.flux.http-status"
 	ResponseKeyHeaders    = "@net.bytepowered.flux.http-headers"
+	ResponseKeyBody       = "@net.bytepowered.flux.http-body"
 )
 
+// NewBackendResponseCodecFuncWith 解析Dubbo响应结果：
+// 1. Attachment中的 codeKey 为响应状态码，headerKey 为响应Header（JSON对象，或者每行一个 Name: Value）；
+// 2. 响应Body为Map并且包含 codeKey/headerKey 字段时，从Body中读取状态码和Header，并以 ResponseKeyBody 字段作为响应Body；
+// 没有 ResponseKeyBody 字段时，移除状态码和Header字段后的Map作为响应Body；Body中的定义优先于Attachment；
 func NewBackendResponseCodecFuncWith(codeKey, headerKey string) flux.BackendResponseCodecFunc {
 	return func(ctx flux.Context, raw interface{}) (*flux.BackendResponse, error) {
 		// 支持Dubbo返回Result类型
@@ -27,17 +34,42 @@ func NewBackendResponseCodecFuncWith(codeKey, headerKey string) flux.BackendResp
 		}
 		data := rpcr.Result()
 		status := flux.StatusOK
+		headers := make(http.Header, 0)
 		for k, v := range rpcr.Attachments() {
 			if k == codeKey {
-				status = cast.ToInt(v)
+				code, err := cast.ToIntE(v)
+				if nil != err {
+					logger.Warnw("Invalid rpc response status attachment", "status", v)
+					return nil, ErrDecodeInvalidStatus
+				}
+				status = code
 			} else if k == headerKey {
-				// TODO 需要更新Attachment类型为map[string]interface{}
+				header, err := ParseHeaderAttachment(v)
+				if nil != err {
+					return nil, err
+				}
+				mergeHeader(headers, header)
 			} else {
 				attrs[k] = v
 			}
 		}
+		if bv, ok := WrapBodyValues(data); ok && bv.hasAnyKey(codeKey, headerKey) {
+			if _, ok := bv[codeKey]; ok {
+				code, err := bv.ReadStatusValue(codeKey)
+				if nil != err {
+					return nil, err
+				}
+				status = code
+			}
+			header, err := bv.ReadHeaderValue(headerKey)
+			if nil != err {
+				return nil, err
+			}
+			mergeHeader(headers, header)
+			data = bv.withoutKeys(codeKey, headerKey).ReadBodyValue(ResponseKeyBody)
+		}
 		return &flux.BackendResponse{
-			StatusCode: status, Headers: make(http.Header, 0), Attachments: attrs, Body: data,
+			StatusCode: status, Headers: headers, Attachments: attrs, Body: UnwrapBodyValues(data),
 		}, nil
 	}
 }
@@ -45,3 +77,64 @@ func NewBackendResponseCodecFuncWith(codeKey, headerKey string) flux.BackendResp
 func NewBackendResponseCodecFunc() flux.BackendResponseCodecFunc {
 	return NewBackendResponseCodecFuncWith(ResponseKeyStatusCode, ResponseKeyHeaders)
 }
+
+// ParseHeaderAttachment 解析Attachment中的响应Header：JSON对象（值为字符串或者字符串列表），或者每行一个 Name: Value
+func ParseHeaderAttachment(value string) (http.Header, error) {
+	value = strings.TrimSpace(value)
+	if "" == value {
+		return make(http.Header), nil
+	}
+	if strings.HasPrefix(value, "{") {
+		var values map[string]interface{}
+		if err := _json.UnmarshalFromString(value, &values); nil != err {
+			logger.Warnw("Invalid rpc response headers attachment", "value", value, "error", err)
+			return nil, ErrDecodeInvalidHeaders
+		}
+		out := make(http.Header, len(values))
+		for k, v := range values {
+			if vs, ok := v.([]interface{}); ok {
+				for _, iv := range vs {
+					out.Add(k, cast.ToString(iv))
+				}
+			} else {
+				out.Add(k, cast.ToString(v))
+			}
+		}
+		return out, nil
+	}
+	out := make(http.Header)
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); "" == line {
+			continue
+		}
+		pair := strings.SplitN(line, ":", 2)
+		if len(pair) != 2 || "" == strings.TrimSpace(pair[0]) {
+			logger.Warnw("Invalid rpc response headers attachment", "value", value)
+			return nil, ErrDecodeInvalidHeaders
+		}
+		out.Add(strings.TrimSpace(pair[0]), strings.TrimSpace(pair[1]))
+	}
+	return out, nil
+}
+
+// FilterResponseHeader 只保留允许列表中的响应Header；允许列表为空时，保留全部Header
+func FilterResponseHeader(header http.Header, allow []string) http.Header {
+	if len(allow) == 0 {
+		return header
+	}
+	out := make(http.Header, len(allow))
+	for _, name := range allow {
+		key := http.CanonicalHeaderKey(name)
+		if vs, ok := header[key]; ok {
+			out[key] = vs
+		}
+	}
+	return out
+}
+
+func mergeHeader(dst http.Header, src http.Header) {
+	for k, vs := range src {
+		key := http.CanonicalHeaderKey(k)
+		dst[key] = append(dst[key], vs...)
+	}
+}
@@ -0,0 +1,179 @@
+package dubbo
+
+import (
+	"errors"
+	"github.com/apache/dubbo-go/protocol"
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestBackendResponseCodecFunc(t *testing.T) {
+	codec := NewBackendResponseCodecFunc()
+	cases := []struct {
+		name    string
+		raw     interface{}
+		status  int
+		headers http.Header
+		body    interface{}
+		attrs   map[string]interface{}
+		err     error
+	}{
+		{
+			name:    "raw value",
+			raw:     "hello",
+			status:  flux.StatusOK,
+			headers: http.Header{},
+			body:    "hello",
+		},
+		{
+			name: "attachments",
+			raw: &protocol.RPCResult{
+				Attrs: map[string]string{
+					ResponseKeyStatusCode: "201",
+					ResponseKeyHeaders:    `{"Cache-Control":"no-cache","set-cookie":["a=1","b=2"]}`,
+					"trace":               "t1",
+				},
+				Rest: "created",
+			},
+			status: 201,
+			headers: http.Header{
+				"Cache-Control": []string{"no-cache"},
+				"Set-Cookie":    []string{"a=1", "b=2"},
+			},
+			body:  "created",
+			attrs: map[string]interface{}{"trace": "t1"},
+		},
+		{
+			name: "attachment header lines",
+			raw: &protocol.RPCResult{
+				Attrs: map[string]string{
+					ResponseKeyHeaders: "X-Total: 10\nSet-Cookie: a=1\nSet-Cookie: b=2",
+				},
+				Rest: "ok",
+			},
+			status: flux.StatusOK,
+			headers: http.Header{
+				"X-Total":    []string{"10"},
+				"Set-Cookie": []string{"a=1", "b=2"},
+			},
+			body:  "ok",
+			attrs: map[string]interface{}{},
+		},
+		{
+			name: "body fields",
+			raw: &protocol.RPCResult{
+				Rest: map[interface{}]interface{}{
+					ResponseKeyStatusCode: int32(302),
+					ResponseKeyHeaders: map[interface{}]interface{}{
+						"Location":   "/login",
+						"Set-Cookie": []interface{}{"a=1", "b=2"},
+					},
+					ResponseKeyBody: "redirect",
+				},
+			},
+			status: 302,
+			headers: http.Header{
+				"Location":   []string{"/login"},
+				"Set-Cookie": []string{"a=1", "b=2"},
+			},
+			body:  "redirect",
+			attrs: map[string]interface{}{},
+		},
+		{
+			name: "body fields without body key",
+			raw: &protocol.RPCResult{
+				Attrs: map[string]string{ResponseKeyStatusCode: "500"},
+				Rest: map[interface{}]interface{}{
+					ResponseKeyStatusCode: "200",
+					ResponseKeyHeaders:    map[string]interface{}{"X-Name": "flux"},
+					"id":                  1,
+				},
+			},
+			status:  200,
+			headers: http.Header{"X-Name": []string{"flux"}},
+			body:    map[interface{}]interface{}{"id": 1},
+			attrs:   map[string]interface{}{},
+		},
+		{
+			name: "plain body map",
+			raw: &protocol.RPCResult{
+				Rest: map[interface{}]interface{}{"id": 1},
+			},
+			status:  flux.StatusOK,
+			headers: http.Header{},
+			body:    map[interface{}]interface{}{"id": 1},
+			attrs:   map[string]interface{}{},
+		},
+		{
+			name: "invalid header attachment",
+			raw: &protocol.RPCResult{
+				Attrs: map[string]string{ResponseKeyHeaders: "invalid-header"},
+			},
+			err: ErrDecodeInvalidHeaders,
+		},
+		{
+			name: "invalid status attachment",
+			raw: &protocol.RPCResult{
+				Attrs: map[string]string{ResponseKeyStatusCode: "abc"},
+			},
+			err: ErrDecodeInvalidStatus,
+		},
+		{
+			name: "invalid body headers",
+			raw: &protocol.RPCResult{
+				Rest: map[interface{}]interface{}{ResponseKeyHeaders: "abc"},
+			},
+			err: ErrDecodeInvalidHeaders,
+		},
+		{
+			name: "rpc error",
+			raw:  &protocol.RPCResult{Err: errors.New("rpc error")},
+			err:  errors.New("rpc error"),
+		},
+	}
+	assert := assert2.New(t)
+	for _, tc := range cases {
+		resp, err := codec(nil, tc.raw)
+		if nil != tc.err {
+			assert.Equal(tc.err, err, tc.name)
+			continue
+		}
+		assert.NoError(err, tc.name)
+		assert.Equal(tc.status, resp.StatusCode, tc.name)
+		assert.Equal(tc.headers, resp.Headers, tc.name)
+		assert.Equal(tc.body, resp.Body, tc.name)
+		if nil != tc.attrs {
+			assert.Equal(tc.attrs, resp.Attachments, tc.name)
+		}
+	}
+}
+
+func TestFilterResponseHeader(t *testing.T) {
+	header := http.Header{
+		"Set-Cookie":    []string{"a=1"},
+		"Cache-Control": []string{"no-cache"},
+		"X-Internal":    []string{"secret"},
+	}
+	cases := []struct {
+		allow    []string
+		expected http.Header
+	}{
+		{
+			allow:    nil,
+			expected: header,
+		},
+		{
+			allow: []string{"set-cookie", "cache-control", "x-missing"},
+			expected: http.Header{
+				"Set-Cookie":    []string{"a=1"},
+				"Cache-Control": []string{"no-cache"},
+			},
+		},
+	}
+	assert := assert2.New(t)
+	for _, tc := range cases {
+		assert.Equal(tc.expected, FilterResponseHeader(header, tc.allow))
+	}
+}
@@ -0,0 +1,51 @@
+package dubbo
+
+import (
+	"sync"
+)
+
+// flightGroup 合并相同Key的并发调用：同一个Key同时只执行一次，其它调用等待并共享执行结果；
+// 不同Key之间互不阻塞；
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done   chan struct{}
+	value  interface{}
+	panics interface{}
+}
+
+// Do 执行Key对应的函数；相同Key正在执行时，等待其完成并返回其结果；执行函数Panic时，所有等待的调用也将Panic
+func (g *flightGroup) Do(key string, fn func() interface{}) interface{} {
+	g.mutex.Lock()
+	if nil == g.calls {
+		g.calls = make(map[string]*flightCall, 4)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mutex.Unlock()
+		<-call.done
+		if nil != call.panics {
+			panic(call.panics)
+		}
+		return call.value
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mutex.Unlock()
+	defer func() {
+		if r := recover(); nil != r {
+			call.panics = r
+		}
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(call.done)
+		if nil != call.panics {
+			panic(call.panics)
+		}
+	}()
+	call.value = fn()
+	return call.value
+}
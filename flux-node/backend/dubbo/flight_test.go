@@ -0,0 +1,71 @@
+package dubbo
+
+import (
+	assert2 "github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroup_SameKey(t *testing.T) {
+	assert := assert2.New(t)
+	var group flightGroup
+	var calls int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([]interface{}, 8)
+	for i := 0; i < len(results); i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			results[idx] = group.Do("key", func() interface{} {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "value"
+			})
+		}(i)
+	}
+	time.Sleep(time.Millisecond * 20)
+	close(release)
+	wg.Wait()
+	assert.Equal(int32(1), calls)
+	for _, v := range results {
+		assert.Equal("value", v)
+	}
+}
+
+func TestFlightGroup_DifferentKeys(t *testing.T) {
+	assert := assert2.New(t)
+	var group flightGroup
+	release := make(chan struct{})
+	defer close(release)
+	go group.Do("slow", func() interface{} {
+		<-release
+		return nil
+	})
+	done := make(chan interface{})
+	go func() {
+		done <- group.Do("fast", func() interface{} {
+			return "fast"
+		})
+	}()
+	select {
+	case v := <-done:
+		assert.Equal("fast", v)
+	case <-time.After(time.Second):
+		assert.Fail("blocked by another key")
+	}
+}
+
+func TestFlightGroup_Panic(t *testing.T) {
+	var group flightGroup
+	assert2.Panics(t, func() {
+		group.Do("key", func() interface{} {
+			panic("failed")
+		})
+	})
+	assert2.Equal(t, "next", group.Do("key", func() interface{} {
+		return "next"
+	}))
+}
@@ -0,0 +1,87 @@
+package dubbo
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"sync"
+	"sync/atomic"
+)
+
+// ReferencePreloader 在服务注册时预先创建Reference，避免首次请求时创建Reference的延迟；
+// 同一个Reference（Interface+Group+Version）同时只预加载一次：预加载期间提交的服务，只保留最新的一个，
+// 在当前预加载完成后重新执行；并限制同时创建Reference的数量；
+type ReferencePreloader struct {
+	load     func(service *flux.BackendService)
+	tokens   chan struct{}
+	pending  int64
+	mutex    sync.Mutex
+	inflight map[string]struct{}
+	queued   map[string]flux.BackendService
+	group    sync.WaitGroup
+}
+
+// NewReferencePreloader 创建ReferencePreloader；concurrency 为同时创建Reference的最大数量
+func NewReferencePreloader(load func(service *flux.BackendService), concurrency int) *ReferencePreloader {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &ReferencePreloader{
+		load:     load,
+		tokens:   make(chan struct{}, concurrency),
+		inflight: make(map[string]struct{}, 16),
+		queued:   make(map[string]flux.BackendService, 4),
+	}
+}
+
+// Submit 提交预加载服务的Reference；相同Reference正在预加载时，记录最新的服务，在预加载完成后重新执行
+func (p *ReferencePreloader) Submit(service flux.BackendService) {
+	key := ReferenceKey(&service)
+	p.mutex.Lock()
+	if _, ok := p.inflight[key]; ok {
+		p.queued[key] = service
+		p.mutex.Unlock()
+		return
+	}
+	p.inflight[key] = struct{}{}
+	p.mutex.Unlock()
+	atomic.AddInt64(&p.pending, 1)
+	p.group.Add(1)
+	go func() {
+		defer p.group.Done()
+		for {
+			p.run(key, &service)
+			p.mutex.Lock()
+			next, ok := p.queued[key]
+			if !ok {
+				delete(p.inflight, key)
+				p.mutex.Unlock()
+				atomic.AddInt64(&p.pending, -1)
+				return
+			}
+			delete(p.queued, key)
+			p.mutex.Unlock()
+			service = next
+		}
+	}()
+}
+
+func (p *ReferencePreloader) run(key string, service *flux.BackendService) {
+	p.tokens <- struct{}{}
+	defer func() {
+		if r := recover(); nil != r {
+			logger.Errorw("DUBBO:REFERENCE:PRELOAD", "key", key, "error", r)
+		}
+		<-p.tokens
+	}()
+	p.load(service)
+}
+
+// Pending 返回未完成预加载的Reference数量
+func (p *ReferencePreloader) Pending() int {
+	return int(atomic.LoadInt64(&p.pending))
+}
+
+// Wait 等待已提交的预加载全部完成
+func (p *ReferencePreloader) Wait() {
+	p.group.Wait()
+}
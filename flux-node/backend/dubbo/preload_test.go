@@ -0,0 +1,102 @@
+package dubbo
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReferencePreloader_Concurrency(t *testing.T) {
+	assert := assert2.New(t)
+	var running, maxRunning, loaded int32
+	release := make(chan struct{})
+	preloader := NewReferencePreloader(func(service *flux.BackendService) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&loaded, 1)
+	}, 2)
+	for i := 0; i < 6; i++ {
+		preloader.Submit(newTestService("net.bytepowered.Hello"+strconv.Itoa(i), "hello", "", "", ""))
+	}
+	assert.Equal(6, preloader.Pending())
+	close(release)
+	preloader.Wait()
+	assert.Equal(0, preloader.Pending())
+	assert.Equal(int32(6), loaded)
+	assert.True(maxRunning <= 2, "max concurrency: %d", maxRunning)
+}
+
+func TestReferencePreloader_SameReference(t *testing.T) {
+	assert := assert2.New(t)
+	var mutex sync.Mutex
+	loaded := make([]string, 0)
+	release := make(chan struct{})
+	preloader := NewReferencePreloader(func(service *flux.BackendService) {
+		<-release
+		mutex.Lock()
+		loaded = append(loaded, service.AttrRpcGroup()+":"+service.AttrRpcTimeout())
+		mutex.Unlock()
+	}, 4)
+	preloader.Submit(newTestService("net.bytepowered.Hello", "hello", "g1", "", "1s"))
+	// 相同Reference正在预加载：只保留最新的服务，预加载完成后重新执行
+	preloader.Submit(newTestService("net.bytepowered.Hello", "hello", "g1", "", "2s"))
+	preloader.Submit(newTestService("net.bytepowered.Hello", "world", "g1", "", "3s"))
+	preloader.Submit(newTestService("net.bytepowered.Hello", "hello", "g2", "", "1s"))
+	assert.Equal(2, preloader.Pending())
+	close(release)
+	preloader.Wait()
+	assert.Equal(0, preloader.Pending())
+	assert.ElementsMatch([]string{"g1:1s", "g1:3s", "g2:1s"}, loaded)
+	g1 := make([]string, 0)
+	for _, v := range loaded {
+		if strings.HasPrefix(v, "g1:") {
+			g1 = append(g1, v)
+		}
+	}
+	assert.Equal([]string{"g1:1s", "g1:3s"}, g1)
+}
+
+func TestReferencePreloader_Panic(t *testing.T) {
+	assert := assert2.New(t)
+	preloader := NewReferencePreloader(func(service *flux.BackendService) {
+		panic("refer failed")
+	}, 1)
+	preloader.Submit(newTestService("net.bytepowered.Hello", "hello", "", "", ""))
+	preloader.Wait()
+	assert.Equal(0, preloader.Pending())
+}
+
+func TestBackendTransportService_Ready(t *testing.T) {
+	assert := assert2.New(t)
+	recorder := &referenceRecorder{}
+	release := make(chan struct{})
+	transport := &BackendTransportService{
+		references: NewReferenceCache(recorder.factory, recorder.destroyer, 0),
+	}
+	transport.preloader = NewReferencePreloader(func(service *flux.BackendService) {
+		<-release
+		transport.references.Load(service)
+	}, 1)
+	// Discovery初始元数据分发完成前，未就绪
+	assert.False(transport.Ready())
+	// Endpoint内嵌的服务同样提交预加载
+	transport.OnBackendServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeAdded,
+		Service: newTestService("net.bytepowered.Hello", "hello", "g1", "", "5s")})
+	transport.OnDiscoveryReady()
+	assert.False(transport.Ready())
+	close(release)
+	transport.preloader.Wait()
+	assert.True(transport.Ready())
+	assert.Equal([]string{"net.bytepowered.Hello:g1:"}, recorder.created)
+}
@@ -0,0 +1,327 @@
+package dubbo
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+import (
+	"github.com/apache/dubbo-go/common"
+	dubgo "github.com/apache/dubbo-go/config"
+	"github.com/apache/dubbo-go/protocol"
+)
+
+type (
+	// ReferenceFactory 创建Dubbo泛化调用的Reference和Service实例
+	ReferenceFactory func(service *flux.BackendService) (*dubgo.ReferenceConfig, common.RPCService)
+	// ReferenceDestroyer 销毁Dubbo Reference，释放连接和注册中心订阅等资源
+	ReferenceDestroyer func(ref *dubgo.ReferenceConfig)
+)
+
+// ReferenceKey 返回缓存Reference的Key：Interface+Group+Version
+func ReferenceKey(service *flux.BackendService) string {
+	return service.Interface + ":" + service.AttrRpcGroup() + ":" + service.AttrRpcVersion()
+}
+
+// referenceSignature 返回影响Reference配置的服务属性；属性变更时需要重建Reference
+func referenceSignature(service *flux.BackendService) string {
+	return strings.Join([]string{service.RemoteHost, service.AttrRpcTimeout(), service.AttrRpcRetries()}, "|")
+}
+
+// ReferenceEntry 缓存的Reference实例
+type ReferenceEntry struct {
+	Key       string
+	Interface string
+	Group     string
+	Version   string
+	Signature string
+	CreatedAt time.Time
+	reference *dubgo.ReferenceConfig
+	service   common.RPCService
+	lastUsed  int64 // UnixNano，原子读写
+}
+
+func (e *ReferenceEntry) touch(now time.Time) {
+	atomic.StoreInt64(&e.lastUsed, now.UnixNano())
+}
+
+// LastUsed 返回最近一次使用的时间
+func (e *ReferenceEntry) LastUsed() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&e.lastUsed))
+}
+
+// ReferenceInfo Reference缓存的查询信息
+type ReferenceInfo struct {
+	Key       string   `json:"key"`
+	Interface string   `json:"interface"`
+	Group     string   `json:"group"`
+	Version   string   `json:"version"`
+	Signature string   `json:"signature"`
+	CreatedAt string   `json:"createdAt"`
+	LastUsed  string   `json:"lastUsed"`
+	Services  []string `json:"services"`
+}
+
+// ReferenceCache 按 Interface+Group+Version 缓存Dubbo Reference；
+// 1. 服务添加、更新事件中 RemoteHost/Timeout/Retries 变更时，销毁旧的Reference，在下次调用时重建；Group/Version 变更时，使用新的Key；
+// 2. 创建Reference期间发生更新事件时，丢弃使用旧定义创建的Reference，按最新的服务定义重建；
+// 3. 服务删除事件中，没有任何服务使用的Reference被销毁；
+// 4. 超过空闲时间未被使用的Reference被销毁，重建时使用调用方的服务定义；
+// 已缓存Reference的查询不加锁，Reference与最新的服务定义不一致时重建；创建Reference按Key合并并发调用，不同Key的创建互不阻塞；
+type ReferenceCache struct {
+	mutex       sync.Mutex // 保护缓存和服务关系的修改
+	entries     sync.Map   // ReferenceKey -> *ReferenceEntry
+	services    sync.Map   // ServiceId -> ReferenceKey
+	latest      sync.Map   // ReferenceKey -> *latestService，最新的服务定义
+	flight      flightGroup
+	idleTimeout time.Duration
+	factory     ReferenceFactory
+	destroyer   ReferenceDestroyer
+}
+
+// NewReferenceCache 创建ReferenceCache；idleTimeout 为0时，不销毁空闲Reference
+func NewReferenceCache(factory ReferenceFactory, destroyer ReferenceDestroyer, idleTimeout time.Duration) *ReferenceCache {
+	return &ReferenceCache{
+		idleTimeout: idleTimeout,
+		factory:     factory,
+		destroyer:   destroyer,
+	}
+}
+
+// Load 返回服务对应的RPCService；Reference不存在时创建
+func (c *ReferenceCache) Load(service *flux.BackendService) common.RPCService {
+	key := ReferenceKey(service)
+	c.bind(service.ServiceID(), key)
+	if entry, ok := c.entry(key); ok && c.isLatest(entry) {
+		entry.touch(time.Now())
+		return entry.service
+	}
+	return c.flight.Do(key, func() interface{} {
+		for {
+			// 等待期间，其它调用可能已完成创建
+			if entry, ok := c.entry(key); ok && c.isLatest(entry) {
+				return entry.service
+			}
+			latest := c.latestOf(key, service)
+			entry := c.create(key, &latest)
+			// 创建期间服务定义可能已更新：与最新的服务定义比较后再缓存
+			c.mutex.Lock()
+			if c.isLatest(entry) {
+				if prev, ok := c.entry(key); ok {
+					c.destroy(prev, "outdated")
+				}
+				c.entries.Store(key, entry)
+				c.mutex.Unlock()
+				return entry.service
+			}
+			c.mutex.Unlock()
+			c.release(entry, "outdated")
+		}
+	}).(common.RPCService)
+}
+
+func (c *ReferenceCache) create(key string, service *flux.BackendService) *ReferenceEntry {
+	logger.Infow("DUBBO:REFERENCE:CREATE", "key", key, "interface", service.Interface)
+	ref, srv := c.factory(service)
+	now := time.Now()
+	entry := &ReferenceEntry{
+		Key:       key,
+		Interface: service.Interface,
+		Group:     service.AttrRpcGroup(),
+		Version:   service.AttrRpcVersion(),
+		Signature: referenceSignature(service),
+		CreatedAt: now,
+		reference: ref,
+		service:   srv,
+	}
+	entry.touch(now)
+	return entry
+}
+
+// latestService 最新的服务定义；缓存签名，避免每次调用时重新计算
+type latestService struct {
+	service   flux.BackendService
+	signature string
+}
+
+func newLatestService(service flux.BackendService) *latestService {
+	return &latestService{service: service, signature: referenceSignature(&service)}
+}
+
+// latestOf 返回Key对应的最新服务定义；没有记录时，使用调用方的服务定义
+func (c *ReferenceCache) latestOf(key string, service *flux.BackendService) flux.BackendService {
+	if v, ok := c.latest.Load(key); ok {
+		return v.(*latestService).service
+	}
+	v, _ := c.latest.LoadOrStore(key, newLatestService(*service))
+	return v.(*latestService).service
+}
+
+// isLatest 判断Reference是否按最新的服务定义创建；服务已删除时，不再比较
+func (c *ReferenceCache) isLatest(entry *ReferenceEntry) bool {
+	v, ok := c.latest.Load(entry.Key)
+	if !ok {
+		return true
+	}
+	return v.(*latestService).signature == entry.Signature
+}
+
+// OnServiceEvent 根据服务变更事件，销毁失效的Reference；包括Endpoint内嵌服务的变更事件
+func (c *ReferenceCache) OnServiceEvent(event flux.BackendServiceEvent) {
+	service := event.Service
+	key := ReferenceKey(&service)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	switch event.EventType {
+	case flux.EventTypeAdded, flux.EventTypeUpdated:
+		c.rebind(service.ServiceID(), key)
+		c.latest.Store(key, newLatestService(service))
+		if entry, ok := c.entry(key); ok && entry.Signature != referenceSignature(&service) {
+			c.destroy(entry, "updated")
+		}
+	case flux.EventTypeRemoved:
+		c.unbind(service.ServiceID())
+	}
+}
+
+// Sweep 销毁超过空闲时间未被使用的Reference，返回销毁的数量
+func (c *ReferenceCache) Sweep(now time.Time) int {
+	if c.idleTimeout <= 0 {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	count := 0
+	c.entries.Range(func(_, v interface{}) bool {
+		if entry := v.(*ReferenceEntry); now.Sub(entry.LastUsed()) >= c.idleTimeout {
+			// 同时清除记录的服务定义，重建时使用调用方的服务定义
+			c.latest.Delete(entry.Key)
+			c.destroy(entry, "idle")
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// Close 销毁全部Reference
+func (c *ReferenceCache) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries.Range(func(_, v interface{}) bool {
+		c.destroy(v.(*ReferenceEntry), "close")
+		return true
+	})
+}
+
+// Snapshot 返回Reference缓存的查询信息，按Key排序
+func (c *ReferenceCache) Snapshot() []ReferenceInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	services := make(map[string][]string, 16)
+	c.services.Range(func(id, key interface{}) bool {
+		services[key.(string)] = append(services[key.(string)], id.(string))
+		return true
+	})
+	out := make([]ReferenceInfo, 0, 16)
+	c.entries.Range(func(_, v interface{}) bool {
+		entry := v.(*ReferenceEntry)
+		ids := services[entry.Key]
+		sort.Strings(ids)
+		out = append(out, ReferenceInfo{
+			Key:       entry.Key,
+			Interface: entry.Interface,
+			Group:     entry.Group,
+			Version:   entry.Version,
+			Signature: entry.Signature,
+			CreatedAt: entry.CreatedAt.Format(time.RFC3339),
+			LastUsed:  entry.LastUsed().Format(time.RFC3339),
+			Services:  ids,
+		})
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+func (c *ReferenceCache) entry(key string) (*ReferenceEntry, bool) {
+	if v, ok := c.entries.Load(key); ok {
+		return v.(*ReferenceEntry), true
+	}
+	return nil, false
+}
+
+// bind 记录服务使用的Reference；关系未变化时不加锁
+func (c *ReferenceCache) bind(serviceId, key string) {
+	if v, ok := c.services.Load(serviceId); ok && v.(string) == key {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rebind(serviceId, key)
+}
+
+// rebind 记录服务使用的Reference；服务的 Group/Version 变更时，释放旧的Reference；调用方需持有锁
+func (c *ReferenceCache) rebind(serviceId, key string) {
+	if prev, ok := c.services.Load(serviceId); ok && prev.(string) != key {
+		c.unbind(serviceId)
+	}
+	c.services.Store(serviceId, key)
+}
+
+// unbind 移除服务与Reference的关系；Reference没有被其它服务使用时，销毁Reference；调用方需持有锁
+func (c *ReferenceCache) unbind(serviceId string) {
+	v, ok := c.services.Load(serviceId)
+	if !ok {
+		return
+	}
+	c.services.Delete(serviceId)
+	key, used := v.(string), false
+	c.services.Range(func(_, k interface{}) bool {
+		used = k.(string) == key
+		return !used
+	})
+	if used {
+		return
+	}
+	c.latest.Delete(key)
+	if entry, ok := c.entry(key); ok {
+		c.destroy(entry, "removed")
+	}
+}
+
+func (c *ReferenceCache) destroy(entry *ReferenceEntry, reason string) {
+	c.entries.Delete(entry.Key)
+	c.release(entry, reason)
+}
+
+// release 销毁Reference，不修改缓存
+func (c *ReferenceCache) release(entry *ReferenceEntry, reason string) {
+	logger.Infow("DUBBO:REFERENCE:DESTROY", "key", entry.Key, "reason", reason)
+	if nil != c.destroyer && nil != entry.reference {
+		c.destroyer(entry.reference)
+	}
+}
+
+// DestroyReference 销毁Reference的Invoker，关闭连接并取消注册中心订阅；
+// DubboGo的ReferenceConfig没有提供销毁方法，通过反射读取私有的invoker字段；
+func DestroyReference(ref *dubgo.ReferenceConfig) {
+	field := reflect.ValueOf(ref).Elem().FieldByName("invoker")
+	if !field.IsValid() {
+		logger.Warnw("DUBBO:REFERENCE:DESTROY: invoker field not found", "interface", ref.InterfaceName)
+		return
+	}
+	value := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface()
+	if invoker, ok := value.(protocol.Invoker); ok && nil != invoker {
+		invoker.Destroy()
+	}
+}
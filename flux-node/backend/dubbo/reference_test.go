@@ -0,0 +1,298 @@
+package dubbo
+
+import (
+	"context"
+	"github.com/apache/dubbo-go/common"
+	dubgo "github.com/apache/dubbo-go/config"
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type referenceRecorder struct {
+	created   []string
+	destroyed []string
+}
+
+func (r *referenceRecorder) factory(service *flux.BackendService) (*dubgo.ReferenceConfig, common.RPCService) {
+	r.created = append(r.created, ReferenceKey(service))
+	ref := dubgo.NewReferenceConfig(service.Interface, context.Background())
+	ref.InterfaceName = service.Interface
+	return ref, dubgo.NewGenericService(service.Interface)
+}
+
+func (r *referenceRecorder) destroyer(ref *dubgo.ReferenceConfig) {
+	r.destroyed = append(r.destroyed, ref.InterfaceName)
+}
+
+func newTestService(iface, method, group, host, timeout string) flux.BackendService {
+	return flux.BackendService{
+		Interface:  iface,
+		Method:     method,
+		RemoteHost: host,
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+			{Name: flux.ServiceAttrTagRpcProto, Value: flux.ProtoDubbo},
+			{Name: flux.ServiceAttrTagRpcGroup, Value: group},
+			{Name: flux.ServiceAttrTagRpcTimeout, Value: timeout},
+		}},
+		EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: map[string]interface{}{}},
+	}
+}
+
+func TestReferenceCache_Load(t *testing.T) {
+	assert := assert2.New(t)
+	recorder := &referenceRecorder{}
+	cache := NewReferenceCache(recorder.factory, recorder.destroyer, 0)
+	hello := newTestService("net.bytepowered.Hello", "hello", "g1", "", "5s")
+	world := newTestService("net.bytepowered.Hello", "world", "g1", "", "5s")
+	other := newTestService("net.bytepowered.Hello", "hello", "g2", "", "5s")
+	srv := cache.Load(&hello)
+	assert.Equal(srv, cache.Load(&world), "same interface+group+version share reference")
+	cache.Load(&other)
+	assert.Equal([]string{"net.bytepowered.Hello:g1:", "net.bytepowered.Hello:g2:"}, recorder.created)
+	infos := cache.Snapshot()
+	assert.Equal(2, len(infos))
+	// 相同ServiceId使用新的Group时，关联到新的Reference
+	assert.Equal([]string{"net.bytepowered.Hello:world"}, infos[0].Services)
+	assert.Equal([]string{"net.bytepowered.Hello:hello"}, infos[1].Services)
+	assert.Equal(0, len(recorder.destroyed))
+}
+
+func TestReferenceCache_OnServiceEvent(t *testing.T) {
+	recorder := &referenceRecorder{}
+	cache := NewReferenceCache(recorder.factory, recorder.destroyer, 0)
+	hello := newTestService("net.bytepowered.Hello", "hello", "g1", "", "5s")
+	world := newTestService("net.bytepowered.Hello", "world", "g1", "", "5s")
+	cases := []struct {
+		name      string
+		load      []flux.BackendService
+		event     flux.BackendServiceEvent
+		destroyed int
+		entries   int
+	}{
+		{
+			name:      "update without reference changes",
+			load:      []flux.BackendService{hello, world},
+			event:     flux.BackendServiceEvent{EventType: flux.EventTypeUpdated, Service: hello},
+			destroyed: 0,
+			entries:   1,
+		},
+		{
+			name:      "update timeout",
+			event:     flux.BackendServiceEvent{EventType: flux.EventTypeUpdated, Service: newTestService("net.bytepowered.Hello", "hello", "g1", "", "10s")},
+			destroyed: 1,
+			entries:   0,
+		},
+		{
+			name:      "remove service still referenced",
+			load:      []flux.BackendService{world},
+			event:     flux.BackendServiceEvent{EventType: flux.EventTypeRemoved, Service: hello},
+			destroyed: 1,
+			entries:   1,
+		},
+		{
+			name:      "remove last service",
+			event:     flux.BackendServiceEvent{EventType: flux.EventTypeRemoved, Service: world},
+			destroyed: 2,
+			entries:   0,
+		},
+	}
+	assert := assert2.New(t)
+	for _, tc := range cases {
+		for _, srv := range tc.load {
+			cache.Load(&srv)
+		}
+		cache.OnServiceEvent(tc.event)
+		assert.Equal(tc.destroyed, len(recorder.destroyed), tc.name)
+		assert.Equal(tc.entries, len(cache.Snapshot()), tc.name)
+	}
+}
+
+func TestReferenceCache_GroupChanged(t *testing.T) {
+	assert := assert2.New(t)
+	recorder := &referenceRecorder{}
+	cache := NewReferenceCache(recorder.factory, recorder.destroyer, 0)
+	hello := newTestService("net.bytepowered.Hello", "hello", "g1", "", "5s")
+	cache.Load(&hello)
+	updated := newTestService("net.bytepowered.Hello", "hello", "g2", "", "5s")
+	cache.OnServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeUpdated, Service: updated})
+	assert.Equal(1, len(recorder.destroyed))
+	cache.Load(&updated)
+	infos := cache.Snapshot()
+	assert.Equal(1, len(infos))
+	assert.Equal("g2", infos[0].Group)
+}
+
+func TestReferenceCache_Sweep(t *testing.T) {
+	assert := assert2.New(t)
+	recorder := &referenceRecorder{}
+	cache := NewReferenceCache(recorder.factory, recorder.destroyer, time.Minute)
+	hello := newTestService("net.bytepowered.Hello", "hello", "g1", "", "5s")
+	cache.Load(&hello)
+	assert.Equal(0, cache.Sweep(time.Now()))
+	assert.Equal(1, cache.Sweep(time.Now().Add(time.Minute*2)))
+	assert.Equal(0, len(cache.Snapshot()))
+	cache.Load(&hello)
+	assert.Equal(2, len(recorder.created), "recreate after idle destroyed")
+}
+
+func TestReferenceCache_EmbeddedServiceUpdated(t *testing.T) {
+	assert := assert2.New(t)
+	recorder := &referenceRecorder{}
+	cache := NewReferenceCache(recorder.factory, recorder.destroyer, time.Minute)
+	signature := func() string {
+		infos := cache.Snapshot()
+		if len(infos) != 1 {
+			return ""
+		}
+		return infos[0].Signature
+	}
+	h1 := newTestService("net.bytepowered.Hello", "hello", "g1", "h1", "5s")
+	h2 := newTestService("net.bytepowered.Hello", "hello", "g1", "h2", "5s")
+	h3 := newTestService("net.bytepowered.Hello", "hello", "g1", "h3", "5s")
+	cache.Load(&h1)
+	cache.Load(&h2)
+	assert.Equal("h1|5s|", signature())
+	// 空闲销毁后，按调用方的服务定义重建
+	assert.Equal(1, cache.Sweep(time.Now().Add(time.Minute*2)))
+	cache.Load(&h2)
+	assert.Equal("h2|5s|", signature())
+	// Endpoint更新时，内嵌服务以添加或更新事件通知
+	cache.OnServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeAdded, Service: h3})
+	assert.Equal(2, len(recorder.destroyed))
+	cache.Load(&h2)
+	assert.Equal("h3|5s|", signature())
+	cache.OnServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeRemoved, Service: h3})
+	assert.Equal(0, len(cache.Snapshot()))
+	assert.Equal(3, len(recorder.created))
+}
+
+func TestDestroyReference_NoInvoker(t *testing.T) {
+	assert2.NotPanics(t, func() {
+		DestroyReference(dubgo.NewReferenceConfig("net.bytepowered.Hello", context.Background()))
+	})
+}
+
+func TestReferenceCache_ConcurrentLoad(t *testing.T) {
+	assert := assert2.New(t)
+	var created int32
+	release := make(chan struct{})
+	factory := func(service *flux.BackendService) (*dubgo.ReferenceConfig, common.RPCService) {
+		atomic.AddInt32(&created, 1)
+		if "net.bytepowered.Slow" == service.Interface {
+			<-release
+		}
+		return dubgo.NewReferenceConfig(service.Interface, context.Background()), dubgo.NewGenericService(service.Interface)
+	}
+	cache := NewReferenceCache(factory, nil, 0)
+	fast := newTestService("net.bytepowered.Fast", "hello", "", "", "")
+	slow := newTestService("net.bytepowered.Slow", "hello", "", "", "")
+	cache.Load(&fast)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Load(&slow)
+		}()
+	}
+	// 创建中的Reference不阻塞已缓存Reference的查询
+	done := make(chan struct{})
+	go func() {
+		cache.Load(&fast)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail("cached lookup blocked by reference creation")
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(int32(2), atomic.LoadInt32(&created))
+	assert.Equal(2, len(cache.Snapshot()))
+}
+
+func TestReferenceCache_UpdateDuringCreate(t *testing.T) {
+	assert := assert2.New(t)
+	var mutex sync.Mutex
+	created, destroyed := make([]string, 0), make([]string, 0)
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	factory := func(service *flux.BackendService) (*dubgo.ReferenceConfig, common.RPCService) {
+		mutex.Lock()
+		created = append(created, service.AttrRpcTimeout())
+		mutex.Unlock()
+		started <- struct{}{}
+		<-release
+		ref := dubgo.NewReferenceConfig(service.Interface, context.Background())
+		ref.RequestTimeout = service.AttrRpcTimeout()
+		return ref, dubgo.NewGenericService(service.Interface)
+	}
+	destroyer := func(ref *dubgo.ReferenceConfig) {
+		mutex.Lock()
+		destroyed = append(destroyed, ref.RequestTimeout)
+		mutex.Unlock()
+	}
+	cache := NewReferenceCache(factory, destroyer, 0)
+	hello := newTestService("net.bytepowered.Hello", "hello", "g1", "", "5s")
+	done := make(chan struct{})
+	go func() {
+		cache.Load(&hello)
+		close(done)
+	}()
+	<-started
+	// 创建Reference期间，服务超时时间更新
+	cache.OnServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeUpdated,
+		Service: newTestService("net.bytepowered.Hello", "hello", "g1", "", "10s")})
+	close(release)
+	<-done
+	assert.Equal([]string{"5s", "10s"}, created)
+	assert.Equal([]string{"5s"}, destroyed)
+	infos := cache.Snapshot()
+	assert.Equal(1, len(infos))
+	assert.Equal("|10s|", infos[0].Signature)
+}
+
+func TestReferenceCache_ConcurrentUpdate(t *testing.T) {
+	assert := assert2.New(t)
+	var created, destroyed int32
+	factory := func(service *flux.BackendService) (*dubgo.ReferenceConfig, common.RPCService) {
+		atomic.AddInt32(&created, 1)
+		time.Sleep(time.Millisecond)
+		return dubgo.NewReferenceConfig(service.Interface, context.Background()), dubgo.NewGenericService(service.Interface)
+	}
+	destroyer := func(ref *dubgo.ReferenceConfig) {
+		atomic.AddInt32(&destroyed, 1)
+	}
+	cache := NewReferenceCache(factory, destroyer, 0)
+	timeouts := []string{"1s", "2s", "3s"}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				hello := newTestService("net.bytepowered.Hello", "hello", "g1", "", timeouts[(i+n)%len(timeouts)])
+				cache.Load(&hello)
+			}
+		}(i)
+	}
+	// 调用与服务更新事件并发
+	for n := 0; n < 50; n++ {
+		cache.OnServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeUpdated,
+			Service: newTestService("net.bytepowered.Hello", "hello", "g1", "", timeouts[n%len(timeouts)])})
+		time.Sleep(time.Millisecond / 2)
+	}
+	wg.Wait()
+	// 使用最后一次更新事件的服务定义
+	hello := newTestService("net.bytepowered.Hello", "hello", "g1", "", "5s")
+	cache.Load(&hello)
+	infos := cache.Snapshot()
+	assert.Equal(1, len(infos))
+	assert.Equal("|"+timeouts[49%len(timeouts)]+"|", infos[0].Signature)
+	// 除缓存中的Reference外，其它Reference均已销毁
+	assert.Equal(atomic.LoadInt32(&created)-1, atomic.LoadInt32(&destroyed))
+}
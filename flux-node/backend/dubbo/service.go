@@ -7,7 +7,7 @@ import (
 	"github.com/bytepowered/flux/flux-node"
 	jsoniter "github.com/json-iterator/go"
 	"reflect"
-	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,6 +29,16 @@ import (
 const (
 	ConfigKeyTraceEnable    = "trace_enable"
 	ConfigKeyReferenceDelay = "reference_delay"
+	// 传递请求ID的Attachment名称
+	ConfigKeyRequestIdAttachment = "request_id_attachment"
+	// 允许返回给客户端的响应Header名称列表；为空时返回全部
+	ConfigKeyResponseHeaderAllow = "response_header_allow"
+	// Reference空闲销毁时间；为0时不销毁
+	ConfigKeyReferenceIdleTimeout = "reference_idle_timeout"
+	// 是否在服务注册时预先创建Reference；开启后，预加载完成前Transport处于未就绪状态
+	ConfigKeyReferencePreload = "reference_preload"
+	// 同时预加载Reference的最大数量
+	ConfigKeyReferencePreloadConcurrency = "reference_preload_concurrency"
 )
 
 func init() {
@@ -41,8 +51,12 @@ var (
 )
 
 var (
-	_     flux.BackendTransport = new(BackendTransportService)
-	_json                       = jsoniter.ConfigCompatibleWithStandardLibrary
+	_     flux.BackendTransport            = new(BackendTransportService)
+	_     flux.BackendServiceEventListener = new(BackendTransportService)
+	_     flux.DiscoveryReadyListener      = new(BackendTransportService)
+	_     flux.Inspector                   = new(BackendTransportService)
+	_     flux.Readiness                   = new(BackendTransportService)
+	_json                                  = jsoniter.ConfigCompatibleWithStandardLibrary
 )
 
 type (
@@ -79,8 +93,13 @@ type BackendTransportService struct {
 	responseCodecFunc flux.BackendResponseCodecFunc // 解析响应结果的函数
 	// 内部私有
 	traceEnable   bool
+	requestIdAtt  string
+	headerAllow   []string
 	configuration *flux.Configuration
-	serviceMutex  sync.RWMutex
+	references    *ReferenceCache
+	preloader     *ReferencePreloader
+	discovered    int32 // Discovery初始元数据是否已分发完成
+	sweepQuit     chan struct{}
 }
 
 // WithArgumentAssembleFunc 用于配置Dubbo参数封装实现函数
@@ -171,13 +190,17 @@ func NewBackendTransportServiceOverrides(overrides ...Option) flux.BackendTransp
 			"password": "dubbo.registry.password",
 		}),
 		WithDefaults(map[string]interface{}{
-			ConfigKeyReferenceDelay: time.Millisecond * 10,
-			ConfigKeyTraceEnable:    false,
-			"timeout":               "5000",
-			"retries":               "0",
-			"cluster":               "failover",
-			"load_balance":          "random",
-			"protocol":              dubbo.DUBBO,
+			ConfigKeyReferenceDelay:              time.Millisecond * 10,
+			ConfigKeyTraceEnable:                 false,
+			ConfigKeyRequestIdAttachment:         flux.XRequestId,
+			ConfigKeyReferenceIdleTimeout:        time.Minute * 30,
+			ConfigKeyReferencePreload:            false,
+			ConfigKeyReferencePreloadConcurrency: 4,
+			"timeout":                            "5000",
+			"retries":                            "0",
+			"cluster":                            "failover",
+			"load_balance":                       "random",
+			"protocol":                           dubbo.DUBBO,
 		}),
 		WithGenericServiceFunc(func(backend *flux.BackendService) common.RPCService {
 			return dubgo.NewGenericService(backend.Interface)
@@ -211,6 +234,16 @@ func (b *BackendTransportService) Init(config *flux.Configuration) error {
 	config.SetDefaults(b.defaults)
 	b.configuration = config
 	b.traceEnable = config.GetBool(ConfigKeyTraceEnable)
+	b.requestIdAtt = config.GetString(ConfigKeyRequestIdAttachment)
+	b.headerAllow = config.GetStringSlice(ConfigKeyResponseHeaderAllow)
+	b.references = NewReferenceCache(b.newGenericReference, DestroyReference, config.GetDuration(ConfigKeyReferenceIdleTimeout))
+	if config.GetBool(ConfigKeyReferencePreload) {
+		concurrency := config.GetInt(ConfigKeyReferencePreloadConcurrency)
+		b.preloader = NewReferencePreloader(func(service *flux.BackendService) {
+			b.references.Load(service)
+		}, concurrency)
+		logger.Infow("Dubbo backend transport reference preload", "concurrency", concurrency)
+	}
 	logger.Infow("Dubbo backend transport request trace", "enable", b.traceEnable)
 	// Set default impl if not present
 	if nil == b.optionsFunc {
@@ -234,15 +267,72 @@ func (b *BackendTransportService) Init(config *flux.Configuration) error {
 
 // Startup startup service
 func (b *BackendTransportService) Startup() error {
+	if idle := b.configuration.GetDuration(ConfigKeyReferenceIdleTimeout); idle > 0 {
+		b.sweepQuit = make(chan struct{})
+		go b.sweepIdleReferences(idle, b.sweepQuit)
+	}
 	return nil
 }
 
 // Shutdown shutdown service
 func (b *BackendTransportService) Shutdown(_ context.Context) error {
+	if nil != b.sweepQuit {
+		close(b.sweepQuit)
+	}
 	dubgo.BeforeShutdown()
 	return nil
 }
 
+// OnBackendServiceEvent 服务变更时，销毁失效的Reference；开启预加载时，预先创建新增和更新服务的Reference；
+// 包括独立定义的服务和Endpoint内嵌的服务；
+func (b *BackendTransportService) OnBackendServiceEvent(event flux.BackendServiceEvent) {
+	b.references.OnServiceEvent(event)
+	if nil != b.preloader && flux.EventTypeRemoved != event.EventType {
+		b.preloader.Submit(event.Service)
+	}
+}
+
+// OnDiscoveryReady Discovery初始元数据已分发完成，此前的服务都已提交预加载
+func (b *BackendTransportService) OnDiscoveryReady() {
+	atomic.StoreInt32(&b.discovered, 1)
+}
+
+// Ready 开启预加载时，Discovery初始元数据分发完成，并且全部已提交的Reference创建完成后返回就绪状态
+func (b *BackendTransportService) Ready() bool {
+	if nil == b.preloader {
+		return true
+	}
+	return atomic.LoadInt32(&b.discovered) == 1 && b.preloader.Pending() == 0
+}
+
+// Inspect 返回Reference缓存的状态
+func (b *BackendTransportService) Inspect() interface{} {
+	return map[string]interface{}{
+		"references": b.references.Snapshot(),
+	}
+}
+
+func (b *BackendTransportService) sweepIdleReferences(idle time.Duration, quit <-chan struct{}) {
+	interval := idle / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	} else if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case now := <-ticker.C:
+			if n := b.references.Sweep(now); n > 0 {
+				logger.Infow("DUBBO:REFERENCE:SWEEP", "destroyed", n)
+			}
+		}
+	}
+}
+
 // Exchange do exchange with context
 func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
 	return backend.DoExchangeTransport(ctx, b)
@@ -289,6 +379,7 @@ func (b *BackendTransportService) InvokeCodec(ctx flux.Context, service flux.Bac
 		}
 	}
 	fluxpkg.AssertNotNil(result, "dubbo: <result> must not nil, request.id: "+ctx.RequestId())
+	result.Headers = FilterResponseHeader(result.Headers, b.headerAllow)
 	return result, nil
 }
 
@@ -309,6 +400,10 @@ func (b *BackendTransportService) DoInvoke(types []string, values interface{}, s
 			CauseError: err,
 		}
 	}
+	// 传递请求ID
+	if sm, ok := att.(map[string]string); ok && "" != b.requestIdAtt {
+		sm[b.requestIdAtt] = ctx.RequestId()
+	}
 	generic := b.LoadGenericService(&service)
 	goctx := context.WithValue(ctx.Context(), constant.AttachmentKey, att)
 	resultW := b.invokeFunc(goctx, []interface{}{service.Method, types, values}, generic)
@@ -335,13 +430,13 @@ func (b *BackendTransportService) DoInvoke(types []string, values interface{}, s
 	}
 }
 
-// LoadGenericService create and cache dubbo generic service
+// LoadGenericService 返回服务对应的Dubbo泛化调用Service；按 Interface+Group+Version 缓存Reference
 func (b *BackendTransportService) LoadGenericService(backend *flux.BackendService) common.RPCService {
-	b.serviceMutex.Lock()
-	defer b.serviceMutex.Unlock()
-	if srv := dubgo.GetConsumerService(backend.Interface); nil != srv {
-		return srv
-	}
+	return b.references.Load(backend)
+}
+
+// newGenericReference 创建并初始化Dubbo泛化调用的Reference
+func (b *BackendTransportService) newGenericReference(backend *flux.BackendService) (*dubgo.ReferenceConfig, common.RPCService) {
 	newRef := NewReference(backend.Interface, backend, b.configuration)
 	// Options
 	const msg = "Dubbo option-func return nil reference"
@@ -352,7 +447,6 @@ func (b *BackendTransportService) LoadGenericService(backend *flux.BackendServic
 	}
 	logger.Infow("DUBBO:GENERIC:CREATE: PREPARE", "interface", backend.Interface)
 	srv := b.serviceFunc(backend)
-	dubgo.SetConsumerService(srv)
 	newRef.Refer(srv)
 	newRef.Implement(srv)
 	t := b.configuration.GetDuration(ConfigKeyReferenceDelay)
@@ -361,7 +455,7 @@ func (b *BackendTransportService) LoadGenericService(backend *flux.BackendServic
 	}
 	<-time.After(t)
 	logger.Infow("DUBBO:GENERIC:CREATE: OJBK", "interface", backend.Interface)
-	return srv
+	return newRef, srv
 }
 
 func newConsumerRegistry(config *flux.Configuration) (string, *dubgo.RegistryConfig) {
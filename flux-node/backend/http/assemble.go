@@ -1,16 +1,13 @@
 package http
 
 import (
-	"context"
 	"fmt"
 	"github.com/bytepowered/flux/flux-node"
-	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/spf13/cast"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 )
 
 func DefaultArgumentAssemble(service *flux.BackendService, inURL *url.URL, bodyReader io.ReadCloser, ctx flux.Context) (*http.Request, error) {
@@ -53,14 +50,8 @@ func DefaultArgumentAssemble(service *flux.BackendService, inURL *url.URL, bodyR
 		RawQuery:   newQuery,
 		Fragment:   inURL.Fragment,
 	}
-	to := service.AttrRpcTimeout()
-	timeout, err := time.ParseDuration(to)
-	if err != nil {
-		logger.Warnf("Illegal endpoint rpc-timeout: ", to)
-		timeout = time.Second * 10
-	}
-	toctx, _ := context.WithTimeout(ctx.Context(), timeout)
-	newRequest, err := http.NewRequestWithContext(toctx, service.Method, newUrl.String(), newBodyReader)
+	// 请求超时由 ExecuteRequest 控制，并在响应Body关闭时释放
+	newRequest, err := http.NewRequestWithContext(ctx.Context(), service.Method, newUrl.String(), newBodyReader)
 	if nil != err {
 		return nil, fmt.Errorf("new request, method: %s, url: %s, err: %w", service.Method, newUrl, err)
 	}
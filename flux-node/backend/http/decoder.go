@@ -20,6 +20,17 @@ func NewBackendResponseCodecFunc() flux.BackendResponseCodecFunc {
 				Body:       nil,
 			}, ErrUnknownHttpBackendResponse
 		}
+		// 流式响应：透传给客户端，不缓存全部数据
+		if flux.IsStreamResponse(resp.Header, resp.TransferEncoding, ctx.Endpoint().AttrStream()) {
+			return &flux.BackendResponse{
+				StatusCode: resp.StatusCode,
+				Headers:    resp.Header,
+				Body: &flux.StreamBody{
+					ReadCloser:  resp.Body,
+					ContentType: resp.Header.Get(flux.HeaderContentType),
+				},
+			}, nil
+		}
 		return &flux.BackendResponse{
 			StatusCode: resp.StatusCode,
 			Headers:    resp.Header,
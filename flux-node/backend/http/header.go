@@ -0,0 +1,271 @@
+package http
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/common"
+	"github.com/bytepowered/flux/flux-node/internal"
+	"github.com/spf13/cast"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Header策略定义的服务属性（Attributes）；名称列表可定义多个属性，或者使用逗号分隔；
+const (
+	AttrTagHeaderAllow     = "headerallow"     // 允许透传的请求Header；未定义时透传全部
+	AttrTagHeaderDeny      = "headerdeny"      // 禁止透传的请求Header
+	AttrTagHeaderAttrs     = "headerattrs"     // 作为请求Header传递的Context属性；未定义或者为 * 时传递全部属性
+	AttrTagHeaderAdd       = "headeradd"       // 添加请求Header，格式：Name:Value；Value支持查找表达式 ${scope:key|default}
+	AttrTagHeaderSet       = "headerset"       // 设置（覆盖）请求Header，格式同 headeradd
+	AttrTagHeaderRemove    = "headerremove"    // 移除请求Header；在其它规则之后执行
+	AttrTagHeaderForwarded = "headerforwarded" // 是否生成 X-Forwarded-For/Proto/Host，默认为true
+	AttrTagRespHeaderAllow = "respheaderallow" // 允许返回给客户端的后端响应Header；未定义时返回全部
+	AttrTagRespHeaderDeny  = "respheaderdeny"  // 禁止返回给客户端的后端响应Header
+)
+
+const (
+	HeaderXForwardedFor   = "X-Forwarded-For"
+	HeaderXForwardedProto = "X-Forwarded-Proto"
+	HeaderXForwardedHost  = "X-Forwarded-Host"
+)
+
+var (
+	// 逐跳（hop-by-hop）Header，只对单个连接有效，不能被代理转发：RFC 7230 6.1
+	hopByHopHeaders = []string{
+		"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+		"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+	}
+	// 由HttpClient根据请求生成的Header
+	clientGeneratedHeaders = []string{"Host", "Content-Length"}
+)
+
+// HeaderValue 定义Header键值对
+type HeaderValue struct {
+	Name  string
+	Value string
+}
+
+// HeaderPolicy 后端服务的请求和响应Header策略
+type HeaderPolicy struct {
+	Allow      []string
+	Deny       []string
+	Attributes []string
+	Add        []HeaderValue
+	Set        []HeaderValue
+	Remove     []string
+	Forwarded  bool
+	// 响应Header策略
+	ResponseAllow []string
+	ResponseDeny  []string
+}
+
+// ParseHeaderPolicy 解析服务属性定义的Header策略
+func ParseHeaderPolicy(service flux.BackendService) (HeaderPolicy, error) {
+	policy := HeaderPolicy{
+		Allow:         headerNamesOf(service, AttrTagHeaderAllow),
+		Deny:          headerNamesOf(service, AttrTagHeaderDeny),
+		Remove:        headerNamesOf(service, AttrTagHeaderRemove),
+		ResponseAllow: headerNamesOf(service, AttrTagRespHeaderAllow),
+		ResponseDeny:  headerNamesOf(service, AttrTagRespHeaderDeny),
+		Forwarded:     true,
+	}
+	for _, attr := range service.GetAttrs(AttrTagHeaderAttrs) {
+		for _, v := range strings.Split(attr.GetString(), ",") {
+			if v = strings.TrimSpace(v); "" != v {
+				policy.Attributes = append(policy.Attributes, v)
+			}
+		}
+	}
+	if attr := service.GetAttr(AttrTagHeaderForwarded); nil != attr.Value {
+		forwarded, err := cast.ToBoolE(attr.Value)
+		if nil != err {
+			return policy, fmt.Errorf("invalid header policy, %s: %v", AttrTagHeaderForwarded, attr.Value)
+		}
+		policy.Forwarded = forwarded
+	}
+	var err error
+	if policy.Add, err = headerValuesOf(service, AttrTagHeaderAdd); nil != err {
+		return policy, err
+	}
+	if policy.Set, err = headerValuesOf(service, AttrTagHeaderSet); nil != err {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// headerPolicyOf 返回服务的Header策略；同一请求中只解析一次，解析结果保存在请求Context中
+func headerPolicyOf(ctx flux.Context, service flux.BackendService) (HeaderPolicy, error) {
+	key := internal.ContextKeyHttpHeaderPolicy + service.ServiceID()
+	if v, ok := ctx.GetVariable(key); ok {
+		if policy, ok := v.(HeaderPolicy); ok {
+			return policy, nil
+		}
+	}
+	policy, err := ParseHeaderPolicy(service)
+	if nil == err {
+		ctx.SetVariable(key, policy)
+	}
+	return policy, err
+}
+
+// UpstreamHeader 根据策略生成转发到后端的请求Header：
+// 1. base 为网关生成的默认Header；客户端Header按 Allow/Deny 过滤后透传，并移除逐跳Header；
+// 2. 按 Attributes 传递Context属性，设置请求ID，生成 X-Forwarded-* Header；
+// 3. 依次执行 Add/Set/Remove 规则；
+func (p HeaderPolicy) UpstreamHeader(ctx flux.Context, base http.Header, requestIdHeader string) http.Header {
+	header := base.Clone()
+	if nil == header {
+		header = make(http.Header, 16)
+	}
+	inHeader := ctx.Request().HeaderVars()
+	for k, vs := range FilterHeader(inHeader, p.Allow, p.Deny) {
+		header[k] = append([]string(nil), vs...)
+	}
+	for k, v := range ctx.Attributes() {
+		if p.forwardAttribute(k) {
+			header.Set(k, cast.ToString(v))
+		}
+	}
+	if "" != requestIdHeader {
+		header.Set(requestIdHeader, ctx.RequestId())
+	}
+	if p.Forwarded {
+		setForwardedHeaders(ctx, inHeader, header)
+	}
+	for _, hv := range p.Add {
+		header.Add(hv.Name, common.RenderTemplate(hv.Value, ctx))
+	}
+	for _, hv := range p.Set {
+		header.Set(hv.Name, common.RenderTemplate(hv.Value, ctx))
+	}
+	for _, name := range p.Remove {
+		header.Del(name)
+	}
+	return header
+}
+
+// DownstreamHeader 根据策略过滤后端响应Header，并移除逐跳Header
+func (p HeaderPolicy) DownstreamHeader(header http.Header) http.Header {
+	return FilterHeader(header, p.ResponseAllow, p.ResponseDeny)
+}
+
+func (p HeaderPolicy) forwardAttribute(name string) bool {
+	if len(p.Attributes) == 0 {
+		return true
+	}
+	for _, v := range p.Attributes {
+		if "*" == v || strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterHeader 按 allow/deny 名称列表过滤Header；allow为空时允许全部；
+// 始终移除逐跳Header，以及Connection Header中声明的Header；
+func FilterHeader(header http.Header, allow []string, deny []string) http.Header {
+	out := make(http.Header, len(header))
+	if nil == header {
+		return out
+	}
+	excludes := make(map[string]struct{}, 16)
+	for _, name := range hopByHopHeaders {
+		excludes[name] = struct{}{}
+	}
+	for _, name := range clientGeneratedHeaders {
+		excludes[name] = struct{}{}
+	}
+	for _, v := range header.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); "" != name {
+				excludes[http.CanonicalHeaderKey(name)] = struct{}{}
+			}
+		}
+	}
+	for _, name := range deny {
+		excludes[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	var includes map[string]struct{}
+	if len(allow) > 0 {
+		includes = make(map[string]struct{}, len(allow))
+		for _, name := range allow {
+			includes[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+	}
+	for k, vs := range header {
+		key := http.CanonicalHeaderKey(k)
+		if _, ok := excludes[key]; ok {
+			continue
+		}
+		if nil != includes {
+			if _, ok := includes[key]; !ok {
+				continue
+			}
+		}
+		out[key] = append(out[key], vs...)
+	}
+	return out
+}
+
+// setForwardedHeaders 生成 X-Forwarded-* Header；X-Forwarded-For 追加客户端地址，
+// X-Forwarded-Proto/Host 保留上游代理设置的值
+func setForwardedHeaders(ctx flux.Context, inHeader http.Header, header http.Header) {
+	proto, clientIP := "http", ""
+	if webex := ctx.Exchange(); nil != webex {
+		if request, err := webex.HttpRequest(); nil == err {
+			if nil != request.TLS {
+				proto = "https"
+			}
+			if host, _, err := net.SplitHostPort(request.RemoteAddr); nil == err {
+				clientIP = host
+			} else {
+				clientIP = request.RemoteAddr
+			}
+		}
+	}
+	if "" == clientIP {
+		clientIP = ctx.Request().Address()
+	}
+	if "" != clientIP {
+		if prior := inHeader.Values(HeaderXForwardedFor); len(prior) > 0 {
+			clientIP = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		header.Set(HeaderXForwardedFor, clientIP)
+	}
+	if v := inHeader.Get(HeaderXForwardedProto); "" != v {
+		proto = v
+	}
+	header.Set(HeaderXForwardedProto, proto)
+	host := inHeader.Get(HeaderXForwardedHost)
+	if "" == host {
+		host = ctx.Request().Host()
+	}
+	if "" != host {
+		header.Set(HeaderXForwardedHost, host)
+	}
+}
+
+func headerNamesOf(service flux.BackendService, tag string) []string {
+	out := make([]string, 0, 2)
+	for _, attr := range service.GetAttrs(tag) {
+		for _, v := range strings.Split(attr.GetString(), ",") {
+			if v = strings.TrimSpace(v); "" != v {
+				out = append(out, http.CanonicalHeaderKey(v))
+			}
+		}
+	}
+	return out
+}
+
+func headerValuesOf(service flux.BackendService, tag string) ([]HeaderValue, error) {
+	out := make([]HeaderValue, 0, 2)
+	for _, attr := range service.GetAttrs(tag) {
+		pair := strings.SplitN(attr.GetString(), ":", 2)
+		if len(pair) != 2 || "" == strings.TrimSpace(pair[0]) {
+			return nil, fmt.Errorf("invalid header policy, %s: %s", tag, attr.GetString())
+		}
+		out = append(out, HeaderValue{Name: strings.TrimSpace(pair[0]), Value: strings.TrimSpace(pair[1])})
+	}
+	return out, nil
+}
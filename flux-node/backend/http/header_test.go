@@ -0,0 +1,154 @@
+package http
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/boot"
+	"github.com/bytepowered/flux/flux-node/context"
+	"github.com/bytepowered/flux/flux-node/internal"
+	"github.com/bytepowered/flux/flux-node/testkit"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFilterHeader(t *testing.T) {
+	header := http.Header{
+		"Host":           []string{"gateway.com"},
+		"Connection":     []string{"keep-alive, X-Hop"},
+		"Keep-Alive":     []string{"timeout=5"},
+		"Content-Length": []string{"10"},
+		"X-Hop":          []string{"1"},
+		"X-Token":        []string{"t"},
+		"X-Tenant":       []string{"t1"},
+		"Accept":         []string{"*/*"},
+	}
+	cases := []struct {
+		allow    []string
+		deny     []string
+		expected http.Header
+	}{
+		{expected: http.Header{"X-Token": []string{"t"}, "X-Tenant": []string{"t1"}, "Accept": []string{"*/*"}}},
+		{deny: []string{"x-token"}, expected: http.Header{"X-Tenant": []string{"t1"}, "Accept": []string{"*/*"}}},
+		{allow: []string{"X-Tenant", "Connection"}, expected: http.Header{"X-Tenant": []string{"t1"}}},
+		{allow: []string{"X-Tenant"}, deny: []string{"X-Tenant"}, expected: http.Header{}},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		assert.Equal(tcase.expected, FilterHeader(header, tcase.allow, tcase.deny), "allow: %v, deny: %v", tcase.allow, tcase.deny)
+	}
+}
+
+func TestParseHeaderPolicy(t *testing.T) {
+	cases := []struct {
+		attrs []flux.Attribute
+		error bool
+	}{
+		{attrs: nil},
+		{attrs: []flux.Attribute{{Name: AttrTagHeaderAdd, Value: "X-Tenant: ${header:X-Tenant|none}"}}},
+		{attrs: []flux.Attribute{{Name: AttrTagHeaderSet, Value: "X-Tenant"}}, error: true},
+		{attrs: []flux.Attribute{{Name: AttrTagHeaderForwarded, Value: "maybe"}}, error: true},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		service := flux.BackendService{EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: tcase.attrs}}
+		policy, err := ParseHeaderPolicy(service)
+		if tcase.error {
+			assert.Error(err)
+		} else {
+			assert.NoError(err)
+			assert.True(policy.Forwarded)
+		}
+	}
+	policy, _ := ParseHeaderPolicy(flux.BackendService{EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+		{Name: AttrTagHeaderAllow, Value: "x-token, X-Tenant"},
+		{Name: AttrTagHeaderAllow, Value: "accept"},
+		{Name: AttrTagHeaderForwarded, Value: false},
+	}}})
+	assert.Equal([]string{"X-Token", "X-Tenant", "Accept"}, policy.Allow)
+	assert.False(policy.Forwarded)
+}
+
+func TestBackendTransportService_HeaderPolicy(t *testing.T) {
+	assert := assert2.New(t)
+	var received http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Header().Set("X-Internal-Trace", "trace-1")
+		w.Header().Set("X-Result", "ok")
+		w.Header().Set(flux.HeaderContentType, flux.MIMEApplicationJSON)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+	gw := testkit.NewGateway(t, testkit.WithServerOptions(boot.WithWebExchangeHooks(func(webex flux.WebExchange, ctx flux.Context) {
+		ctx.SetAttribute("tenant", "t1")
+		ctx.SetAttribute("secret", "s")
+	})))
+	gw.AddEndpoint(flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/profile", Version: "v1",
+		Service: flux.BackendService{
+			Scheme: "http", RemoteHost: strings.TrimPrefix(upstream.URL, "http://"), Interface: "/profile", Method: "GET",
+			EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+				{Name: flux.ServiceAttrTagRpcProto, Value: flux.ProtoHttp},
+				{Name: AttrTagHeaderDeny, Value: "Cookie"},
+				{Name: AttrTagHeaderAttrs, Value: "tenant"},
+				{Name: AttrTagHeaderAdd, Value: "X-Client: ${header:X-Client|web}"},
+				{Name: AttrTagHeaderSet, Value: "X-Gateway: flux"},
+				{Name: AttrTagHeaderRemove, Value: "X-Debug"},
+				{Name: AttrTagRespHeaderDeny, Value: "X-Internal-Trace"},
+			}},
+		},
+	})
+	gw.Request(http.MethodGet, "/profile", http.Header{
+		"Cookie":          []string{"sid=1"},
+		"X-Debug":         []string{"1"},
+		"X-Gateway":       []string{"fake"},
+		"X-Forwarded-For": []string{"10.0.0.1"},
+		"Connection":      []string{"X-Hop"},
+		"X-Hop":           []string{"1"},
+	}, nil).
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, "X-Result", "ok").
+		AssertHeader(t, "X-Internal-Trace", "")
+	if !assert.NotNil(received) {
+		return
+	}
+	assert.Equal("", received.Get("Cookie"))
+	assert.Equal("", received.Get("X-Debug"))
+	assert.Equal("", received.Get("X-Hop"))
+	assert.Equal("", received.Get("secret"))
+	assert.Equal("t1", received.Get("tenant"))
+	assert.Equal("web", received.Get("X-Client"))
+	assert.Equal([]string{"flux"}, received.Values("X-Gateway"))
+	assert.Equal("10.0.0.1, 127.0.0.1", received.Get(HeaderXForwardedFor))
+	assert.Equal("http", received.Get(HeaderXForwardedProto))
+	assert.Equal(strings.TrimPrefix(gw.URL(), "http://"), received.Get(HeaderXForwardedHost))
+	assert.NotEmpty(received.Get(flux.XRequestId))
+}
+
+func TestHeaderPolicyOf(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := context.NewMockWith("header-policy", map[string]interface{}{})
+	service := flux.BackendService{Interface: "/users", Method: "GET",
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+			{Name: AttrTagHeaderAllow, Value: "X-Token"},
+		}}}
+	policy, err := headerPolicyOf(ctx, service)
+	assert.NoError(err)
+	assert.Equal([]string{"X-Token"}, policy.Allow)
+	// 同一请求中复用已解析的策略
+	service.Attributes = []flux.Attribute{{Name: AttrTagHeaderAllow, Value: "X-Tenant"}}
+	policy, err = headerPolicyOf(ctx, service)
+	assert.NoError(err)
+	assert.Equal([]string{"X-Token"}, policy.Allow)
+	// 解析失败的策略不缓存
+	invalid := flux.BackendService{Interface: "/orders", Method: "GET",
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+			{Name: AttrTagHeaderForwarded, Value: "maybe"},
+		}}}
+	_, err = headerPolicyOf(ctx, invalid)
+	assert.Error(err)
+	_, ok := ctx.GetVariable(internal.ContextKeyHttpHeaderPolicy + invalid.ServiceID())
+	assert.False(ok)
+}
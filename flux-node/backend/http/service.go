@@ -1,17 +1,28 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-node/backend"
 	"github.com/bytepowered/flux/flux-node/ext"
-	"github.com/spf13/cast"
+	"github.com/bytepowered/flux/flux-node/logger"
 	"io"
 	"net/http"
 	"net/url"
 	"time"
 )
 
+const (
+	ConfigKeyRequestIdHeader   = "request_id_header"
+	ConfigKeyTimeout           = "timeout"
+	ConfigKeyStreamMaxDuration = "stream_max_duration"
+)
+
+const (
+	defaultRequestTimeout = time.Second * 10
+)
+
 func init() {
 	ext.RegisterBackendTransport(flux.ProtoHttp, NewBackendTransportService())
 }
@@ -29,23 +40,35 @@ type BackendTransportService struct {
 	httpClient        *http.Client
 	responseCodecFunc flux.BackendResponseCodecFunc
 	argAssembleFunc   ArgumentsAssembleFunc
+	requestIdHeader   string
+	websocket         *WebSocketProxy
+	// 未定义rpctimeout属性时，默认的请求超时时间
+	timeout time.Duration
+	// 未定义streamtimeout属性时，流式响应的最大持续时间；0表示不限制
+	streamMaxDuration time.Duration
 }
 
 func NewBackendTransportService() *BackendTransportService {
 	return &BackendTransportService{
-		httpClient: &http.Client{
-			Timeout: time.Second * 10,
-		},
+		// 不设置Client.Timeout：它包含读取响应Body的时间，会中断流式响应；超时由请求Context控制；
+		httpClient:        &http.Client{},
 		responseCodecFunc: NewBackendResponseCodecFunc(),
+		argAssembleFunc:   DefaultArgumentAssemble,
+		requestIdHeader:   flux.XRequestId,
+		websocket:         NewWebSocketProxyWith(flux.NewEmptyConfiguration()),
+		timeout:           defaultRequestTimeout,
 	}
 }
 
 func NewBackendTransportServiceWith(opts ...Option) *BackendTransportService {
 	bts := &BackendTransportService{
-		httpClient: &http.Client{
-			Timeout: time.Second * 10,
-		},
+		// 不设置Client.Timeout：它包含读取响应Body的时间，会中断流式响应；超时由请求Context控制；
+		httpClient:        &http.Client{},
 		responseCodecFunc: NewBackendResponseCodecFunc(),
+		argAssembleFunc:   DefaultArgumentAssemble,
+		requestIdHeader:   flux.XRequestId,
+		websocket:         NewWebSocketProxyWith(flux.NewEmptyConfiguration()),
+		timeout:           defaultRequestTimeout,
 	}
 	for _, opt := range opts {
 		opt(bts)
@@ -74,11 +97,28 @@ func WithArgumentAssembleFunc(fun ArgumentsAssembleFunc) Option {
 	}
 }
 
+// Init init transport
+func (b *BackendTransportService) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyRequestIdHeader: flux.XRequestId,
+		ConfigKeyTimeout:         defaultRequestTimeout,
+	})
+	b.requestIdHeader = config.GetString(ConfigKeyRequestIdHeader)
+	b.timeout = config.GetDuration(ConfigKeyTimeout)
+	b.streamMaxDuration = config.GetDuration(ConfigKeyStreamMaxDuration)
+	b.websocket = NewWebSocketProxyWith(config)
+	return nil
+}
+
 func (b *BackendTransportService) GetResponseCodecFunc() flux.BackendResponseCodecFunc {
 	return b.responseCodecFunc
 }
 
 func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	// WebSocket代理：接管客户端连接，双向转发消息帧
+	if ctx.Endpoint().AttrWebSocket() {
+		return b.websocket.Exchange(ctx, ctx.BackendService(), b.requestIdHeader)
+	}
 	return backend.DoExchangeTransport(ctx, b)
 }
 
@@ -98,6 +138,10 @@ func (b *BackendTransportService) InvokeCodec(ctx flux.Context, service flux.Bac
 			CauseError: fmt.Errorf("decode http response, err: %w", err),
 		}
 	}
+	// 按服务定义的Header策略过滤响应Header；请求时已解析并校验策略定义
+	if policy, err := headerPolicyOf(ctx, service); nil == err {
+		result.Headers = policy.DownstreamHeader(result.Headers)
+	}
 	return result, nil
 }
 
@@ -115,14 +159,30 @@ func (b *BackendTransportService) Invoke(ctx flux.Context, service flux.BackendS
 	return b.ExecuteRequest(newRequest, service, ctx)
 }
 
-func (b *BackendTransportService) ExecuteRequest(newRequest *http.Request, _ flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
-	// Header透传以及传递AttrValues
-	newRequest.Header = ctx.Request().HeaderVars()
-	for k, v := range ctx.Attributes() {
-		newRequest.Header.Set(k, cast.ToString(v))
+func (b *BackendTransportService) ExecuteRequest(newRequest *http.Request, service flux.BackendService, ctx flux.Context) (interface{}, *flux.ServeError) {
+	// 按服务定义的Header策略，透传客户端Header以及传递AttrValues
+	policy, err := headerPolicyOf(ctx, service)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayEndpoint,
+			Message:    flux.ErrorMessageHttpHeaderPolicy,
+			CauseError: err,
+		}
+	}
+	header := policy.UpstreamHeader(ctx, newRequest.Header, b.requestIdHeader)
+	// 请求参数由网关重新编码时，使用重新编码的ContentType
+	if ct := newRequest.Header.Get(flux.HeaderContentType); "" != ct && len(service.Arguments) > 0 {
+		header.Set(flux.HeaderContentType, ct)
 	}
-	resp, err := b.httpClient.Do(newRequest)
+	newRequest.Header = header
+	// 请求超时：普通响应包含读取Body的时间；流式响应在收到响应头后，改为流式响应的最大持续时间；
+	reqctx, cancel := context.WithCancel(newRequest.Context())
+	timer := time.AfterFunc(b.timeoutOf(service), cancel)
+	resp, err := b.httpClient.Do(newRequest.WithContext(reqctx))
 	if nil != err {
+		timer.Stop()
+		cancel()
 		msg := flux.ErrorMessageHttpInvokeFailed
 		if uErr, ok := err.(*url.Error); ok {
 			msg = fmt.Sprintf("HTTPEX:REMOTE_ERROR:%s", uErr.Error())
@@ -134,5 +194,45 @@ func (b *BackendTransportService) ExecuteRequest(newRequest *http.Request, _ flu
 			CauseError: err,
 		}
 	}
+	if flux.IsStreamResponse(resp.Header, resp.TransferEncoding, ctx.Endpoint().AttrStream()) {
+		timer.Stop()
+		if duration := b.streamDurationOf(ctx.Endpoint()); duration > 0 {
+			timer = time.AfterFunc(duration, cancel)
+		}
+	}
+	// 响应Body关闭时，释放请求Context
+	resp.Body = &cancelReadCloser{ReadCloser: resp.Body, cancel: func() {
+		timer.Stop()
+		cancel()
+	}}
 	return resp, nil
 }
+
+func (b *BackendTransportService) timeoutOf(service flux.BackendService) time.Duration {
+	if to := service.AttrRpcTimeout(); "" != to {
+		if timeout, err := time.ParseDuration(to); nil == err && timeout > 0 {
+			return timeout
+		}
+		logger.Warnw("BACKEND:HTTP:ILLEGAL_TIMEOUT", "rpc-timeout", to, "service", service.ServiceID())
+	}
+	return b.timeout
+}
+
+func (b *BackendTransportService) streamDurationOf(endpoint flux.Endpoint) time.Duration {
+	if duration := endpoint.AttrStreamTimeout(); duration > 0 {
+		return duration
+	}
+	return b.streamMaxDuration
+}
+
+// cancelReadCloser 关闭响应Body时，取消请求Context
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (c *cancelReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
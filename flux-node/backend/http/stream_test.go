@@ -0,0 +1,164 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/testkit"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsStreamResponse(t *testing.T) {
+	cases := []struct {
+		contentType      string
+		transferEncoding []string
+		streamChunked    bool
+		expected         bool
+	}{
+		{contentType: "application/json", expected: false},
+		{contentType: "text/event-stream", expected: true},
+		{contentType: "text/event-stream; charset=utf-8", expected: true},
+		{contentType: "application/json", transferEncoding: []string{"chunked"}, expected: false},
+		{contentType: "application/json", transferEncoding: []string{"chunked"}, streamChunked: true, expected: true},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		header := http.Header{flux.HeaderContentType: []string{tcase.contentType}}
+		assert.Equal(tcase.expected, flux.IsStreamResponse(header, tcase.transferEncoding, tcase.streamChunked))
+	}
+}
+
+func newStreamEndpoint(pattern, remoteHost string, attrs ...flux.Attribute) flux.Endpoint {
+	return flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: pattern, Version: "v1",
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: attrs},
+		Service: flux.BackendService{
+			Scheme: "http", RemoteHost: remoteHost, Interface: "/events", Method: "GET",
+			EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+				{Name: flux.ServiceAttrTagRpcProto, Value: flux.ProtoHttp},
+				{Name: flux.ServiceAttrTagRpcTimeout, Value: "100ms"},
+			}},
+		},
+	}
+}
+
+func TestBackendTransportService_ServerSentEvents(t *testing.T) {
+	assert := assert2.New(t)
+	// 后端服务：逐个发送事件，等待客户端收到首个事件后才发送后续事件
+	received := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(flux.HeaderContentType, flux.MIMETextEventStream)
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			_, _ = fmt.Fprintf(w, "data: event-%d\n\n", i)
+			w.(http.Flusher).Flush()
+			if 0 == i {
+				select {
+				case <-received:
+				case <-time.After(time.Second * 3):
+					return
+				}
+			}
+		}
+	}))
+	defer upstream.Close()
+	gw := testkit.NewGateway(t)
+	// 流式响应的持续时间超过rpctimeout，不应被中断
+	gw.AddEndpoint(newStreamEndpoint("/events", strings.TrimPrefix(upstream.URL, "http://")))
+	resp, err := http.Get(gw.URL() + "/events")
+	if !assert.NoError(err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(flux.MIMETextEventStream, resp.Header.Get(flux.HeaderContentType))
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.NoError(err)
+	assert.Equal("data: event-0\n", line)
+	time.Sleep(time.Millisecond * 200)
+	close(received)
+	rest, err := ioutil.ReadAll(reader)
+	assert.NoError(err)
+	assert.Equal("\ndata: event-1\n\ndata: event-2\n\n", string(rest))
+}
+
+func TestBackendTransportService_StreamTimeout(t *testing.T) {
+	assert := assert2.New(t)
+	// 后端服务：持续发送事件，直到请求被取消
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(flux.HeaderContentType, flux.MIMETextEventStream)
+		w.WriteHeader(http.StatusOK)
+		for {
+			if _, err := fmt.Fprint(w, "data: ping\n\n"); nil != err {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond * 20):
+			}
+		}
+	}))
+	defer upstream.Close()
+	gw := testkit.NewGateway(t)
+	gw.AddEndpoint(newStreamEndpoint("/events/limited", strings.TrimPrefix(upstream.URL, "http://"),
+		flux.Attribute{Name: flux.EndpointAttrTagStreamTimeout, Value: "300ms"}))
+	start := time.Now()
+	resp, err := http.Get(gw.URL() + "/events/limited")
+	if !assert.NoError(err) {
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	elapsed := time.Since(start)
+	assert.True(elapsed >= time.Millisecond*300, "elapsed: %s", elapsed)
+	assert.True(elapsed < time.Second*3, "elapsed: %s", elapsed)
+	assert.True(strings.HasPrefix(string(body), "data: ping\n\n"))
+}
+
+func TestBackendTransportService_ChunkedResponse(t *testing.T) {
+	// 后端服务：分块发送数据，发送间隔超过rpctimeout
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(flux.HeaderContentType, "text/plain")
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, "part-0;")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(time.Millisecond * 300):
+		}
+		_, _ = fmt.Fprint(w, "part-1;")
+	}))
+	defer upstream.Close()
+	host := strings.TrimPrefix(upstream.URL, "http://")
+	cases := []struct {
+		pattern  string
+		attrs    []flux.Attribute
+		complete bool
+	}{
+		// 未标记stream的分块响应，仍受rpctimeout限制
+		{pattern: "/chunked/default", complete: false},
+		{pattern: "/chunked/stream", attrs: []flux.Attribute{{Name: flux.EndpointAttrTagStream, Value: "true"}}, complete: true},
+	}
+	assert := assert2.New(t)
+	gw := testkit.NewGateway(t)
+	for _, tcase := range cases {
+		gw.AddEndpoint(newStreamEndpoint(tcase.pattern, host, tcase.attrs...))
+		complete := false
+		if resp, err := http.Get(gw.URL() + tcase.pattern); nil == err {
+			body, _ := ioutil.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			complete = http.StatusOK == resp.StatusCode && "part-0;part-1;" == string(body)
+		}
+		assert.Equal(tcase.complete, complete, tcase.pattern)
+	}
+}
@@ -0,0 +1,349 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ConfigKeyWebSocketIdleTimeout      = "websocket_idle_timeout"
+	ConfigKeyWebSocketHandshakeTimeout = "websocket_handshake_timeout"
+	ConfigKeyWebSocketMaxMessageSize   = "websocket_max_message_size"
+	ConfigKeyWebSocketAllowedOrigins   = "websocket_allowed_origins"
+)
+
+const (
+	wsDirectionUpstream   = "upstream"
+	wsDirectionDownstream = "downstream"
+)
+
+var (
+	// 握手时由Dialer生成，不能透传到后端的请求Header
+	wsHandshakeHeaders = []string{
+		"Host", "Upgrade", "Connection", "Content-Length", "Transfer-Encoding", "Keep-Alive", "Te", "Trailer",
+		"Proxy-Connection", "Proxy-Authorization", "Proxy-Authenticate",
+		"Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions",
+	}
+)
+
+// WebSocketMetrics WebSocket代理的连接和消息指标
+type WebSocketMetrics struct {
+	Connections *prometheus.CounterVec
+	Active      *prometheus.GaugeVec
+	Duration    *prometheus.HistogramVec
+	Messages    *prometheus.CounterVec
+	Bytes       *prometheus.CounterVec
+}
+
+// NewWebSocketMetrics 创建并注册指标；如果已注册相同指标，使用已注册的指标实例；
+func NewWebSocketMetrics() *WebSocketMetrics {
+	return &WebSocketMetrics{
+		Connections: registerCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flux",
+			Subsystem: "websocket",
+			Name:      "connections_total",
+			Help:      "Number of proxied websocket connections",
+		}, []string{"service"})).(*prometheus.CounterVec),
+		Active: registerCollector(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "flux",
+			Subsystem: "websocket",
+			Name:      "connections_active",
+			Help:      "Number of active proxied websocket connections",
+		}, []string{"service"})).(*prometheus.GaugeVec),
+		Duration: registerCollector(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "flux",
+			Subsystem: "websocket",
+			Name:      "connection_duration_seconds",
+			Help:      "Lifetime of proxied websocket connections",
+			Buckets:   []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600},
+		}, []string{"service"})).(*prometheus.HistogramVec),
+		Messages: registerCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flux",
+			Subsystem: "websocket",
+			Name:      "messages_total",
+			Help:      "Number of proxied websocket messages",
+		}, []string{"service", "direction"})).(*prometheus.CounterVec),
+		Bytes: registerCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flux",
+			Subsystem: "websocket",
+			Name:      "message_bytes_total",
+			Help:      "Size of proxied websocket messages",
+		}, []string{"service", "direction"})).(*prometheus.CounterVec),
+	}
+}
+
+// WebSocketProxy 接管客户端的WebSocket握手请求，连接后端服务RemoteHost，并双向转发消息帧；
+// 握手请求与普通请求一样经过Filter链（鉴权、权限验证等），在Backend交换阶段才升级连接；
+type WebSocketProxy struct {
+	dialer         *websocket.Dialer
+	idleTimeout    time.Duration
+	maxMessageSize int64
+	allowedOrigins []string
+	metrics        *WebSocketMetrics
+}
+
+// NewWebSocketProxyWith 根据配置创建；配置项：
+// websocket_idle_timeout: 连接空闲超时时间，双向均无消息时关闭连接；
+// websocket_handshake_timeout: 与后端服务握手的超时时间；
+// websocket_max_message_size: 单个消息的最大字节数，超过时关闭连接；
+// websocket_allowed_origins: 允许的Origin列表；未配置时只允许同源请求；配置 * 时允许全部；
+func NewWebSocketProxyWith(config *flux.Configuration) *WebSocketProxy {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyWebSocketIdleTimeout:      time.Minute,
+		ConfigKeyWebSocketHandshakeTimeout: time.Second * 10,
+		ConfigKeyWebSocketMaxMessageSize:   1024 * 1024,
+	})
+	return &WebSocketProxy{
+		dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: config.GetDuration(ConfigKeyWebSocketHandshakeTimeout),
+		},
+		idleTimeout:    config.GetDuration(ConfigKeyWebSocketIdleTimeout),
+		maxMessageSize: config.GetInt64(ConfigKeyWebSocketMaxMessageSize),
+		allowedOrigins: config.GetStringSlice(ConfigKeyWebSocketAllowedOrigins),
+		metrics:        NewWebSocketMetrics(),
+	}
+}
+
+// Exchange 升级客户端连接，并转发消息直到任意一方关闭连接或者请求被取消
+func (p *WebSocketProxy) Exchange(ctx flux.Context, service flux.BackendService, requestIdHeader string) *flux.ServeError {
+	webex := ctx.Exchange()
+	if nil == webex {
+		return newWebSocketError(flux.StatusServerError, flux.ErrorMessageWebSocketNotSupported, errors.New("web exchange not found"))
+	}
+	request, err := webex.HttpRequest()
+	if nil != err {
+		return newWebSocketError(flux.StatusServerError, flux.ErrorMessageWebSocketNotSupported, err)
+	}
+	writer, err := webex.HttpResponseWriter()
+	if nil != err {
+		return newWebSocketError(flux.StatusServerError, flux.ErrorMessageWebSocketNotSupported, err)
+	}
+	if !websocket.IsWebSocketUpgrade(request) {
+		return newWebSocketError(flux.StatusBadRequest, flux.ErrorMessageWebSocketUpgradeRequired,
+			errors.New("request is not a websocket upgrade"))
+	}
+	if !p.checkOrigin(request) {
+		return newWebSocketError(flux.StatusAccessDenied, flux.ErrorMessageWebSocketUpgradeFailed,
+			fmt.Errorf("origin not allowed: %s", request.Header.Get("Origin")))
+	}
+	policy, err := ParseHeaderPolicy(service)
+	if nil != err {
+		return newWebSocketError(flux.StatusServerError, flux.ErrorMessageHttpHeaderPolicy, err)
+	}
+	// 先连接后端服务，后端握手失败时，可以向客户端返回Http错误响应
+	upstreamURL := WebSocketURL(service, request.URL)
+	upstream, resp, err := p.dialer.DialContext(ctx.Context(), upstreamURL, p.upstreamHeader(ctx, request, policy, requestIdHeader))
+	if nil != err {
+		if nil != resp {
+			err = fmt.Errorf("%w, status: %d", err, resp.StatusCode)
+		}
+		return newWebSocketError(flux.StatusBadGateway, flux.ErrorMessageWebSocketDialFailed, err)
+	}
+	status := flux.StatusOK
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(*http.Request) bool {
+			return true
+		},
+		Error: func(_ http.ResponseWriter, _ *http.Request, code int, _ error) {
+			status = code
+		},
+	}
+	header := http.Header{}
+	if proto := upstream.Subprotocol(); "" != proto {
+		header.Set("Sec-WebSocket-Protocol", proto)
+	}
+	client, err := upgrader.Upgrade(writer, request, header)
+	if nil != err {
+		_ = upstream.Close()
+		return newWebSocketError(status, flux.ErrorMessageWebSocketUpgradeFailed, err)
+	}
+	ctx.Response().SetStatusCode(flux.StatusSwitchingProtocols)
+	p.serve(ctx, client, upstream, upstreamURL)
+	return nil
+}
+
+func (p *WebSocketProxy) serve(ctx flux.Context, client, upstream *websocket.Conn, upstreamURL string) {
+	serviceId := ctx.BackendServiceId()
+	start := time.Now()
+	p.metrics.Connections.WithLabelValues(serviceId).Inc()
+	active := p.metrics.Active.WithLabelValues(serviceId)
+	active.Inc()
+	trace := logger.TraceContext(ctx)
+	trace.Infow("BACKEND:WEBSOCKET:OPEN", "upstream", upstreamURL)
+	defer func() {
+		active.Dec()
+		elapsed := time.Since(start)
+		p.metrics.Duration.WithLabelValues(serviceId).Observe(elapsed.Seconds())
+		ctx.AddMetric("websocket", elapsed)
+		trace.Infow("BACKEND:WEBSOCKET:CLOSE", "upstream", upstreamURL, "elapses", elapsed.String())
+	}()
+	errs := make(chan error, 2)
+	go func() {
+		errs <- p.pump(upstream, client, serviceId, wsDirectionUpstream)
+	}()
+	go func() {
+		errs <- p.pump(client, upstream, serviceId, wsDirectionDownstream)
+	}()
+	var err error
+	received := 0
+	select {
+	case err = <-errs:
+		received++
+	case <-ctx.Context().Done():
+		// 请求被取消（例如服务停止），通知双方关闭连接
+		err = &websocket.CloseError{Code: websocket.CloseGoingAway, Text: "gateway closing"}
+		closeWebSocket(client, err)
+		closeWebSocket(upstream, err)
+	}
+	if nil != err && !isCloseError(err) {
+		trace.Infow("BACKEND:WEBSOCKET:ERROR", "upstream", upstreamURL, "error", err)
+	}
+	_ = client.Close()
+	_ = upstream.Close()
+	// 等待转发结束
+	for ; received < 2; received++ {
+		<-errs
+	}
+}
+
+// pump 从src读取消息并写入dst；src关闭或者出错时，向dst发送关闭帧
+func (p *WebSocketProxy) pump(dst, src *websocket.Conn, serviceId, direction string) error {
+	src.SetReadLimit(p.maxMessageSize)
+	extend := func(string) error {
+		return src.SetReadDeadline(p.deadline())
+	}
+	src.SetPongHandler(extend)
+	src.SetPingHandler(func(data string) error {
+		_ = extend(data)
+		err := src.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		if err == websocket.ErrCloseSent {
+			return nil
+		} else if e, ok := err.(net.Error); ok && e.Temporary() {
+			return nil
+		}
+		return err
+	})
+	messages := p.metrics.Messages.WithLabelValues(serviceId, direction)
+	bytes := p.metrics.Bytes.WithLabelValues(serviceId, direction)
+	for {
+		_ = src.SetReadDeadline(p.deadline())
+		mtype, data, err := src.ReadMessage()
+		if nil != err {
+			closeWebSocket(dst, err)
+			return err
+		}
+		_ = dst.SetWriteDeadline(p.deadline())
+		if err := dst.WriteMessage(mtype, data); nil != err {
+			closeWebSocket(src, err)
+			return err
+		}
+		messages.Inc()
+		bytes.Add(float64(len(data)))
+	}
+}
+
+func (p *WebSocketProxy) deadline() time.Time {
+	if p.idleTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(p.idleTimeout)
+}
+
+// upstreamHeader 按Header策略透传客户端Header以及AttrValues，移除握手相关的Header；子协议始终透传给后端
+func (p *WebSocketProxy) upstreamHeader(ctx flux.Context, request *http.Request, policy HeaderPolicy, requestIdHeader string) http.Header {
+	header := policy.UpstreamHeader(ctx, nil, requestIdHeader)
+	for _, name := range wsHandshakeHeaders {
+		header.Del(name)
+	}
+	if protocols := request.Header.Values("Sec-WebSocket-Protocol"); len(protocols) > 0 {
+		header["Sec-Websocket-Protocol"] = protocols
+	}
+	return header
+}
+
+func (p *WebSocketProxy) checkOrigin(request *http.Request) bool {
+	origin := request.Header.Get("Origin")
+	if "" == origin {
+		return true
+	}
+	if len(p.allowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return nil == err && strings.EqualFold(u.Host, request.Host)
+	}
+	for _, allowed := range p.allowedOrigins {
+		if "*" == allowed || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebSocketURL 构建后端服务的WebSocket地址：Scheme为http/https时，转换为ws/wss；
+func WebSocketURL(service flux.BackendService, inURL *url.URL) string {
+	scheme := strings.ToLower(service.Scheme)
+	switch scheme {
+	case "https", "wss":
+		scheme = "wss"
+	default:
+		scheme = "ws"
+	}
+	out := &url.URL{
+		Scheme: scheme,
+		Host:   service.RemoteHost,
+		Path:   service.Interface,
+	}
+	if nil != inURL {
+		out.RawQuery = inURL.RawQuery
+	}
+	return out.String()
+}
+
+func closeWebSocket(conn *websocket.Conn, cause error) {
+	code, text := websocket.CloseGoingAway, ""
+	if ce, ok := cause.(*websocket.CloseError); ok {
+		code, text = ce.Code, ce.Text
+		// 1005/1006 为保留状态码，不能在关闭帧中发送
+		if code == websocket.CloseNoStatusReceived || code == websocket.CloseAbnormalClosure {
+			code, text = websocket.CloseNormalClosure, ""
+		}
+	} else if cause == websocket.ErrReadLimit {
+		code = websocket.CloseMessageTooBig
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+}
+
+func isCloseError(err error) bool {
+	_, ok := err.(*websocket.CloseError)
+	return ok
+}
+
+func newWebSocketError(status int, message string, cause error) *flux.ServeError {
+	return &flux.ServeError{
+		StatusCode: status,
+		ErrorCode:  flux.ErrorCodeGatewayBackend,
+		Message:    message,
+		CauseError: cause,
+	}
+}
+
+// 注册指标；如果已注册相同指标，返回已注册的指标实例；
+func registerCollector(c prometheus.Collector) prometheus.Collector {
+	if err := prometheus.Register(c); nil != err {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/boot"
+	"github.com/bytepowered/flux/flux-node/testkit"
+	"github.com/gorilla/websocket"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWebSocketURL(t *testing.T) {
+	cases := []struct {
+		scheme   string
+		query    string
+		expected string
+	}{
+		{scheme: "", expected: "ws://127.0.0.1:8080/chat"},
+		{scheme: "http", query: "room=1", expected: "ws://127.0.0.1:8080/chat?room=1"},
+		{scheme: "HTTPS", expected: "wss://127.0.0.1:8080/chat"},
+		{scheme: "wss", expected: "wss://127.0.0.1:8080/chat"},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		service := flux.BackendService{Scheme: tcase.scheme, RemoteHost: "127.0.0.1:8080", Interface: "/chat"}
+		assert.Equal(tcase.expected, WebSocketURL(service, &url.URL{RawQuery: tcase.query}))
+	}
+}
+
+func TestWebSocketProxy_CheckOrigin(t *testing.T) {
+	cases := []struct {
+		allowed  []string
+		origin   string
+		expected bool
+	}{
+		{origin: "", expected: true},
+		{origin: "http://gateway.com", expected: true},
+		{origin: "http://evil.com", expected: false},
+		{allowed: []string{"http://app.com"}, origin: "http://app.com", expected: true},
+		{allowed: []string{"http://app.com"}, origin: "http://gateway.com", expected: false},
+		{allowed: []string{"*"}, origin: "http://evil.com", expected: true},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		config := flux.NewEmptyConfiguration()
+		config.Set(ConfigKeyWebSocketAllowedOrigins, tcase.allowed)
+		proxy := NewWebSocketProxyWith(config)
+		req := httptest.NewRequest(http.MethodGet, "http://gateway.com/ws", nil)
+		if "" != tcase.origin {
+			req.Header.Set("Origin", tcase.origin)
+		}
+		assert.Equal(tcase.expected, proxy.checkOrigin(req), "origin: %s", tcase.origin)
+	}
+}
+
+func TestWebSocketProxy_Exchange(t *testing.T) {
+	assert := assert2.New(t)
+	// 后端服务：回显消息，并在首个消息中返回鉴权Header
+	upgrader := websocket.Upgrader{Subprotocols: []string{"chat"}}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if nil != err {
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteMessage(websocket.TextMessage, []byte("tenant:"+r.Header.Get("tenant")))
+		for {
+			mtype, data, err := conn.ReadMessage()
+			if nil != err {
+				return
+			}
+			_ = conn.WriteMessage(mtype, data)
+		}
+	}))
+	defer upstream.Close()
+	gw := testkit.NewGateway(t, testkit.WithServerOptions(boot.WithWebExchangeHooks(func(webex flux.WebExchange, ctx flux.Context) {
+		ctx.SetAttribute("tenant", webex.HeaderVar("X-Tenant"))
+	})))
+	gw.AddEndpoint(flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/ws/chat", Version: "v1",
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+			{Name: flux.EndpointAttrTagWebSocket, Value: true},
+		}},
+		Service: flux.BackendService{
+			Scheme: "http", RemoteHost: strings.TrimPrefix(upstream.URL, "http://"),
+			Interface: "/chat", Method: "GET",
+			EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+				{Name: flux.ServiceAttrTagRpcProto, Value: flux.ProtoHttp},
+			}},
+		},
+	})
+	dialer := websocket.Dialer{Subprotocols: []string{"chat"}}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(gw.URL(), "http")+"/ws/chat",
+		http.Header{"X-Tenant": []string{"t1"}})
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+	assert.Equal(http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal("chat", conn.Subprotocol())
+	_, data, err := conn.ReadMessage()
+	assert.NoError(err)
+	assert.Equal("tenant:t1", string(data))
+	for _, msg := range []string{"hello", "flux"} {
+		assert.NoError(conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+		mtype, data, err := conn.ReadMessage()
+		assert.NoError(err)
+		assert.Equal(websocket.TextMessage, mtype)
+		assert.Equal(msg, string(data))
+	}
+}
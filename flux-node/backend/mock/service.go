@@ -0,0 +1,254 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/backend"
+	"github.com/bytepowered/flux/flux-node/common"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/spf13/cast"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Mock响应定义的服务属性（Attributes）
+const (
+	AttrTagMockStatus      = "mockstatus"      // 响应状态码，默认200
+	AttrTagMockHeader      = "mockheader"      // 响应Header，格式：Name:Value；可定义多个
+	AttrTagMockBody        = "mockbody"        // 响应Body模板
+	AttrTagMockDelay       = "mockdelay"       // 模拟响应延迟，格式：200ms, 1s
+	AttrTagMockErrorRate   = "mockerrorrate"   // 模拟错误的概率，取值：0~1
+	AttrTagMockErrorStatus = "mockerrorstatus" // 模拟错误的状态码，默认500
+)
+
+// Mock响应定义的服务扩展信息（Extensions），扩展信息的定义优先于属性定义
+const (
+	ExtensionMock = "mock"
+)
+
+const (
+	ErrorMessageMockInjected = "BACKEND:MK:INJECTED_ERROR"
+	ErrorMessageMockCanceled = "BACKEND:MK:CANCELED"
+)
+
+func init() {
+	ext.RegisterBackendTransport(flux.ProtoMock, NewBackendTransportService())
+}
+
+var _ flux.BackendTransport = new(BackendTransportService)
+
+type (
+	// Option 配置函数
+	Option func(service *BackendTransportService)
+	// RandomFunc 返回[0,1)区间的随机数，用于按概率模拟错误
+	RandomFunc func() float64
+)
+
+// Response 定义Mock响应
+type Response struct {
+	Status      int
+	Headers     map[string]string
+	Body        string
+	Delay       time.Duration
+	ErrorRate   float64
+	ErrorStatus int
+}
+
+// BackendTransportService 根据服务的属性或者扩展信息定义，返回预设响应的后端协议；
+// 用于在后端服务未就绪时进行前端开发联调，或者模拟后端延迟与错误；
+type BackendTransportService struct {
+	responseCodecFunc flux.BackendResponseCodecFunc
+	randomFunc        RandomFunc
+}
+
+func NewBackendTransportService() *BackendTransportService {
+	return NewBackendTransportServiceWith()
+}
+
+func NewBackendTransportServiceWith(opts ...Option) *BackendTransportService {
+	bts := &BackendTransportService{
+		responseCodecFunc: NewBackendResponseCodecFunc(),
+		randomFunc:        rand.Float64,
+	}
+	for _, opt := range opts {
+		opt(bts)
+	}
+	return bts
+}
+
+// WithResponseCodecFunc 用于配置响应数据解析实现函数
+func WithResponseCodecFunc(fun flux.BackendResponseCodecFunc) Option {
+	return func(service *BackendTransportService) {
+		service.responseCodecFunc = fun
+	}
+}
+
+// WithRandomFunc 用于配置模拟错误的随机数函数
+func WithRandomFunc(fun RandomFunc) Option {
+	return func(service *BackendTransportService) {
+		service.randomFunc = fun
+	}
+}
+
+func (b *BackendTransportService) GetResponseCodecFunc() flux.BackendResponseCodecFunc {
+	return b.responseCodecFunc
+}
+
+func (b *BackendTransportService) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchangeTransport(ctx, b)
+}
+
+func (b *BackendTransportService) InvokeCodec(ctx flux.Context, service flux.BackendService) (*flux.BackendResponse, *flux.ServeError) {
+	resp, serr := b.Invoke(ctx, service)
+	if nil != serr {
+		return nil, serr
+	}
+	codec, err := b.responseCodecFunc(ctx, resp)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageBackendDecodeResponse,
+			CauseError: err,
+		}
+	}
+	return codec, nil
+}
+
+func (b *BackendTransportService) Invoke(ctx flux.Context, service flux.BackendService) (interface{}, *flux.ServeError) {
+	mock, err := ParseResponse(service)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayEndpoint,
+			Message:    flux.ErrorMessageBackendDecodeResponse,
+			CauseError: err,
+		}
+	}
+	if mock.Delay > 0 {
+		timer := time.NewTimer(mock.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			break
+		case <-ctx.Context().Done():
+			return nil, &flux.ServeError{
+				StatusCode: flux.StatusBadRequest,
+				ErrorCode:  flux.ErrorCodeGatewayCanceled,
+				Message:    ErrorMessageMockCanceled,
+				CauseError: ctx.Context().Err(),
+			}
+		}
+	}
+	if mock.ErrorRate > 0 && b.randomFunc() < mock.ErrorRate {
+		return nil, &flux.ServeError{
+			StatusCode: mock.ErrorStatus,
+			ErrorCode:  flux.ErrorCodeGatewayBackend,
+			Message:    ErrorMessageMockInjected,
+			CauseError: errors.New("mock injected error"),
+		}
+	}
+	header := make(http.Header, len(mock.Headers))
+	for k, v := range mock.Headers {
+		header.Set(k, common.RenderTemplate(v, ctx))
+	}
+	return &flux.BackendResponse{
+		StatusCode: mock.Status,
+		Headers:    header,
+		Body:       []byte(common.RenderTemplate(mock.Body, ctx)),
+	}, nil
+}
+
+func NewBackendResponseCodecFunc() flux.BackendResponseCodecFunc {
+	return func(ctx flux.Context, value interface{}) (*flux.BackendResponse, error) {
+		if resp, ok := value.(*flux.BackendResponse); ok {
+			return resp, nil
+		}
+		return nil, fmt.Errorf("unexpected mock response type: %T", value)
+	}
+}
+
+// ParseResponse 解析服务定义的Mock响应；扩展信息mock中定义的字段，覆盖属性中的定义；
+func ParseResponse(service flux.BackendService) (Response, error) {
+	out := Response{
+		Status:      http.StatusOK,
+		Headers:     make(map[string]string, 4),
+		Body:        service.GetAttr(AttrTagMockBody).GetString(),
+		ErrorStatus: flux.StatusServerError,
+	}
+	if v := service.GetAttr(AttrTagMockStatus).GetInt(); v > 0 {
+		out.Status = v
+	}
+	for _, attr := range service.GetAttrs(AttrTagMockHeader) {
+		pair := strings.SplitN(attr.GetString(), ":", 2)
+		if len(pair) != 2 || "" == strings.TrimSpace(pair[0]) {
+			return out, fmt.Errorf("invalid mock header: %s", attr.GetString())
+		}
+		out.Headers[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+	}
+	if v := service.GetAttr(AttrTagMockErrorStatus).GetInt(); v > 0 {
+		out.ErrorStatus = v
+	}
+	if err := parseDelay(service.GetAttr(AttrTagMockDelay).Value, &out); nil != err {
+		return out, err
+	}
+	if err := parseErrorRate(service.GetAttr(AttrTagMockErrorRate).Value, &out); nil != err {
+		return out, err
+	}
+	define, ok := service.GetValue(ExtensionMock)
+	if !ok || nil == define {
+		return out, nil
+	}
+	values, err := cast.ToStringMapE(define)
+	if nil != err {
+		return out, fmt.Errorf("invalid mock extension, error: %w", err)
+	}
+	if v, ok := values["status"]; ok {
+		out.Status = cast.ToInt(v)
+	}
+	if v, ok := values["headers"]; ok {
+		for k, hv := range cast.ToStringMapString(v) {
+			out.Headers[k] = hv
+		}
+	}
+	if v, ok := values["body"]; ok {
+		out.Body = cast.ToString(v)
+	}
+	if v, ok := values["errorStatus"]; ok {
+		out.ErrorStatus = cast.ToInt(v)
+	}
+	if err := parseDelay(values["delay"], &out); nil != err {
+		return out, err
+	}
+	if err := parseErrorRate(values["errorRate"], &out); nil != err {
+		return out, err
+	}
+	return out, nil
+}
+
+func parseDelay(value interface{}, out *Response) error {
+	if nil == value || "" == value {
+		return nil
+	}
+	delay, err := cast.ToDurationE(value)
+	if nil != err || delay < 0 {
+		return fmt.Errorf("invalid mock delay: %v", value)
+	}
+	out.Delay = delay
+	return nil
+}
+
+func parseErrorRate(value interface{}, out *Response) error {
+	if nil == value || "" == value {
+		return nil
+	}
+	rate, err := cast.ToFloat64E(value)
+	if nil != err || rate < 0 || rate > 1 {
+		return fmt.Errorf("invalid mock error rate: %v", value)
+	}
+	out.ErrorRate = rate
+	return nil
+}
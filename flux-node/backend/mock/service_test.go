@@ -0,0 +1,111 @@
+package mock
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/context"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newMockService(attrs []flux.Attribute, extensions map[string]interface{}) flux.BackendService {
+	return flux.BackendService{
+		Interface:          "com.foo.UserService",
+		Method:             "get",
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: attrs},
+		EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: extensions},
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	cases := []struct {
+		service  flux.BackendService
+		expected Response
+		error    bool
+	}{
+		{
+			service: newMockService(nil, nil),
+			expected: Response{Status: http.StatusOK, Headers: map[string]string{},
+				ErrorStatus: http.StatusInternalServerError},
+		},
+		{
+			service: newMockService([]flux.Attribute{
+				{Name: AttrTagMockStatus, Value: 201},
+				{Name: AttrTagMockHeader, Value: "X-Mock: true"},
+				{Name: AttrTagMockHeader, Value: "X-Id:${path:id}"},
+				{Name: AttrTagMockBody, Value: `{"id":"${path:id}"}`},
+				{Name: AttrTagMockDelay, Value: "20ms"},
+				{Name: AttrTagMockErrorRate, Value: "0.5"},
+				{Name: AttrTagMockErrorStatus, Value: 503},
+			}, nil),
+			expected: Response{Status: 201, Headers: map[string]string{"X-Mock": "true", "X-Id": "${path:id}"},
+				Body: `{"id":"${path:id}"}`, Delay: 20 * time.Millisecond, ErrorRate: 0.5, ErrorStatus: 503},
+		},
+		{
+			service: newMockService([]flux.Attribute{
+				{Name: AttrTagMockStatus, Value: 201},
+				{Name: AttrTagMockBody, Value: "attr"},
+			}, map[string]interface{}{
+				ExtensionMock: map[string]interface{}{
+					"body":    "extension",
+					"headers": map[string]interface{}{"X-Mock": "ext"},
+					"delay":   "1s",
+				},
+			}),
+			expected: Response{Status: 201, Headers: map[string]string{"X-Mock": "ext"},
+				Body: "extension", Delay: time.Second, ErrorStatus: http.StatusInternalServerError},
+		},
+		{
+			service: newMockService([]flux.Attribute{{Name: AttrTagMockHeader, Value: "invalid"}}, nil),
+			error:   true,
+		},
+		{
+			service: newMockService([]flux.Attribute{{Name: AttrTagMockErrorRate, Value: 1.5}}, nil),
+			error:   true,
+		},
+		{
+			service: newMockService([]flux.Attribute{{Name: AttrTagMockDelay, Value: "abc"}}, nil),
+			error:   true,
+		},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		out, err := ParseResponse(tcase.service)
+		if tcase.error {
+			assert.Error(err)
+			continue
+		}
+		assert.NoError(err)
+		assert.Equal(tcase.expected, out)
+	}
+}
+
+func TestBackendTransportService_Invoke(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := context.NewMockWith("mock", map[string]interface{}{"id": "123", "path-values": url.Values{}})
+	service := newMockService([]flux.Attribute{
+		{Name: AttrTagMockStatus, Value: 202},
+		{Name: AttrTagMockHeader, Value: "X-Id:${path:id}"},
+		{Name: AttrTagMockBody, Value: `{"id":${path:id}}`},
+		{Name: AttrTagMockErrorRate, Value: 0.3},
+	}, nil)
+	// 随机数大于错误率，返回预设响应
+	transport := NewBackendTransportServiceWith(WithRandomFunc(func() float64 {
+		return 0.5
+	}))
+	resp, serr := transport.InvokeCodec(ctx, service)
+	assert.Nil(serr)
+	assert.Equal(202, resp.StatusCode)
+	assert.Equal("123", resp.Headers.Get("X-Id"))
+	assert.Equal([]byte(`{"id":123}`), resp.Body)
+	// 随机数小于错误率，返回模拟错误
+	transport = NewBackendTransportServiceWith(WithRandomFunc(func() float64 {
+		return 0.1
+	}))
+	_, serr = transport.InvokeCodec(ctx, service)
+	assert.NotNil(serr)
+	assert.Equal(http.StatusInternalServerError, serr.StatusCode)
+	assert.Equal(ErrorMessageMockInjected, serr.Message)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/transform"
 	"github.com/bytepowered/flux/flux-pkg"
 )
 
@@ -34,7 +35,17 @@ func DoExchangeTransport(ctx flux.Context, transport flux.BackendTransport) *flu
 			writer.AddHeader(k, v)
 		}
 	}
-	writer.SetPayload(response.Body)
+	// 按Endpoint定义的规则转换响应数据
+	rules, perr := transform.ParseRules(ctx.Endpoint())
+	if nil != perr {
+		return &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayEndpoint,
+			Message:    flux.ErrorMessageTransformInvalid,
+			CauseError: perr,
+		}
+	}
+	writer.SetPayload(rules.Apply(response.Body))
 	return nil
 }
 
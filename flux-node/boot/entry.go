@@ -1,11 +1,9 @@
 package boot
 
 import (
-	"context"
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-node/ext"
 	"github.com/bytepowered/flux/flux-node/logger"
-	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"net/http"
@@ -25,15 +23,8 @@ func InitLogger() {
 	sugar := logger.NewZapLogger(config)
 	logger.SetSimpleLogger(sugar)
 	zap.ReplaceGlobals(sugar.Desugar())
-	ext.SetLoggerFactory(func(values context.Context) flux.Logger {
-		if nil == values {
-			return sugar
-		}
-		if traceId := values.Value(logger.TraceId); nil != traceId {
-			return sugar.With(zap.String(logger.TraceId, cast.ToString(traceId)))
-		}
-		return sugar
-	})
+	// 与默认Factory相同，附加TraceId和Extras字段；运行时调试窗口按其中的appid匹配
+	ext.SetLoggerFactory(logger.SugaredFactory(sugar))
 }
 
 func InitAppConfig(envKey string) {
@@ -0,0 +1,89 @@
+package boot
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"net/http"
+	"sync/atomic"
+)
+
+const (
+	HealthStatusUp   = "UP"
+	HealthStatusDown = "DOWN"
+)
+
+// Drain 设置服务为排空状态：就绪检查返回未就绪，并拒绝新的请求；
+func (s *BootstrapServer) Drain() {
+	if atomic.CompareAndSwapInt32(&s.draining, 0, 1) {
+		logger.Info("Server draining, stop accepting new requests")
+	}
+}
+
+// IsDraining 返回服务是否处于排空状态
+func (s *BootstrapServer) IsDraining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+// IsReady 返回服务是否已就绪，以及各项检查的状态
+func (s *BootstrapServer) IsReady() (bool, map[string]string) {
+	checks := make(map[string]string, 8)
+	ready := true
+	check := func(name string, ok bool) {
+		if ok {
+			checks[name] = HealthStatusUp
+		} else {
+			checks[name] = HealthStatusDown
+			ready = false
+		}
+	}
+	select {
+	case <-s.started:
+		check("server", true)
+	default:
+		check("server", false)
+	}
+	check("draining", !s.IsDraining())
+	check("transports", atomic.LoadInt32(&s.router.started) == 1)
+	for _, dis := range s.endpointDiscoveries() {
+		if r, ok := dis.(flux.Readiness); ok {
+			check("discovery:"+dis.Id(), r.Ready())
+		}
+	}
+	for proto, transport := range ext.BackendTransports() {
+		if r, ok := transport.(flux.Readiness); ok {
+			check("transport:"+proto, r.Ready())
+		}
+	}
+	return ready, checks
+}
+
+// HandleLiveness 存活检查：服务进程可响应即为存活
+func (s *BootstrapServer) HandleLiveness(webex flux.WebExchange) error {
+	return webex.Send(webex, http.Header{}, flux.StatusOK, map[string]interface{}{
+		"status": HealthStatusUp,
+	})
+}
+
+// HandleReadiness 就绪检查：服务已启动、元数据初始快照已加载、后端服务已启动，并且不处于排空状态；
+func (s *BootstrapServer) HandleReadiness(webex flux.WebExchange) error {
+	ready, checks := s.IsReady()
+	status, code := HealthStatusUp, flux.StatusOK
+	if !ready {
+		status, code = HealthStatusDown, http.StatusServiceUnavailable
+	}
+	return webex.Send(webex, http.Header{}, code, map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// HandleDrain 设置服务为排空状态，通常在服务停止前调用，使负载均衡摘除当前实例
+func (s *BootstrapServer) HandleDrain(webex flux.WebExchange) error {
+	logger.Infow("Server drain requested", "address", webex.Address())
+	s.Drain()
+	return webex.Send(webex, http.Header{}, flux.StatusOK, map[string]interface{}{
+		"status":   HealthStatusDown,
+		"draining": true,
+	})
+}
@@ -0,0 +1,110 @@
+package boot
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	inflightWaitInterval = time.Millisecond * 50
+	// DefaultCloseTimeout 等待请求超时后，关闭WebListener和其它组件的最长时间
+	DefaultCloseTimeout = time.Second * 5
+)
+
+// InflightRequest 正在处理中的请求
+type InflightRequest struct {
+	RequestId string        `json:"requestId"`
+	Method    string        `json:"method"`
+	URI       string        `json:"uri"`
+	StartAt   time.Time     `json:"startAt"`
+	Elapsed   time.Duration `json:"elapsed"`
+	cancel    context.CancelFunc
+}
+
+// InflightTracker 跟踪正在处理中的请求，用于服务停止时等待请求完成，或者强制取消超时的请求
+type InflightTracker struct {
+	sequence uint64
+	count    int64
+	requests map[uint64]*InflightRequest
+	mutex    sync.Mutex
+}
+
+func NewInflightTracker() *InflightTracker {
+	return &InflightTracker{
+		requests: make(map[uint64]*InflightRequest, 64),
+	}
+}
+
+// Add 添加跟踪请求，返回跟踪Key；请求结束后，必须调用 Done 移除；
+func (t *InflightTracker) Add(requestId, method, uri string, cancel context.CancelFunc) uint64 {
+	key := atomic.AddUint64(&t.sequence, 1)
+	t.mutex.Lock()
+	t.requests[key] = &InflightRequest{
+		RequestId: requestId,
+		Method:    method,
+		URI:       uri,
+		StartAt:   time.Now(),
+		cancel:    cancel,
+	}
+	t.mutex.Unlock()
+	atomic.AddInt64(&t.count, 1)
+	return key
+}
+
+// Done 移除跟踪请求
+func (t *InflightTracker) Done(key uint64) {
+	t.mutex.Lock()
+	if _, ok := t.requests[key]; ok {
+		delete(t.requests, key)
+		atomic.AddInt64(&t.count, -1)
+	}
+	t.mutex.Unlock()
+}
+
+// Count 返回正在处理中的请求数量
+func (t *InflightTracker) Count() int64 {
+	return atomic.LoadInt64(&t.count)
+}
+
+// Wait 等待全部请求处理完成，直到Context超时；返回请求是否已全部完成；
+func (t *InflightTracker) Wait(ctx context.Context) bool {
+	ticker := time.NewTicker(inflightWaitInterval)
+	defer ticker.Stop()
+	for {
+		if t.Count() == 0 {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return t.Count() == 0
+		case <-ticker.C:
+			continue
+		}
+	}
+}
+
+// CancelAll 强制取消全部正在处理中的请求，返回被取消的请求列表
+func (t *InflightTracker) CancelAll() []InflightRequest {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	out := make([]InflightRequest, 0, len(t.requests))
+	for _, req := range t.requests {
+		req.cancel()
+		copied := *req
+		copied.Elapsed = time.Since(req.StartAt)
+		copied.cancel = nil
+		out = append(out, copied)
+	}
+	return out
+}
+
+// closingContext 返回关闭组件使用的Context：等待请求的Context未过期时继续使用；
+// 已过期时，创建限定时间的新Context，保证WebListener和BackendTransport有时间完成关闭；
+func closingContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if nil == ctx.Err() {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
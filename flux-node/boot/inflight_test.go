@@ -0,0 +1,61 @@
+package boot
+
+import (
+	"context"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestInflightTracker_Wait(t *testing.T) {
+	assert := assert2.New(t)
+	tracker := NewInflightTracker()
+	key := tracker.Add("id-1", "GET", "/a", func() {})
+	assert.Equal(int64(1), tracker.Count())
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		tracker.Done(key)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.True(tracker.Wait(ctx))
+	assert.Equal(int64(0), tracker.Count())
+	// Done twice
+	tracker.Done(key)
+	assert.Equal(int64(0), tracker.Count())
+}
+
+func TestInflightTracker_CancelAll(t *testing.T) {
+	assert := assert2.New(t)
+	tracker := NewInflightTracker()
+	reqctx, reqcancel := context.WithCancel(context.Background())
+	tracker.Add("id-1", "GET", "/a", reqcancel)
+	tracker.Add("id-1", "POST", "/b", func() {})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.False(tracker.Wait(ctx))
+	canceled := tracker.CancelAll()
+	assert.Equal(2, len(canceled))
+	assert.Equal(context.Canceled, reqctx.Err())
+}
+
+func TestClosingContext(t *testing.T) {
+	assert := assert2.New(t)
+	// 未过期时，继续使用原Context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	closectx, closecancel := closingContext(ctx, time.Second)
+	assert.Equal(ctx, closectx)
+	closecancel()
+	assert.NoError(ctx.Err())
+	// 已过期时，使用限定时间的新Context
+	expired, expiredcancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer expiredcancel()
+	<-expired.Done()
+	closectx, closecancel = closingContext(expired, time.Second)
+	defer closecancel()
+	assert.NoError(closectx.Err())
+	deadline, ok := closectx.Deadline()
+	assert.True(ok)
+	assert.True(time.Until(deadline) <= time.Second)
+}
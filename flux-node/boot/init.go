@@ -5,7 +5,9 @@ import (
 	"github.com/bytepowered/flux/flux-node/common"
 	"github.com/bytepowered/flux/flux-node/discovery"
 	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/identity"
 	"github.com/bytepowered/flux/flux-node/logger"
+	fxserializer "github.com/bytepowered/flux/flux-node/serializer"
 )
 
 func init() {
@@ -18,7 +20,27 @@ func init() {
 	serializer := flux.NewJsonSerializer()
 	ext.RegisterSerializer(ext.TypeNameSerializerDefault, serializer)
 	ext.RegisterSerializer(ext.TypeNameSerializerJson, serializer)
+	ext.RegisterSerializer(ext.TypeNameSerializerXml, fxserializer.NewXmlSerializer())
+	ext.RegisterSerializer(ext.TypeNameSerializerMsgPack, fxserializer.NewMsgPackSerializer())
+	ext.RegisterSerializer(ext.TypeNameSerializerProtoJson, fxserializer.NewProtoJsonSerializer())
+	// 响应内容协商：MediaType -> Serializer；Endpoint未定义produces时，只使用JSON
+	ext.RegisterMediaTypeSerializer(flux.MIMEApplicationJSON, ext.TypeNameSerializerJson)
+	ext.RegisterMediaTypeSerializer(flux.MIMEApplicationXML, ext.TypeNameSerializerXml)
+	ext.RegisterMediaTypeSerializer(flux.MIMETextXML, ext.TypeNameSerializerXml)
+	ext.RegisterMediaTypeSerializer(flux.MIMEApplicationMsgPack, ext.TypeNameSerializerMsgPack)
+	ext.RegisterMediaTypeSerializer(flux.MIMEApplicationXMsgPack, ext.TypeNameSerializerMsgPack)
+	ext.RegisterMediaTypeSerializer(flux.MIMEApplicationProtoJSON, ext.TypeNameSerializerProtoJson)
+	// RequestId generator
+	// Default: UUID
+	uuid := identity.NewUUIDGenerator()
+	ext.RegisterRequestIdGenerator(ext.TypeNameRequestIdDefault, uuid)
+	ext.RegisterRequestIdGenerator(ext.TypeNameRequestIdUUID, uuid)
+	ext.RegisterRequestIdGenerator(ext.TypeNameRequestIdULID, identity.NewULIDGenerator())
+	ext.RegisterRequestIdGenerator(ext.TypeNameRequestIdSnowflake, identity.NewSnowflakeGenerator(identity.NodeIdOfHost()))
 	// Endpoint discovery
 	ext.RegisterEndpointDiscovery(discovery.NewZookeeperServiceWith(discovery.ZookeeperId))
 	ext.RegisterEndpointDiscovery(discovery.NewResourceServiceWith(discovery.ResourceId))
+	ext.RegisterEndpointDiscovery(discovery.NewConsulServiceWith(discovery.ConsulId))
+	ext.RegisterEndpointDiscovery(discovery.NewNacosServiceWith(discovery.NacosId))
+	ext.RegisterEndpointDiscovery(discovery.NewHttpServiceWith(discovery.HttpId))
 }
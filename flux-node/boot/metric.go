@@ -1,8 +1,30 @@
 package boot
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	ConfigKeyMetricBuckets        = "buckets"
+	ConfigKeyMetricEndpointLabels = "endpoint_labels"
+	ConfigKeyMetricFilterLabels   = "filter_labels"
+)
+
+// Endpoint维度的指标标签
+const (
+	MetricLabelApplication = "application"
+	MetricLabelRouteKey    = "route_key"
+	MetricLabelVersion     = "version"
+	MetricLabelProto       = "proto"
+	MetricLabelService     = "service"
 )
 
 var (
@@ -27,34 +49,237 @@ var (
 		20.0,
 		30.0,
 	}
+	defaultMetricEndpointLabels = []string{MetricLabelApplication, MetricLabelRouteKey, MetricLabelVersion}
+	defaultMetricFilterLabels   = []string{MetricLabelApplication}
+	defaultMetricObjectives     = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+)
+
+var (
+	metricLabelValueFuncs = map[string]func(ctx flux.Context) string{
+		MetricLabelApplication: func(ctx flux.Context) string {
+			return ctx.Application()
+		},
+		MetricLabelRouteKey: func(ctx flux.Context) string {
+			ep := ctx.Endpoint()
+			return strings.ToUpper(ep.HttpMethod) + "#" + ep.HttpPattern
+		},
+		MetricLabelVersion: func(ctx flux.Context) string {
+			return ctx.Endpoint().Version
+		},
+		MetricLabelProto: func(ctx flux.Context) string {
+			return ctx.BackendService().AttrRpcProto()
+		},
+		MetricLabelService: func(ctx flux.Context) string {
+			return ctx.BackendServiceId()
+		},
+	}
 )
 
 type Metrics struct {
 	EndpointAccess *prometheus.CounterVec
 	EndpointError  *prometheus.CounterVec
 	RouteDuration  *prometheus.HistogramVec
+	// Endpoint维度的指标，标签由配置指定
+	RequestDuration *prometheus.HistogramVec
+	RequestInflight *prometheus.GaugeVec
+	RequestStatus   *prometheus.CounterVec
+	RequestSize     *prometheus.SummaryVec
+	ResponseSize    *prometheus.SummaryVec
+	// 由 Context.AddMetric 记录的各阶段耗时
+	FilterDuration *prometheus.HistogramVec
+	endpointLabels []string
+	filterLabels   []string
 }
 
+// NewMetrics 使用默认配置创建指标
 func NewMetrics() *Metrics {
-	return &Metrics{
-		EndpointAccess: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: defaultMetricNamespace,
-			Subsystem: defaultMetricSubsystem,
-			Name:      "endpoint_access_total",
-			Help:      "Number of endpoint access",
-		}, []string{"ProtoName", "Interface", "Method"}),
-		EndpointError: promauto.NewCounterVec(prometheus.CounterOpts{
-			Namespace: defaultMetricNamespace,
-			Subsystem: defaultMetricSubsystem,
-			Name:      "endpoint_error_total",
-			Help:      "Number of endpoint access errors",
-		}, []string{"ProtoName", "Interface", "Method", "ErrorCode"}),
-		RouteDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
-			Namespace: defaultMetricNamespace,
-			Subsystem: defaultMetricSubsystem,
-			Name:      "endpoint_route_duration",
-			Help:      "Spend time by processing a endpoint",
-			Buckets:   defaultMetricBuckets,
-		}, []string{"ComponentType", "TypeId"}),
+	metrics, err := NewMetricsWith(flux.NewEmptyConfiguration())
+	if nil != err {
+		panic(err)
+	}
+	return metrics
+}
+
+// NewMetricsWith 根据配置创建指标；配置项：
+// buckets: 耗时分布的Buckets；
+// endpoint_labels: Endpoint维度指标的标签列表，可选：application, route_key, version, proto, service；
+// filter_labels: Filter耗时指标的标签列表，可选项与endpoint_labels相同；
+func NewMetricsWith(config *flux.Configuration) (*Metrics, error) {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyMetricEndpointLabels: defaultMetricEndpointLabels,
+		ConfigKeyMetricFilterLabels:   defaultMetricFilterLabels,
+	})
+	buckets := defaultMetricBuckets
+	if config.IsSet(ConfigKeyMetricBuckets) {
+		values, err := toFloat64Slice(config.Get(ConfigKeyMetricBuckets))
+		if nil != err {
+			return nil, fmt.Errorf("metrics, invalid config: %s, error: %w", ConfigKeyMetricBuckets, err)
+		}
+		buckets = values
+	}
+	endpointLabels := config.GetStringSlice(ConfigKeyMetricEndpointLabels)
+	filterLabels := config.GetStringSlice(ConfigKeyMetricFilterLabels)
+	for _, label := range append(copyLabels(endpointLabels), filterLabels...) {
+		if _, ok := metricLabelValueFuncs[label]; !ok {
+			return nil, fmt.Errorf("metrics, unsupported label: %s", label)
+		}
+	}
+	m := &Metrics{endpointLabels: endpointLabels, filterLabels: filterLabels}
+	m.EndpointAccess = mustRegisterCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: defaultMetricNamespace,
+		Subsystem: defaultMetricSubsystem,
+		Name:      "endpoint_access_total",
+		Help:      "Number of endpoint access",
+	}, []string{"ProtoName", "Interface", "Method"})).(*prometheus.CounterVec)
+	m.EndpointError = mustRegisterCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: defaultMetricNamespace,
+		Subsystem: defaultMetricSubsystem,
+		Name:      "endpoint_error_total",
+		Help:      "Number of endpoint access errors",
+	}, []string{"ProtoName", "Interface", "Method", "ErrorCode"})).(*prometheus.CounterVec)
+	m.RouteDuration = mustRegisterCollector(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: defaultMetricNamespace,
+		Subsystem: defaultMetricSubsystem,
+		Name:      "endpoint_route_duration",
+		Help:      "Spend time by processing a endpoint",
+		Buckets:   buckets,
+	}, []string{"ComponentType", "TypeId"})).(*prometheus.HistogramVec)
+	m.RequestDuration = mustRegisterCollector(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: defaultMetricNamespace,
+		Subsystem: defaultMetricSubsystem,
+		Name:      "request_duration_seconds",
+		Help:      "Spend time by processing a request of endpoint",
+		Buckets:   buckets,
+	}, endpointLabels)).(*prometheus.HistogramVec)
+	m.RequestInflight = mustRegisterCollector(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: defaultMetricNamespace,
+		Subsystem: defaultMetricSubsystem,
+		Name:      "request_inflight",
+		Help:      "Number of in-flight requests of endpoint",
+	}, endpointLabels)).(*prometheus.GaugeVec)
+	m.RequestStatus = mustRegisterCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: defaultMetricNamespace,
+		Subsystem: defaultMetricSubsystem,
+		Name:      "response_status_total",
+		Help:      "Number of responses by status class of endpoint",
+	}, append(copyLabels(endpointLabels), "status_class"))).(*prometheus.CounterVec)
+	m.RequestSize = mustRegisterCollector(prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:  defaultMetricNamespace,
+		Subsystem:  defaultMetricSubsystem,
+		Name:       "request_size_bytes",
+		Help:       "Size of request body of endpoint",
+		Objectives: defaultMetricObjectives,
+	}, endpointLabels)).(*prometheus.SummaryVec)
+	m.ResponseSize = mustRegisterCollector(prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:  defaultMetricNamespace,
+		Subsystem:  defaultMetricSubsystem,
+		Name:       "response_size_bytes",
+		Help:       "Size of response body of endpoint",
+		Objectives: defaultMetricObjectives,
+	}, endpointLabels)).(*prometheus.SummaryVec)
+	m.FilterDuration = mustRegisterCollector(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: defaultMetricNamespace,
+		Subsystem: defaultMetricSubsystem,
+		Name:      "filter_duration_seconds",
+		Help:      "Spend time by processing filters and steps, recorded by context metrics",
+		Buckets:   buckets,
+	}, append(copyLabels(filterLabels), "step"))).(*prometheus.HistogramVec)
+	return m, nil
+}
+
+// EndpointLabelValues 返回Endpoint维度指标的标签值
+func (m *Metrics) EndpointLabelValues(ctx flux.Context) []string {
+	return labelValuesOf(m.endpointLabels, ctx)
+}
+
+// FilterLabelValues 返回Filter耗时指标的标签值（不包含step标签）
+func (m *Metrics) FilterLabelValues(ctx flux.Context) []string {
+	return labelValuesOf(m.filterLabels, ctx)
+}
+
+// StatusClass 返回响应状态码的分类：1xx, 2xx, 3xx, 4xx, 5xx
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+func labelValuesOf(labels []string, ctx flux.Context) []string {
+	values := make([]string, len(labels))
+	for i, label := range labels {
+		values[i] = metricLabelValueFuncs[label](ctx)
+	}
+	return values
+}
+
+func copyLabels(labels []string) []string {
+	out := make([]string, len(labels), len(labels)+1)
+	copy(out, labels)
+	return out
+}
+
+// 注册指标；如果已注册相同指标，返回已注册的指标实例；
+func mustRegisterCollector(c prometheus.Collector) prometheus.Collector {
+	if err := prometheus.Register(c); nil != err {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return c
+}
+
+func toFloat64Slice(v interface{}) ([]float64, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		if fs, ok := v.([]float64); ok {
+			return fs, nil
+		}
+		return nil, fmt.Errorf("not a list: %v", v)
+	}
+	out := make([]float64, len(items))
+	for i, item := range items {
+		switch n := item.(type) {
+		case float64:
+			out[i] = n
+		case int:
+			out[i] = float64(n)
+		case string:
+			f, err := strconv.ParseFloat(n, 64)
+			if nil != err {
+				return nil, err
+			}
+			out[i] = f
+		default:
+			return nil, fmt.Errorf("not a number: %v", item)
+		}
+	}
+	return out, nil
+}
+
+// 统计响应数据大小的ResponseWriter
+type countingResponseWriter struct {
+	http.ResponseWriter
+	size int64
+}
+
+func (w *countingResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *countingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *countingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
 	}
+	return nil, nil, errors.New("http.Hijacker not supported")
 }
@@ -0,0 +1,49 @@
+package boot
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStatusClass(t *testing.T) {
+	assert := assert2.New(t)
+	cases := []struct {
+		status int
+		class  string
+	}{
+		{status: 200, class: "2xx"},
+		{status: 204, class: "2xx"},
+		{status: 302, class: "3xx"},
+		{status: 404, class: "4xx"},
+		{status: 503, class: "5xx"},
+		{status: 0, class: "unknown"},
+		{status: 600, class: "unknown"},
+	}
+	for _, c := range cases {
+		assert.Equal(c.class, StatusClass(c.status))
+	}
+}
+
+func TestNewMetricsWith(t *testing.T) {
+	assert := assert2.New(t)
+	_, err := NewMetricsWith(flux.NewConfigurationOfMap(map[string]interface{}{
+		ConfigKeyMetricEndpointLabels: []string{"application", "unknown"},
+	}))
+	assert.Error(err, "must error on unsupported label")
+	_, err = NewMetricsWith(flux.NewConfigurationOfMap(map[string]interface{}{
+		ConfigKeyMetricBuckets: []interface{}{"abc"},
+	}))
+	assert.Error(err, "must error on invalid buckets")
+	// 重复创建时复用已注册的指标
+	config := func() *flux.Configuration {
+		return flux.NewConfigurationOfMap(map[string]interface{}{
+			ConfigKeyMetricBuckets: []interface{}{0.1, 1, "5"},
+		})
+	}
+	m1, err := NewMetricsWith(config())
+	assert.NoError(err)
+	m2, err := NewMetricsWith(config())
+	assert.NoError(err)
+	assert.Equal(m1.RequestDuration, m2.RequestDuration)
+}
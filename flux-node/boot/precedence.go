@@ -0,0 +1,301 @@
+package boot
+
+import (
+	"encoding/json"
+	"github.com/bytepowered/flux/flux-node"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	ConfigKeyPrecedencePriority  = "priority"
+	ConfigKeyPrecedenceMergeRule = "merge_rule"
+)
+
+const (
+	// 优先级最高的来源定义完整覆盖其它来源
+	MergeRuleOverride = "override"
+	// 以优先级最高的来源定义为准，合并其它来源中未定义的属性（Attributes）和扩展信息（Extensions）
+	MergeRuleMerge = "merge"
+)
+
+const (
+	ConflictTypeEndpoint = "endpoint"
+	ConflictTypeService  = "service"
+)
+
+var (
+	// 默认本地静态资源优先，使基于文件的配置可以覆盖注册中心的定义
+	defaultPrecedencePriority = []string{"resource"}
+)
+
+// DiscoveryConflict 多个Discovery来源定义了相同的Endpoint或者Service，并且定义内容不同
+type DiscoveryConflict struct {
+	Type    string   `json:"type"`
+	Key     string   `json:"key"`
+	Winner  string   `json:"winner"`
+	Sources []string `json:"sources"`
+}
+
+type endpointDefines struct {
+	sources map[string]flux.Endpoint
+	applied bool
+}
+
+type serviceDefines struct {
+	sources map[string]flux.BackendService
+	applied bool
+}
+
+// DiscoveryPrecedence 按来源Discovery跟踪Endpoint和Service的定义，根据优先级和合并规则，
+// 计算生效的定义；某个来源删除定义时，由其它来源的定义继续生效；
+type DiscoveryPrecedence struct {
+	priority  map[string]int
+	mergeRule string
+	endpoints map[string]*endpointDefines
+	services  map[string]*serviceDefines
+	mutex     sync.RWMutex
+}
+
+// NewDiscoveryPrecedenceWith 根据配置创建；配置项：
+// priority: Discovery ID列表，排在前面的优先级更高；未列出的Discovery按注册顺序排在后面；
+// merge_rule: 合并规则，可选：override, merge；
+func NewDiscoveryPrecedenceWith(config *flux.Configuration, registered []string) *DiscoveryPrecedence {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyPrecedencePriority:  defaultPrecedencePriority,
+		ConfigKeyPrecedenceMergeRule: MergeRuleOverride,
+	})
+	order := append(config.GetStringSlice(ConfigKeyPrecedencePriority), registered...)
+	priority := make(map[string]int, len(order))
+	for _, id := range order {
+		if _, ok := priority[id]; !ok {
+			priority[id] = len(priority)
+		}
+	}
+	rule := strings.ToLower(config.GetString(ConfigKeyPrecedenceMergeRule))
+	if rule != MergeRuleMerge {
+		rule = MergeRuleOverride
+	}
+	return &DiscoveryPrecedence{
+		priority:  priority,
+		mergeRule: rule,
+		endpoints: make(map[string]*endpointDefines, 64),
+		services:  make(map[string]*serviceDefines, 64),
+	}
+}
+
+// OnEndpointEvent 接收来源Discovery的Endpoint事件，返回需要应用的生效事件
+func (p *DiscoveryPrecedence) OnEndpointEvent(source string, event flux.HttpEndpointEvent) (flux.HttpEndpointEvent, bool) {
+	ep := event.Endpoint
+	key := strings.ToUpper(ep.HttpMethod) + "#" + ep.HttpPattern + "#" + ep.Version
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	defines, ok := p.endpoints[key]
+	if !ok {
+		defines = &endpointDefines{sources: make(map[string]flux.Endpoint, 2)}
+		p.endpoints[key] = defines
+	}
+	if event.EventType == flux.EventTypeRemoved {
+		delete(defines.sources, source)
+	} else {
+		defines.sources[source] = ep
+	}
+	if len(defines.sources) == 0 {
+		delete(p.endpoints, key)
+		if !defines.applied {
+			return event, false
+		}
+		return flux.HttpEndpointEvent{EventType: flux.EventTypeRemoved, Endpoint: ep}, true
+	}
+	effective := p.resolveEndpoint(defines.sources)
+	etype := flux.EventType(flux.EventTypeUpdated)
+	if !defines.applied {
+		etype = flux.EventTypeAdded
+		defines.applied = true
+	}
+	return flux.HttpEndpointEvent{EventType: etype, Endpoint: effective}, true
+}
+
+// OnServiceEvent 接收来源Discovery的Service事件，返回需要应用的生效事件
+func (p *DiscoveryPrecedence) OnServiceEvent(source string, event flux.BackendServiceEvent) (flux.BackendServiceEvent, bool) {
+	srv := event.Service
+	key := serviceSnapshotKey(&srv)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	defines, ok := p.services[key]
+	if !ok {
+		defines = &serviceDefines{sources: make(map[string]flux.BackendService, 2)}
+		p.services[key] = defines
+	}
+	if event.EventType == flux.EventTypeRemoved {
+		delete(defines.sources, source)
+	} else {
+		defines.sources[source] = srv
+	}
+	if len(defines.sources) == 0 {
+		delete(p.services, key)
+		if !defines.applied {
+			return event, false
+		}
+		return flux.BackendServiceEvent{EventType: flux.EventTypeRemoved, Service: srv}, true
+	}
+	effective := p.resolveService(defines.sources)
+	etype := flux.EventType(flux.EventTypeUpdated)
+	if !defines.applied {
+		etype = flux.EventTypeAdded
+		defines.applied = true
+	}
+	return flux.BackendServiceEvent{EventType: etype, Service: effective}, true
+}
+
+// Conflicts 返回多个来源定义内容不一致的Endpoint和Service
+func (p *DiscoveryPrecedence) Conflicts() []DiscoveryConflict {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	out := make([]DiscoveryConflict, 0)
+	for key, defines := range p.endpoints {
+		if len(defines.sources) < 2 {
+			continue
+		}
+		values := make(map[string]interface{}, len(defines.sources))
+		for s, v := range defines.sources {
+			values[s] = v
+		}
+		if c, ok := p.conflictOf(ConflictTypeEndpoint, key, values); ok {
+			out = append(out, c)
+		}
+	}
+	for key, defines := range p.services {
+		if len(defines.sources) < 2 {
+			continue
+		}
+		values := make(map[string]interface{}, len(defines.sources))
+		for s, v := range defines.sources {
+			values[s] = v
+		}
+		if c, ok := p.conflictOf(ConflictTypeService, key, values); ok {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return out[i].Type < out[j].Type
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+func (p *DiscoveryPrecedence) conflictOf(ctype, key string, values map[string]interface{}) (DiscoveryConflict, bool) {
+	sources := p.sortedSources(values)
+	digest := precedenceDigest(values[sources[0]])
+	for _, s := range sources[1:] {
+		if precedenceDigest(values[s]) != digest {
+			return DiscoveryConflict{Type: ctype, Key: key, Winner: sources[0], Sources: sources}, true
+		}
+	}
+	return DiscoveryConflict{}, false
+}
+
+func (p *DiscoveryPrecedence) resolveEndpoint(sources map[string]flux.Endpoint) flux.Endpoint {
+	values := make(map[string]interface{}, len(sources))
+	for s, v := range sources {
+		values[s] = v
+	}
+	ordered := p.sortedSources(values)
+	effective := sources[ordered[0]]
+	if p.mergeRule == MergeRuleMerge {
+		for _, s := range ordered[1:] {
+			lower := sources[s]
+			effective.Attributes = mergeAttributes(effective.Attributes, lower.Attributes)
+			effective.Extensions = mergeExtensions(effective.Extensions, lower.Extensions)
+		}
+	}
+	return effective
+}
+
+func (p *DiscoveryPrecedence) resolveService(sources map[string]flux.BackendService) flux.BackendService {
+	values := make(map[string]interface{}, len(sources))
+	for s, v := range sources {
+		values[s] = v
+	}
+	ordered := p.sortedSources(values)
+	effective := sources[ordered[0]]
+	if p.mergeRule == MergeRuleMerge {
+		for _, s := range ordered[1:] {
+			lower := sources[s]
+			effective.Attributes = mergeAttributes(effective.Attributes, lower.Attributes)
+			effective.Extensions = mergeExtensions(effective.Extensions, lower.Extensions)
+		}
+	}
+	return effective
+}
+
+// 按优先级从高到低排序来源
+func (p *DiscoveryPrecedence) sortedSources(values map[string]interface{}) []string {
+	sources := make([]string, 0, len(values))
+	for s := range values {
+		sources = append(sources, s)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		pi, pj := p.priorityOf(sources[i]), p.priorityOf(sources[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return sources[i] < sources[j]
+	})
+	return sources
+}
+
+func (p *DiscoveryPrecedence) priorityOf(source string) int {
+	if v, ok := p.priority[source]; ok {
+		return v
+	}
+	return len(p.priority)
+}
+
+// 合并属性：保留高优先级的属性，追加低优先级中未定义的属性
+func mergeAttributes(higher, lower []flux.Attribute) []flux.Attribute {
+	out := make([]flux.Attribute, len(higher), len(higher)+len(lower))
+	copy(out, higher)
+	defined := make(map[string]struct{}, len(higher))
+	for _, a := range higher {
+		defined[strings.ToLower(a.Name)] = struct{}{}
+	}
+	for _, a := range lower {
+		if _, ok := defined[strings.ToLower(a.Name)]; !ok {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func mergeExtensions(higher, lower map[string]interface{}) map[string]interface{} {
+	if len(lower) == 0 {
+		return higher
+	}
+	out := make(map[string]interface{}, len(higher)+len(lower))
+	for k, v := range lower {
+		out[k] = v
+	}
+	for k, v := range higher {
+		out[k] = v
+	}
+	return out
+}
+
+func precedenceDigest(v interface{}) string {
+	bytes, _ := json.Marshal(v)
+	return string(bytes)
+}
+
+// HandleConflicts 查询多个Discovery来源之间的定义冲突，以及生效的来源
+func (s *BootstrapServer) HandleConflicts(webex flux.WebExchange) error {
+	conflicts := make([]DiscoveryConflict, 0)
+	if nil != s.precedence {
+		conflicts = s.precedence.Conflicts()
+	}
+	return webex.Send(webex, http.Header{}, flux.StatusOK, conflicts)
+}
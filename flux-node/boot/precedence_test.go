@@ -0,0 +1,75 @@
+package boot
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestEndpoint(iface string, attrs ...flux.Attribute) flux.Endpoint {
+	return flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/a", Version: "v1",
+		Service:            flux.BackendService{Interface: iface, Method: "get"},
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: attrs},
+	}
+}
+
+func TestDiscoveryPrecedence_Shadowing(t *testing.T) {
+	assert := assert2.New(t)
+	precedence := NewDiscoveryPrecedenceWith(flux.NewEmptyConfiguration(), []string{"zookeeper", "resource"})
+	// ZK首次定义
+	evt, ok := precedence.OnEndpointEvent("zookeeper", flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: newTestEndpoint("zk")})
+	assert.True(ok)
+	assert.Equal(flux.EventType(flux.EventTypeAdded), evt.EventType)
+	assert.Equal("zk", evt.Endpoint.Service.Interface)
+	// 本地文件覆盖
+	evt, ok = precedence.OnEndpointEvent("resource", flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: newTestEndpoint("file")})
+	assert.True(ok)
+	assert.Equal(flux.EventType(flux.EventTypeUpdated), evt.EventType)
+	assert.Equal("file", evt.Endpoint.Service.Interface)
+	// ZK更新，仍然由本地文件生效
+	evt, ok = precedence.OnEndpointEvent("zookeeper", flux.HttpEndpointEvent{EventType: flux.EventTypeUpdated, Endpoint: newTestEndpoint("zk2")})
+	assert.True(ok)
+	assert.Equal("file", evt.Endpoint.Service.Interface)
+	conflicts := precedence.Conflicts()
+	assert.Equal(1, len(conflicts))
+	assert.Equal(ConflictTypeEndpoint, conflicts[0].Type)
+	assert.Equal("resource", conflicts[0].Winner)
+	assert.Equal([]string{"resource", "zookeeper"}, conflicts[0].Sources)
+	// 删除本地文件定义，回退到ZK定义
+	evt, ok = precedence.OnEndpointEvent("resource", flux.HttpEndpointEvent{EventType: flux.EventTypeRemoved, Endpoint: newTestEndpoint("file")})
+	assert.True(ok)
+	assert.Equal(flux.EventType(flux.EventTypeUpdated), evt.EventType)
+	assert.Equal("zk2", evt.Endpoint.Service.Interface)
+	assert.Equal(0, len(precedence.Conflicts()))
+	// 全部来源删除
+	evt, ok = precedence.OnEndpointEvent("zookeeper", flux.HttpEndpointEvent{EventType: flux.EventTypeRemoved, Endpoint: newTestEndpoint("zk2")})
+	assert.True(ok)
+	assert.Equal(flux.EventType(flux.EventTypeRemoved), evt.EventType)
+	// 未生效的定义删除，不需要应用
+	_, ok = precedence.OnEndpointEvent("zookeeper", flux.HttpEndpointEvent{EventType: flux.EventTypeRemoved, Endpoint: newTestEndpoint("zk2")})
+	assert.False(ok)
+}
+
+func TestDiscoveryPrecedence_Merge(t *testing.T) {
+	assert := assert2.New(t)
+	precedence := NewDiscoveryPrecedenceWith(flux.NewConfigurationOfMap(map[string]interface{}{
+		ConfigKeyPrecedencePriority:  []string{"resource", "zookeeper"},
+		ConfigKeyPrecedenceMergeRule: "MERGE",
+	}), nil)
+	precedence.OnServiceEvent("zookeeper", flux.BackendServiceEvent{EventType: flux.EventTypeAdded, Service: flux.BackendService{
+		ServiceId: "a", EmbeddedAttributes: flux.EmbeddedAttributes{
+			Attributes: []flux.Attribute{{Name: "rpcproto", Value: "dubbo"}, {Name: "rpctimeout", Value: "5s"}},
+		},
+	}})
+	evt, ok := precedence.OnServiceEvent("resource", flux.BackendServiceEvent{EventType: flux.EventTypeAdded, Service: flux.BackendService{
+		ServiceId: "a", EmbeddedAttributes: flux.EmbeddedAttributes{
+			Attributes: []flux.Attribute{{Name: "RpcTimeout", Value: "1s"}},
+		},
+	}})
+	assert.True(ok)
+	assert.Equal([]flux.Attribute{{Name: "RpcTimeout", Value: "1s"}, {Name: "rpcproto", Value: "dubbo"}}, evt.Service.Attributes)
+	conflicts := precedence.Conflicts()
+	assert.Equal(1, len(conflicts))
+	assert.Equal(ConflictTypeService, conflicts[0].Type)
+}
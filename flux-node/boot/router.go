@@ -9,18 +9,20 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"reflect"
 	"sort"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
 type Router struct {
 	metrics *Metrics
 	hooks   []flux.PrepareHookFunc
+	started int32
 }
 
 func NewRouter() *Router {
 	return &Router{
-		metrics: NewMetrics(),
-		hooks:   make([]flux.PrepareHookFunc, 0, 4),
+		hooks: make([]flux.PrepareHookFunc, 0, 4),
 	}
 }
 
@@ -36,6 +38,12 @@ func (r *Router) Prepare() error {
 
 func (r *Router) Initial() error {
 	logger.Info("Router initialing")
+	// Metrics
+	metrics, err := NewMetricsWith(flux.NewConfigurationOfNS(flux.NamespaceMetrics))
+	if nil != err {
+		return err
+	}
+	r.metrics = metrics
 	// Backends
 	for proto, backend := range ext.BackendTransports() {
 		ns := flux.NamespaceBackendTransports + "." + proto
@@ -95,11 +103,18 @@ func (r *Router) Startup() error {
 			return err
 		}
 	}
+	atomic.StoreInt32(&r.started, 1)
 	return nil
 }
 
+// Shutdown 按Orderer顺序停止组件；EndpointDiscovery 由Server在等待请求完成前停止，此处忽略；
 func (r *Router) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&r.started, 0)
 	for _, shutdown := range sortedShutdown(ext.ShutdownHooks()) {
+		if _, ok := shutdown.(flux.EndpointDiscovery); ok {
+			continue
+		}
+		logger.Infow("Router shutdown", "type", reflect.TypeOf(shutdown))
 		if err := shutdown.Shutdown(ctx); nil != err {
 			return err
 		}
@@ -120,6 +135,28 @@ func (r *Router) Route(ctx flux.Context) *flux.ServeError {
 		}
 		return err
 	}
+	// Metric: Endpoint
+	labels := r.metrics.EndpointLabelValues(ctx)
+	inflight := r.metrics.RequestInflight.WithLabelValues(labels...)
+	inflight.Inc()
+	// 使用defer保证Filter或Transport发生Panic时，也能减少计数
+	defer inflight.Dec()
+	if size := requestSizeOf(ctx); size >= 0 {
+		r.metrics.RequestSize.WithLabelValues(labels...).Observe(float64(size))
+	}
+	doMetricRequestFunc := func(err *flux.ServeError) *flux.ServeError {
+		r.metrics.RequestDuration.WithLabelValues(labels...).Observe(time.Since(ctx.StartAt()).Seconds())
+		status := ctx.Response().StatusCode()
+		if nil != err {
+			status = err.StatusCode
+		}
+		r.metrics.RequestStatus.WithLabelValues(append(labels, StatusClass(status))...).Inc()
+		filterLabels := r.metrics.FilterLabelValues(ctx)
+		for _, m := range ctx.Metrics() {
+			r.metrics.FilterDuration.WithLabelValues(append(filterLabels, m.Name)...).Observe(m.Elapsed.Seconds())
+		}
+		return err
+	}
 	// Metric: Route
 	defer func() {
 		ctx.AddMetric("route", time.Since(ctx.StartAt()))
@@ -165,7 +202,21 @@ func (r *Router) Route(ctx flux.Context) *flux.ServeError {
 	}
 	// Walk filters
 	filters := append(ext.GlobalFilters(), selective...)
-	return doMetricEndpointFunc(r.walk(transport, filters)(ctx))
+	return doMetricRequestFunc(doMetricEndpointFunc(r.walk(transport, filters)(ctx)))
+}
+
+// ObserveResponseSize 记录响应数据大小
+func (r *Router) ObserveResponseSize(ctx flux.Context, size int64) {
+	r.metrics.ResponseSize.WithLabelValues(r.metrics.EndpointLabelValues(ctx)...).Observe(float64(size))
+}
+
+func requestSizeOf(ctx flux.Context) int64 {
+	if length := ctx.Request().HeaderVar(flux.HeaderContentLength); "" != length {
+		if size, err := strconv.ParseInt(length, 10, 64); nil == err {
+			return size
+		}
+	}
+	return -1
 }
 
 func (r *Router) walk(next flux.FilterHandler, filters []flux.Filter) flux.FilterHandler {
@@ -0,0 +1,47 @@
+package boot
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/context"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testProtoPanic = "ROUTE_PANIC"
+
+type panicTransport struct {
+	flux.BackendTransport
+}
+
+func (p *panicTransport) Exchange(_ flux.Context) *flux.ServeError {
+	panic("transport panic")
+}
+
+func TestRouter_RouteInflightOnPanic(t *testing.T) {
+	assert := assert2.New(t)
+	ext.RegisterBackendTransport(testProtoPanic, new(panicTransport))
+	metrics, err := NewMetricsWith(flux.NewEmptyConfiguration())
+	if !assert.NoError(err) {
+		return
+	}
+	router := NewRouter()
+	router.metrics = metrics
+	ctx := context.NewMockWith("rid", map[string]interface{}{
+		"service": flux.BackendService{
+			Interface: "net.bytepowered.Hello",
+			Method:    "hello",
+			EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+				{Name: flux.ServiceAttrTagRpcProto, Value: testProtoPanic},
+			}},
+			EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: map[string]interface{}{}},
+		},
+	})
+	gauge := metrics.RequestInflight.WithLabelValues(metrics.EndpointLabelValues(ctx)...)
+	before := testutil.ToFloat64(gauge)
+	assert.Panics(func() {
+		router.Route(ctx)
+	})
+	assert.Equal(before, testutil.ToFloat64(gauge), "inflight gauge must be decreased on panic")
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/bytepowered/flux/flux-node/context"
 	"github.com/bytepowered/flux/flux-node/ext"
 	"github.com/bytepowered/flux/flux-node/inspect"
+	"github.com/bytepowered/flux/flux-node/internal"
 	"github.com/bytepowered/flux/flux-node/listener"
 	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/bytepowered/flux/flux-pkg"
@@ -18,6 +19,8 @@ import (
 	"os/signal"
 	"runtime/debug"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -33,6 +36,21 @@ const (
 	ListenServerIdAdmin = "admin"
 )
 
+// 检查Discovery初始事件是否已分发完成的间隔
+const discoveryReadyCheckInterval = time.Millisecond * 100
+
+type (
+	// 标记来源Discovery的事件
+	sourcedEndpointEvent struct {
+		source string
+		event  flux.HttpEndpointEvent
+	}
+	sourcedServiceEvent struct {
+		source string
+		event  flux.BackendServiceEvent
+	}
+)
+
 type (
 	// Option 配置HttpServeEngine函数
 	Option func(bs *BootstrapServer)
@@ -50,6 +68,16 @@ type BootstrapServer struct {
 	stopped           chan struct{}
 	banner            string
 	routeTraceEnabled bool
+	draining          int32
+	inflight          *InflightTracker
+	snapshot          *DiscoverySnapshot
+	precedence        *DiscoveryPrecedence
+	discoveries       []flux.EndpointDiscovery
+	quit              chan struct{}
+	closeTimeout      time.Duration
+	bound             map[string]struct{} // 已绑定到WebListener的路由Key；只在事件处理协程中读写
+	looping           sync.WaitGroup      // 事件处理循环；停止Discovery时等待循环退出
+	pending           int32               // 已从Discovery接收、尚未处理完成的事件数量
 }
 
 // WithWebExchangeHooks 配置请求Hook函数列表
@@ -73,6 +101,13 @@ func WithServerBanner(banner string) Option {
 	}
 }
 
+// WithCloseTimeout 配置等待请求超时后，关闭WebListener和其它组件的最长时间
+func WithCloseTimeout(timeout time.Duration) Option {
+	return func(bs *BootstrapServer) {
+		bs.closeTimeout = timeout
+	}
+}
+
 // WithPrepareHooks 配置服务启动预备阶段Hook函数列表
 func WithPrepareHooks(hooks ...flux.PrepareHookFunc) Option {
 	return func(bs *BootstrapServer) {
@@ -80,6 +115,13 @@ func WithPrepareHooks(hooks ...flux.PrepareHookFunc) Option {
 	}
 }
 
+// WithEndpointDiscoveries 指定Server使用的Discovery列表；未指定时，使用全局注册的Discovery；
+func WithEndpointDiscoveries(discoveries ...flux.EndpointDiscovery) Option {
+	return func(bs *BootstrapServer) {
+		bs.discoveries = discoveries
+	}
+}
+
 func WithWebListener(server flux.WebListener) Option {
 	return func(bs *BootstrapServer) {
 		bs.AddListenServer(server.ListenerId(), server)
@@ -100,21 +142,42 @@ func NewDefaultBootstrapServer(options ...Option) *BootstrapServer {
 			listener.WithWebHandlers([]listener.WebHandlerTuple{
 				{Method: "GET", Pattern: "/inspect/endpoints", Handler: inspect.EndpointsHandler},
 				{Method: "GET", Pattern: "/inspect/services", Handler: inspect.ServicesHandler},
+				{Method: "GET", Pattern: "/inspect/transports", Handler: inspect.TransportsHandler},
 				{Method: "GET", Pattern: "/inspect/metrics", Handler: flux.WrapHttpHandler(promhttp.Handler())},
+				// 运行时日志级别
+				{Method: "GET", Pattern: "/inspect/logging", Handler: inspect.LoggingHandler},
+				{Method: "POST", Pattern: "/inspect/logging", Handler: inspect.LoggingUpdateHandler},
+				// 校验Endpoint和Service定义
+				{Method: "POST", Pattern: "/inspect/validate", Handler: inspect.ValidateHandler},
 			}),
 		)),
 	}
-	return NewBootstrapServerWith(append(opts, options...)...)
+	srv := NewBootstrapServerWith(append(opts, options...)...)
+	// 健康检查
+	if admin, ok := srv.WebListenerById(ListenServerIdAdmin); ok {
+		admin.AddHandler("GET", "/inspect/snapshot", srv.HandleSnapshot)
+		admin.AddHandler("GET", "/inspect/conflicts", srv.HandleConflicts)
+		admin.AddHandler("GET", "/health/live", srv.HandleLiveness)
+		admin.AddHandler("GET", "/health/ready", srv.HandleReadiness)
+		admin.AddHandler("POST", "/drain", srv.HandleDrain)
+	}
+	return srv
 }
 
 func NewBootstrapServerWith(opts ...Option) *BootstrapServer {
 	srv := &BootstrapServer{
-		router:   NewRouter(),
-		listener: make(map[string]flux.WebListener, 2),
-		hooks:    make([]flux.WebExchangeHook, 0, 4),
-		started:  make(chan struct{}),
-		stopped:  make(chan struct{}),
-		banner:   defaultBanner,
+		snapshot:     NewDiscoverySnapshotWith(flux.NewEmptyConfiguration()),
+		precedence:   NewDiscoveryPrecedenceWith(flux.NewEmptyConfiguration(), nil),
+		router:       NewRouter(),
+		listener:     make(map[string]flux.WebListener, 2),
+		hooks:        make([]flux.WebExchangeHook, 0, 4),
+		started:      make(chan struct{}),
+		stopped:      make(chan struct{}),
+		quit:         make(chan struct{}),
+		inflight:     NewInflightTracker(),
+		banner:       defaultBanner,
+		closeTimeout: DefaultCloseTimeout,
+		bound:        make(map[string]struct{}, 16),
 	}
 	for _, opt := range opts {
 		opt(srv)
@@ -136,7 +199,13 @@ func (s *BootstrapServer) Initial() error {
 		}
 	}
 	// Discovery
-	for _, dis := range ext.EndpointDiscoveries() {
+	s.snapshot = NewDiscoverySnapshotWith(flux.NewConfigurationOfNS(flux.NamespaceDiscoverySnapshot))
+	registered := make([]string, 0, 4)
+	for _, dis := range s.endpointDiscoveries() {
+		registered = append(registered, dis.Id())
+	}
+	s.precedence = NewDiscoveryPrecedenceWith(flux.NewConfigurationOfNS(flux.NamespaceDiscoveryPrecedence), registered)
+	for _, dis := range s.endpointDiscoveries() {
 		if err := s.router.AddInitHook(dis, LoadEndpointDiscoveryConfig(dis.Id())); nil != err {
 			return err
 		}
@@ -158,17 +227,22 @@ func (s *BootstrapServer) start() error {
 	if err := s.router.Startup(); nil != err {
 		return err
 	}
-	endpoints := make(chan flux.HttpEndpointEvent, 2)
-	services := make(chan flux.BackendServiceEvent, 2)
-	defer func() {
-		close(endpoints)
-		close(services)
+	// 事件Channel不主动关闭：Discovery可能在停止过程中仍在发送事件，由事件处理循环在服务停止时退出；
+	endpoints := make(chan sourcedEndpointEvent, 2)
+	services := make(chan sourcedServiceEvent, 2)
+	// 从本地快照恢复元数据，保证注册中心不可用时仍可提供服务
+	s.restoreSnapshot()
+	// 先启动事件处理循环，再启动Discovery监听，避免Discovery同步发送事件时阻塞
+	s.looping.Add(1)
+	go func() {
+		defer s.looping.Done()
+		s.loopDiscoveryEvents(endpoints, services)
 	}()
 	if err := s.startDiscovery(endpoints, services); nil != err {
 		return err
 	}
 	// Start Servers
-	var errch chan error
+	errch := make(chan error, len(s.listener))
 	for lid, wl := range s.listener {
 		go func(id string, server flux.WebListener) {
 			logger.Infow("WebListener starting, server-id: " + id)
@@ -179,35 +253,105 @@ func (s *BootstrapServer) start() error {
 	return <-errch
 }
 
-func (s *BootstrapServer) startDiscovery(endpoints chan flux.HttpEndpointEvent, services chan flux.BackendServiceEvent) error {
-	for _, discovery := range ext.EndpointDiscoveries() {
-		if err := discovery.WatchEndpoints(endpoints); nil != err {
+// startDiscovery 为每个Discovery创建独立的事件Channel，事件标记来源Discovery后，转发到事件处理循环
+func (s *BootstrapServer) startDiscovery(endpoints chan sourcedEndpointEvent, services chan sourcedServiceEvent) error {
+	for _, discovery := range s.endpointDiscoveries() {
+		id := discovery.Id()
+		// 不使用缓冲：Discovery报告就绪时，已发送的事件都已被转发协程接收并计入待处理数量
+		epch := make(chan flux.HttpEndpointEvent)
+		srvch := make(chan flux.BackendServiceEvent)
+		go s.forwardDiscoveryEvents(id, epch, srvch, endpoints, services)
+		if err := discovery.WatchEndpoints(epch); nil != err {
 			return err
 		}
-		if err := discovery.WatchServices(services); nil != err {
+		if err := discovery.WatchServices(srvch); nil != err {
 			return err
 		}
 	}
-	go func() {
-		logger.Info("Discovery event loop: START")
-		defer logger.Info("Discovery event loop: STOP")
-		for {
+	return nil
+}
+
+func (s *BootstrapServer) forwardDiscoveryEvents(source string,
+	epch <-chan flux.HttpEndpointEvent, srvch <-chan flux.BackendServiceEvent,
+	endpoints chan<- sourcedEndpointEvent, services chan<- sourcedServiceEvent) {
+	for {
+		select {
+		case <-s.quit:
+			return
+
+		case evt := <-epch:
+			atomic.AddInt32(&s.pending, 1)
 			select {
-			case epEvt, ok := <-endpoints:
-				if !ok {
-					return
-				}
-				s.onHttpEndpointEvent(epEvt)
+			case endpoints <- sourcedEndpointEvent{source: source, event: evt}:
+			case <-s.quit:
+				return
+			}
+
+		case evt := <-srvch:
+			atomic.AddInt32(&s.pending, 1)
+			select {
+			case services <- sourcedServiceEvent{source: source, event: evt}:
+			case <-s.quit:
+				return
+			}
+		}
+	}
+}
 
-			case esEvt, ok := <-services:
-				if !ok {
-					return
+func (s *BootstrapServer) loopDiscoveryEvents(endpoints chan sourcedEndpointEvent, services chan sourcedServiceEvent) {
+	logger.Info("Discovery event loop: START")
+	defer logger.Info("Discovery event loop: STOP")
+	var snapshotTick <-chan time.Time
+	if s.snapshotEnabled() {
+		ticker := time.NewTicker(s.snapshot.Interval())
+		defer ticker.Stop()
+		snapshotTick = ticker.C
+	}
+	readyTicker := time.NewTicker(discoveryReadyCheckInterval)
+	defer readyTicker.Stop()
+	readyTick := readyTicker.C
+	for {
+		select {
+		case <-s.quit:
+			return
+
+		case sourced, ok := <-endpoints:
+			if !ok {
+				return
+			}
+			// 根据来源优先级，计算生效的定义
+			if epEvt, apply := s.precedence.OnEndpointEvent(sourced.source, sourced.event); apply {
+				s.onHttpEndpointEvent(epEvt)
+				if s.snapshotEnabled() {
+					s.snapshot.OnEndpointEvent(epEvt)
 				}
+			}
+			atomic.AddInt32(&s.pending, -1)
+
+		case sourced, ok := <-services:
+			if !ok {
+				return
+			}
+			if esEvt, apply := s.precedence.OnServiceEvent(sourced.source, sourced.event); apply {
 				s.onBackendServiceEvent(esEvt)
+				if s.snapshotEnabled() {
+					s.snapshot.OnServiceEvent(esEvt)
+				}
+			}
+			atomic.AddInt32(&s.pending, -1)
+
+		case <-snapshotTick:
+			s.syncSnapshot()
+
+		case <-readyTick:
+			// 全部Discovery就绪，并且初始事件已处理完成后，只通知一次
+			if s.discoveriesReady() && atomic.LoadInt32(&s.pending) == 0 {
+				readyTicker.Stop()
+				readyTick = nil
+				s.notifyDiscoveryReady()
 			}
 		}
-	}()
-	return nil
+	}
 }
 
 func (s *BootstrapServer) route(webex flux.WebExchange, server flux.WebListener, endpoints *flux.MultiEndpoint) error {
@@ -240,7 +384,15 @@ func (s *BootstrapServer) route(webex flux.WebExchange, server flux.WebListener,
 		}
 		return flux.ErrRouteNotFound
 	}
-	ctxw := context.New(webex, endpoint)
+	// 响应内容协商：由ResponseWriter根据Endpoint定义的格式和Accept选择序列化格式
+	webex.SetVariable(internal.ContextKeyResponseProduces, endpoint.AttrProduces())
+	webex.SetVariable(internal.ContextKeyRouteApplication, endpoint.Application)
+	// 跟踪处理中的请求，服务停止时等待请求完成或者强制取消
+	reqctx, cancel := goctx.WithCancel(webex.Context())
+	defer cancel()
+	key := s.inflight.Add(webex.RequestId(), webex.Method(), webex.URI(), cancel)
+	defer s.inflight.Done(key)
+	ctxw := context.NewWith(reqctx, webex, endpoint)
 	ctxw.SetAttribute(flux.XRequestTime, ctxw.StartAt().Unix())
 	ctxw.SetAttribute(flux.XRequestId, webex.RequestId())
 	ctxw.SetAttribute(flux.XRequestHost, webex.Host())
@@ -259,6 +411,20 @@ func (s *BootstrapServer) route(webex flux.WebExchange, server flux.WebListener,
 		r := ctxw.Response()
 		logger.TraceContext(ctxw).Infow("SERVER:ROUTE:RESPONSE/DATA", "statusCode", r.StatusCode())
 		defer endfunc(ctxw.StartAt())
+		// 协议已升级（WebSocket），连接已被接管，不再写入响应
+		if r.StatusCode() == flux.StatusSwitchingProtocols {
+			return nil
+		}
+		// 统计响应数据大小
+		if w, err := webex.HttpResponseWriter(); nil == err {
+			counter := &countingResponseWriter{ResponseWriter: w}
+			if nil == webex.SetHttpResponseWriter(counter) {
+				defer func() {
+					_ = webex.SetHttpResponseWriter(w)
+					s.router.ObserveResponseSize(ctxw, counter.size)
+				}()
+			}
+		}
 		return server.Write(webex, r.HeaderVars(), r.StatusCode(), r.Payload())
 	} else {
 		logger.TraceContext(ctxw).Errorw("SERVER:ROUTE:RESPONSE/ERROR", "statusCode", err.StatusCode, "error", err)
@@ -293,6 +459,28 @@ func (s *BootstrapServer) onBackendServiceEvent(event flux.BackendServiceEvent)
 			ext.RemoveBackendService(service.AliasId)
 		}
 	}
+	notifyBackendTransport(event)
+}
+
+// notifyDiscoveryReady 通知BackendTransport，Discovery的初始元数据已分发完成
+func (s *BootstrapServer) notifyDiscoveryReady() {
+	logger.Info("Discovery initial events dispatched")
+	for _, transport := range ext.BackendTransports() {
+		if listener, ok := transport.(flux.DiscoveryReadyListener); ok {
+			listener.OnDiscoveryReady()
+		}
+	}
+}
+
+// notifyBackendTransport 通知服务协议对应的BackendTransport
+func notifyBackendTransport(event flux.BackendServiceEvent) {
+	if proto := event.Service.AttrRpcProto(); "" != proto {
+		if transport, ok := ext.BackendTransportByProto(proto); ok {
+			if listener, ok := transport.(flux.BackendServiceEventListener); ok {
+				listener.OnBackendServiceEvent(event)
+			}
+		}
+	}
 }
 
 func (s *BootstrapServer) onHttpEndpointEvent(event flux.HttpEndpointEvent) {
@@ -308,13 +496,14 @@ func (s *BootstrapServer) onHttpEndpointEvent(event flux.HttpEndpointEvent) {
 	endpoint := event.Endpoint
 	initArguments(endpoint.Service.Arguments)
 	initArguments(endpoint.Permission.Arguments)
-	bind, isreg := s.selectMultiEndpoint(routeKey, &endpoint)
+	bind := s.selectMultiEndpoint(routeKey, &endpoint)
 	switch event.EventType {
 	case flux.EventTypeAdded:
 		logger.Infow("SERVER:META:ENDPOINT:ADD", "version", endpoint.Version, "method", method, "pattern", pattern)
 		bind.Update(endpoint.Version, &endpoint)
-		// 根据Endpoint属性，选择ListenServer来绑定
-		if isreg {
+		// 根据Endpoint属性，选择ListenServer来绑定；每个路由Key只绑定一次
+		if _, ok := s.bound[routeKey]; !ok {
+			s.bound[routeKey] = struct{}{}
 			id := endpoint.GetAttr(flux.EndpointAttrTagServerId).GetString()
 			if id == "" {
 				id = ListenerIdDefault
@@ -334,18 +523,71 @@ func (s *BootstrapServer) onHttpEndpointEvent(event flux.HttpEndpointEvent) {
 		logger.Infow("SERVER:META:ENDPOINT:REMOVE", "method", method, "pattern", pattern)
 		bind.Delete(endpoint.Version)
 	}
+	// Endpoint内嵌的服务定义不经过独立的服务事件，同样需要通知BackendTransport
+	for _, service := range []flux.BackendService{endpoint.Service, endpoint.Permission} {
+		if service.IsValid() {
+			notifyBackendTransport(flux.BackendServiceEvent{EventType: event.EventType, Service: service})
+		}
+	}
 }
 
 // Shutdown to cleanup resources
+// 停止流程：
+// 1. 设置排空状态，拒绝新的请求；
+// 2. 停止Discovery监听和事件处理循环；
+// 3. 等待处理中的请求完成，直到超时；超时后强制取消剩余请求，并报告被取消的请求；
+// 4. 关闭WebListener；
+// 5. 按Orderer顺序停止BackendTransport等其它组件；
+// 等待请求超时后，第4、5步使用新的Context，最长等待 closeTimeout；
 func (s *BootstrapServer) Shutdown(ctx goctx.Context) error {
 	logger.Info("Server shutdown...")
 	defer close(s.stopped)
+	s.Drain()
+	// Discovery
+	s.shutdownDiscovery(ctx)
+	// In-flight requests
+	if count := s.inflight.Count(); count > 0 {
+		logger.Infow("Server shutdown, waiting in-flight requests", "count", count)
+	}
+	if !s.inflight.Wait(ctx) {
+		canceled := s.inflight.CancelAll()
+		logger.Warnw("Server shutdown, in-flight requests forcibly canceled", "count", len(canceled))
+		for _, req := range canceled {
+			logger.Trace(req.RequestId).Warnw("SERVER:SHUTDOWN:CANCELED",
+				"method", req.Method, "uri", req.URI, "elapsed", req.Elapsed.String())
+		}
+	}
+	closectx, cancel := closingContext(ctx, s.closeTimeout)
+	defer cancel()
 	for id, server := range s.listener {
-		if err := server.Close(ctx); nil != err {
+		if err := server.Close(closectx); nil != err {
 			logger.Warnw("Server["+id+"] shutdown http server", "error", err)
 		}
 	}
-	return s.router.Shutdown(ctx)
+	return s.router.Shutdown(closectx)
+}
+
+func (s *BootstrapServer) shutdownDiscovery(ctx goctx.Context) {
+	select {
+	case <-s.quit:
+		return
+	default:
+		close(s.quit)
+	}
+	// 等待正在处理的事件完成，避免停止后仍在通知BackendTransport
+	s.looping.Wait()
+	for _, dis := range s.endpointDiscoveries() {
+		if shutdown, ok := dis.(flux.Shutdowner); ok {
+			if err := shutdown.Shutdown(ctx); nil != err {
+				logger.Warnw("Server shutdown discovery", "discovery-id", dis.Id(), "error", err)
+			}
+		}
+	}
+}
+
+// InflightCount 返回正在处理中的请求数量
+func (s *BootstrapServer) InflightCount() int64 {
+	return s.inflight.Count()
 }
 
 // GracefulShutdown
@@ -409,16 +651,28 @@ func (s *BootstrapServer) AddWebExchangeHook(f flux.WebExchangeHook) {
 
 func (s *BootstrapServer) newEndpointHandler(server flux.WebListener, endpoint *flux.MultiEndpoint) flux.WebHandler {
 	return func(webex flux.WebExchange) error {
+		// 排空状态下，拒绝新的请求，并通知客户端关闭连接
+		if s.IsDraining() {
+			webex.SetResponseHeader("Connection", "close")
+			return flux.ErrServerDraining
+		}
 		return s.route(webex, server, endpoint)
 	}
 }
 
-func (s *BootstrapServer) selectMultiEndpoint(routeKey string, endpoint *flux.Endpoint) (*flux.MultiEndpoint, bool) {
+func (s *BootstrapServer) selectMultiEndpoint(routeKey string, endpoint *flux.Endpoint) *flux.MultiEndpoint {
 	if mve, ok := ext.EndpointByKey(routeKey); ok {
-		return mve, false
+		return mve
 	} else {
-		return ext.RegisterEndpoint(routeKey, endpoint), true
+		return ext.RegisterEndpoint(routeKey, endpoint)
+	}
+}
+
+func (s *BootstrapServer) endpointDiscoveries() []flux.EndpointDiscovery {
+	if nil != s.discoveries {
+		return s.discoveries
 	}
+	return ext.EndpointDiscoveries()
 }
 
 func (s *BootstrapServer) defaultListener() flux.WebListener {
@@ -0,0 +1,133 @@
+package boot
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testProtoEmbedded = "EMBEDDED_RECORD"
+
+type recordTransport struct {
+	flux.BackendTransport
+	events []flux.BackendServiceEvent
+}
+
+func (r *recordTransport) OnBackendServiceEvent(event flux.BackendServiceEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestBootstrapServer_EmbeddedServiceEvent(t *testing.T) {
+	assert := assert2.New(t)
+	transport := new(recordTransport)
+	ext.RegisterBackendTransport(testProtoEmbedded, transport)
+	endpoint := func(host string) flux.Endpoint {
+		return flux.Endpoint{
+			HttpMethod: "GET", HttpPattern: "/embedded", Version: "v1",
+			Service: flux.BackendService{
+				Interface: "net.bytepowered.Hello", Method: "hello", RemoteHost: host,
+				EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+					{Name: flux.ServiceAttrTagRpcProto, Value: testProtoEmbedded},
+				}},
+			},
+		}
+	}
+	server := NewBootstrapServerWith()
+	server.onHttpEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: endpoint("h1")})
+	server.onHttpEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeUpdated, Endpoint: endpoint("h2")})
+	server.onHttpEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeRemoved, Endpoint: endpoint("h2")})
+	// 没有定义Permission，只通知内嵌的Service
+	if !assert.Equal(3, len(transport.events)) {
+		return
+	}
+	for i, expected := range []struct {
+		eventType flux.EventType
+		host      string
+	}{
+		{eventType: flux.EventTypeAdded, host: "h1"},
+		{eventType: flux.EventTypeUpdated, host: "h2"},
+		{eventType: flux.EventTypeRemoved, host: "h2"},
+	} {
+		assert.Equal(expected.eventType, transport.events[i].EventType)
+		assert.Equal(expected.host, transport.events[i].Service.RemoteHost)
+	}
+}
+
+type readyDiscovery struct {
+	ready int32
+}
+
+func (d *readyDiscovery) Id() string {
+	return "ready-test"
+}
+
+func (d *readyDiscovery) WatchEndpoints(events chan<- flux.HttpEndpointEvent) error {
+	go func() {
+		for _, pattern := range []string{"/ready/a", "/ready/b"} {
+			events <- flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: flux.Endpoint{
+				HttpMethod: "GET", HttpPattern: pattern, Version: "v1",
+				Service: flux.BackendService{
+					Interface: "net.bytepowered.Hello", Method: pattern,
+					EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+						{Name: flux.ServiceAttrTagRpcProto, Value: testProtoReady},
+					}},
+				},
+			}}
+		}
+		atomic.StoreInt32(&d.ready, 1)
+	}()
+	return nil
+}
+
+func (d *readyDiscovery) WatchServices(_ chan<- flux.BackendServiceEvent) error {
+	return nil
+}
+
+func (d *readyDiscovery) Ready() bool {
+	return atomic.LoadInt32(&d.ready) == 1
+}
+
+const testProtoReady = "READY_RECORD"
+
+type readyTransport struct {
+	flux.BackendTransport
+	mutex  sync.Mutex
+	events int
+	ready  chan int
+}
+
+func (r *readyTransport) OnBackendServiceEvent(_ flux.BackendServiceEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events++
+}
+
+func (r *readyTransport) OnDiscoveryReady() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ready <- r.events
+}
+
+func TestBootstrapServer_DiscoveryReady(t *testing.T) {
+	assert := assert2.New(t)
+	transport := &readyTransport{ready: make(chan int, 1)}
+	ext.RegisterBackendTransport(testProtoReady, transport)
+	server := NewBootstrapServerWith()
+	server.discoveries = []flux.EndpointDiscovery{new(readyDiscovery)}
+	defer close(server.quit)
+	endpoints := make(chan sourcedEndpointEvent, 2)
+	services := make(chan sourcedServiceEvent, 2)
+	go server.loopDiscoveryEvents(endpoints, services)
+	assert.NoError(server.startDiscovery(endpoints, services))
+	// 初始事件全部分发到BackendTransport后，才通知Discovery就绪
+	select {
+	case dispatched := <-transport.ready:
+		assert.Equal(2, dispatched)
+	case <-time.After(time.Second * 3):
+		assert.Fail("discovery ready not notified")
+	}
+}
@@ -0,0 +1,343 @@
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ConfigKeySnapshotEnable   = "enable"
+	ConfigKeySnapshotPath     = "path"
+	ConfigKeySnapshotInterval = "interval"
+)
+
+const (
+	// 元数据来自注册中心
+	SnapshotSourceRegistry = "registry"
+	// 元数据来自本地快照，尚未与注册中心完成对账
+	SnapshotSourceSnapshot = "snapshot"
+)
+
+const (
+	defaultSnapshotPath     = "./snapshot/discovery.json"
+	defaultSnapshotInterval = time.Second * 30
+)
+
+// Snapshot 本地快照文件的数据结构
+type Snapshot struct {
+	CreatedAt time.Time             `json:"createdAt"`
+	Endpoints []flux.Endpoint       `json:"endpoints"`
+	Services  []flux.BackendService `json:"services"`
+}
+
+// SnapshotStatus 本地快照的运行状态
+type SnapshotStatus struct {
+	Enabled          bool      `json:"enabled"`
+	Path             string    `json:"path"`
+	Source           string    `json:"source"`
+	CreatedAt        time.Time `json:"createdAt"`
+	Age              string    `json:"age"`
+	SavedAt          time.Time `json:"savedAt"`
+	RestoredAt       time.Time `json:"restoredAt"`
+	Reconciled       bool      `json:"reconciled"`
+	Endpoints        int       `json:"endpoints"`
+	Services         int       `json:"services"`
+	PendingEndpoints int       `json:"pendingEndpoints"`
+	PendingServices  int       `json:"pendingServices"`
+}
+
+// DiscoverySnapshot 记录Discovery接收到的元数据，并定期持久化到本地快照文件；
+// 注册中心在启动时不可用时，从快照恢复路由；注册中心恢复后，删除未被注册中心确认的快照数据；
+type DiscoverySnapshot struct {
+	enabled   bool
+	path      string
+	interval  time.Duration
+	endpoints map[string]flux.Endpoint
+	services  map[string]flux.BackendService
+	// 从快照恢复、尚未被注册中心确认的元数据
+	restoredEndpoints map[string]flux.Endpoint
+	restoredServices  map[string]flux.BackendService
+	source            string
+	createdAt         time.Time
+	savedAt           time.Time
+	restoredAt        time.Time
+	reconciled        bool
+	dirty             bool
+	mutex             sync.RWMutex
+}
+
+// NewDiscoverySnapshotWith 根据配置创建本地快照；配置项：
+// enable: 是否开启本地快照，默认关闭；
+// path: 快照文件路径；
+// interval: 快照持久化的时间间隔；
+func NewDiscoverySnapshotWith(config *flux.Configuration) *DiscoverySnapshot {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeySnapshotEnable:   false,
+		ConfigKeySnapshotPath:     defaultSnapshotPath,
+		ConfigKeySnapshotInterval: defaultSnapshotInterval,
+	})
+	interval := config.GetDuration(ConfigKeySnapshotInterval)
+	if interval <= 0 {
+		interval = defaultSnapshotInterval
+	}
+	return &DiscoverySnapshot{
+		enabled:           config.GetBool(ConfigKeySnapshotEnable),
+		path:              config.GetString(ConfigKeySnapshotPath),
+		interval:          interval,
+		endpoints:         make(map[string]flux.Endpoint, 64),
+		services:          make(map[string]flux.BackendService, 64),
+		restoredEndpoints: make(map[string]flux.Endpoint),
+		restoredServices:  make(map[string]flux.BackendService),
+		source:            SnapshotSourceRegistry,
+		reconciled:        true,
+	}
+}
+
+func (d *DiscoverySnapshot) Enabled() bool {
+	return d.enabled
+}
+
+func (d *DiscoverySnapshot) Interval() time.Duration {
+	return d.interval
+}
+
+// Load 读取本地快照文件；文件不存在时返回nil
+func (d *DiscoverySnapshot) Load() (*Snapshot, error) {
+	bytes, err := ioutil.ReadFile(d.path)
+	if nil != err {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read snapshot, path: %s, error: %w", d.path, err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(bytes, &snapshot); nil != err {
+		return nil, fmt.Errorf("decode snapshot, path: %s, error: %w", d.path, err)
+	}
+	return &snapshot, nil
+}
+
+// Restore 标记快照数据为已恢复状态，等待注册中心确认
+func (d *DiscoverySnapshot) Restore(snapshot *Snapshot) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, ep := range snapshot.Endpoints {
+		d.restoredEndpoints[endpointSnapshotKey(&ep)] = ep
+	}
+	for _, srv := range snapshot.Services {
+		d.restoredServices[serviceSnapshotKey(&srv)] = srv
+	}
+	d.source = SnapshotSourceSnapshot
+	d.createdAt = snapshot.CreatedAt
+	d.restoredAt = time.Now()
+	d.reconciled = false
+}
+
+// OnEndpointEvent 记录来自注册中心的Endpoint事件
+func (d *DiscoverySnapshot) OnEndpointEvent(event flux.HttpEndpointEvent) {
+	key := endpointSnapshotKey(&event.Endpoint)
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.restoredEndpoints, key)
+	if event.EventType == flux.EventTypeRemoved {
+		delete(d.endpoints, key)
+	} else {
+		d.endpoints[key] = event.Endpoint
+	}
+	d.dirty = true
+}
+
+// OnServiceEvent 记录来自注册中心的Service事件
+func (d *DiscoverySnapshot) OnServiceEvent(event flux.BackendServiceEvent) {
+	key := serviceSnapshotKey(&event.Service)
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.restoredServices, key)
+	if event.EventType == flux.EventTypeRemoved {
+		delete(d.services, key)
+	} else {
+		d.services[key] = event.Service
+	}
+	d.dirty = true
+}
+
+// Reconcile 完成与注册中心的对账，返回未被注册中心确认、需要删除的快照数据
+func (d *DiscoverySnapshot) Reconcile() ([]flux.Endpoint, []flux.BackendService) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.reconciled {
+		return nil, nil
+	}
+	endpoints := make([]flux.Endpoint, 0, len(d.restoredEndpoints))
+	for _, ep := range d.restoredEndpoints {
+		endpoints = append(endpoints, ep)
+	}
+	services := make([]flux.BackendService, 0, len(d.restoredServices))
+	for _, srv := range d.restoredServices {
+		services = append(services, srv)
+	}
+	d.restoredEndpoints = make(map[string]flux.Endpoint)
+	d.restoredServices = make(map[string]flux.BackendService)
+	d.source = SnapshotSourceRegistry
+	d.reconciled = true
+	d.dirty = true
+	return endpoints, services
+}
+
+// Save 将注册中心的元数据持久化到快照文件；数据未变化时不写入；
+func (d *DiscoverySnapshot) Save() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if !d.dirty {
+		return nil
+	}
+	snapshot := Snapshot{
+		CreatedAt: time.Now(),
+		Endpoints: make([]flux.Endpoint, 0, len(d.endpoints)),
+		Services:  make([]flux.BackendService, 0, len(d.services)),
+	}
+	for _, ep := range d.endpoints {
+		snapshot.Endpoints = append(snapshot.Endpoints, ep)
+	}
+	for _, srv := range d.services {
+		snapshot.Services = append(snapshot.Services, srv)
+	}
+	bytes, err := json.MarshalIndent(snapshot, "", "  ")
+	if nil != err {
+		return fmt.Errorf("encode snapshot, error: %w", err)
+	}
+	if err := writeFileAtomic(d.path, bytes); nil != err {
+		return fmt.Errorf("write snapshot, path: %s, error: %w", d.path, err)
+	}
+	d.createdAt = snapshot.CreatedAt
+	d.savedAt = snapshot.CreatedAt
+	d.dirty = false
+	return nil
+}
+
+// Status 返回快照的运行状态
+func (d *DiscoverySnapshot) Status() SnapshotStatus {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	status := SnapshotStatus{
+		Enabled:          d.enabled,
+		Path:             d.path,
+		Source:           d.source,
+		CreatedAt:        d.createdAt,
+		SavedAt:          d.savedAt,
+		RestoredAt:       d.restoredAt,
+		Reconciled:       d.reconciled,
+		Endpoints:        len(d.endpoints),
+		Services:         len(d.services),
+		PendingEndpoints: len(d.restoredEndpoints),
+		PendingServices:  len(d.restoredServices),
+	}
+	if !d.createdAt.IsZero() {
+		status.Age = time.Since(d.createdAt).Truncate(time.Second).String()
+	}
+	return status
+}
+
+func endpointSnapshotKey(ep *flux.Endpoint) string {
+	return strings.ToUpper(ep.HttpMethod) + "#" + ep.HttpPattern + "#" + ep.Version
+}
+
+func serviceSnapshotKey(srv *flux.BackendService) string {
+	if "" != srv.ServiceId {
+		return srv.ServiceId
+	}
+	return srv.Interface + ":" + srv.Method
+}
+
+// 先写入临时文件再重命名，避免进程中断时产生不完整的快照文件
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); nil != err {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if nil != err {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); nil != err {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); nil != err {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// HandleSnapshot 查询本地快照的状态：数据来源、快照时间和快照年龄等
+func (s *BootstrapServer) HandleSnapshot(webex flux.WebExchange) error {
+	return webex.Send(webex, http.Header{}, flux.StatusOK, s.snapshot.Status())
+}
+
+func (s *BootstrapServer) snapshotEnabled() bool {
+	return nil != s.snapshot && s.snapshot.Enabled()
+}
+
+// restoreSnapshot 从本地快照恢复Endpoint和Service元数据；需要在Discovery事件循环启动前调用；
+func (s *BootstrapServer) restoreSnapshot() {
+	if !s.snapshotEnabled() {
+		return
+	}
+	snapshot, err := s.snapshot.Load()
+	if nil != err {
+		logger.Warnw("SERVER:SNAPSHOT:LOAD", "error", err)
+		return
+	}
+	if nil == snapshot {
+		logger.Infow("SERVER:SNAPSHOT:NOT_FOUND")
+		return
+	}
+	logger.Infow("SERVER:SNAPSHOT:RESTORE", "created-at", snapshot.CreatedAt,
+		"endpoints", len(snapshot.Endpoints), "services", len(snapshot.Services))
+	s.snapshot.Restore(snapshot)
+	for _, srv := range snapshot.Services {
+		s.onBackendServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeAdded, Service: srv})
+	}
+	for _, ep := range snapshot.Endpoints {
+		s.onHttpEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: ep})
+	}
+}
+
+// syncSnapshot 当全部Discovery就绪后，删除未被注册中心确认的快照数据，并持久化最新的元数据；
+func (s *BootstrapServer) syncSnapshot() {
+	if !s.discoveriesReady() {
+		return
+	}
+	endpoints, services := s.snapshot.Reconcile()
+	if len(endpoints) > 0 || len(services) > 0 {
+		logger.Infow("SERVER:SNAPSHOT:RECONCILE", "stale-endpoints", len(endpoints), "stale-services", len(services))
+	}
+	for _, ep := range endpoints {
+		s.onHttpEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeRemoved, Endpoint: ep})
+	}
+	for _, srv := range services {
+		s.onBackendServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeRemoved, Service: srv})
+	}
+	if err := s.snapshot.Save(); nil != err {
+		logger.Warnw("SERVER:SNAPSHOT:SAVE", "error", err)
+	}
+}
+
+func (s *BootstrapServer) discoveriesReady() bool {
+	for _, dis := range s.endpointDiscoveries() {
+		if r, ok := dis.(flux.Readiness); ok && !r.Ready() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package boot
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestSnapshot(t *testing.T) (*DiscoverySnapshot, func()) {
+	dir, err := ioutil.TempDir("", "flux-snapshot")
+	if nil != err {
+		t.Fatal(err)
+	}
+	snapshot := NewDiscoverySnapshotWith(flux.NewConfigurationOfMap(map[string]interface{}{
+		ConfigKeySnapshotEnable: true,
+		ConfigKeySnapshotPath:   filepath.Join(dir, "discovery.json"),
+	}))
+	return snapshot, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestDiscoverySnapshot_SaveLoad(t *testing.T) {
+	assert := assert2.New(t)
+	snapshot, clean := newTestSnapshot(t)
+	defer clean()
+	loaded, err := snapshot.Load()
+	assert.NoError(err)
+	assert.Nil(loaded)
+	snapshot.OnEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/a", Version: "v1",
+		Service: flux.BackendService{ServiceId: "a:get", Interface: "a", Method: "get"},
+	}})
+	snapshot.OnServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeAdded, Service: flux.BackendService{
+		Interface: "b", Method: "get",
+	}})
+	assert.NoError(snapshot.Save())
+	loaded, err = snapshot.Load()
+	assert.NoError(err)
+	assert.Equal(1, len(loaded.Endpoints))
+	assert.Equal("/a", loaded.Endpoints[0].HttpPattern)
+	assert.Equal(1, len(loaded.Services))
+	assert.Equal("b", loaded.Services[0].Interface)
+	status := snapshot.Status()
+	assert.Equal(SnapshotSourceRegistry, status.Source)
+	assert.False(status.SavedAt.IsZero())
+}
+
+func TestDiscoverySnapshot_Reconcile(t *testing.T) {
+	assert := assert2.New(t)
+	snapshot, clean := newTestSnapshot(t)
+	defer clean()
+	endpoint := func(pattern string) flux.Endpoint {
+		return flux.Endpoint{HttpMethod: "GET", HttpPattern: pattern, Version: "v1"}
+	}
+	snapshot.Restore(&Snapshot{
+		Endpoints: []flux.Endpoint{endpoint("/a"), endpoint("/b")},
+		Services:  []flux.BackendService{{ServiceId: "s1"}, {ServiceId: "s2"}},
+	})
+	status := snapshot.Status()
+	assert.Equal(SnapshotSourceSnapshot, status.Source)
+	assert.False(status.Reconciled)
+	assert.Equal(2, status.PendingEndpoints)
+	// 注册中心确认部分数据
+	snapshot.OnEndpointEvent(flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: endpoint("/a")})
+	snapshot.OnServiceEvent(flux.BackendServiceEvent{EventType: flux.EventTypeUpdated, Service: flux.BackendService{ServiceId: "s2"}})
+	endpoints, services := snapshot.Reconcile()
+	assert.Equal([]flux.Endpoint{endpoint("/b")}, endpoints)
+	assert.Equal([]flux.BackendService{{ServiceId: "s1"}}, services)
+	status = snapshot.Status()
+	assert.Equal(SnapshotSourceRegistry, status.Source)
+	assert.True(status.Reconciled)
+	assert.Equal(0, status.PendingEndpoints)
+	// 只对账一次
+	endpoints, services = snapshot.Reconcile()
+	assert.Nil(endpoints)
+	assert.Nil(services)
+}
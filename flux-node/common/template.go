@@ -0,0 +1,62 @@
+package common
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/spf13/cast"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+var (
+	// 模板变量，格式：${scope:key} 或者 ${scope:key|default}
+	templateVarPattern = regexp.MustCompile(`\$\{([^}]+)}`)
+)
+
+// RenderTemplate 渲染模板，使用请求中的值替换模板变量；
+// 模板变量格式：${scope:key}，例如：${path:id}, ${query:name}, ${header:X-Tenant}, ${attr:tenant}；
+// 可以使用 ${scope:key|default} 指定查找不到值时的默认值；
+func RenderTemplate(text string, ctx flux.Context) string {
+	if !strings.Contains(text, "${") {
+		return text
+	}
+	return templateVarPattern.ReplaceAllStringFunc(text, func(match string) string {
+		expr := strings.TrimSpace(match[2 : len(match)-1])
+		defval := ""
+		if idx := strings.Index(expr, "|"); idx >= 0 {
+			expr, defval = strings.TrimSpace(expr[:idx]), strings.TrimSpace(expr[idx+1:])
+		}
+		value, err := LookupMTValueByExpr(expr, ctx)
+		if nil != err {
+			ctx.Logger().Warnw("TEMPLATE:LOOKUP", "expr", expr, "error", err)
+			return defval
+		}
+		if str := templateValueString(value); "" != str {
+			return str
+		}
+		return defval
+	})
+}
+
+func templateValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case io.Reader:
+		if c, ok := v.(io.Closer); ok {
+			defer c.Close()
+		}
+		bytes, _ := ioutil.ReadAll(v)
+		return string(bytes)
+	}
+	if str, err := cast.ToStringE(value); nil == err {
+		return str
+	}
+	if bytes, err := ext.JSONMarshal(value); nil == err {
+		return string(bytes)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,28 @@
+package common
+
+import (
+	"github.com/bytepowered/flux/flux-node/context"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	ctx := context.NewMockWith("mock", map[string]interface{}{
+		"id":     "123",
+		"tenant": "t1",
+	})
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{text: "plain", expected: "plain"},
+		{text: `{"id":"${path:id}"}`, expected: `{"id":"123"}`},
+		{text: `${attr:tenant}/${ path:id }`, expected: "t1/123"},
+		{text: `${query:name|guest}`, expected: "guest"},
+		{text: `${invalid}`, expected: ""},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		assert.Equal(tcase.expected, RenderTemplate(tcase.text, ctx))
+	}
+}
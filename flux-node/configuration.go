@@ -13,6 +13,9 @@ const (
 	NamespaceWebListeners              = "web_listeners"
 	NamespaceBackendTransports         = "backend_transports"
 	NamespaceEndpointDiscoveryServices = "endpoint_discovery_services"
+	NamespaceMetrics                   = "metrics"
+	NamespaceDiscoverySnapshot         = "discovery_snapshot"
+	NamespaceDiscoveryPrecedence       = "discovery_precedence"
 )
 
 // NewGlobalConfiguration 创建全局Viper实例的配置对象
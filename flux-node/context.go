@@ -166,6 +166,10 @@ type Context interface {
 	// Response 返回响应数据接口
 	Response() Response
 
+	// Exchange 返回当前请求的WebExchange对象；
+	// 用于需要直接访问Web连接的场景，例如WebSocket代理接管客户端连接；
+	Exchange() WebExchange
+
 	// Application 返回当前Endpoint对应的应用名
 	Application() string
 
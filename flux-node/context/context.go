@@ -45,8 +45,14 @@ func ReleaseContext(c *AttacheContext) {
 }
 
 func New(webex flux.WebExchange, endpoint *flux.Endpoint) *AttacheContext {
+	return NewWith(webex.Context(), webex, endpoint)
+}
+
+// NewWith 使用指定的Context作为父Context，构建请求上下文；
+// 通常用于由外部控制请求的取消，例如服务停止时强制取消请求；
+func NewWith(parent context.Context, webex flux.WebExchange, endpoint *flux.Endpoint) *AttacheContext {
 	ctx := AcquireContext()
-	ctx.attach(webex, endpoint)
+	ctx.attach(parent, webex, endpoint)
 	return ctx
 }
 
@@ -58,6 +64,10 @@ func (c *AttacheContext) Response() flux.Response {
 	return c.response
 }
 
+func (c *AttacheContext) Exchange() flux.WebExchange {
+	return c.exchange
+}
+
 func (c *AttacheContext) Endpoint() flux.Endpoint {
 	return *c.endpoint()
 }
@@ -185,8 +195,8 @@ func (c *AttacheContext) endpoint() *flux.Endpoint {
 	return c.context.Value(internal.ContextKeyRouteEndpoint).(*flux.Endpoint)
 }
 
-func (c *AttacheContext) attach(webex flux.WebExchange, endpoint *flux.Endpoint) *AttacheContext {
-	c.context = context.WithValue(webex.Context(), internal.ContextKeyRouteEndpoint, endpoint)
+func (c *AttacheContext) attach(parent context.Context, webex flux.WebExchange, endpoint *flux.Endpoint) *AttacheContext {
+	c.context = context.WithValue(parent, internal.ContextKeyRouteEndpoint, endpoint)
 	c.exchange = webex
 	c.attributes = nil
 	c.variables = nil
@@ -192,6 +192,10 @@ func (mc *MockContext) Response() flux.Response {
 	return nil
 }
 
+func (mc *MockContext) Exchange() flux.WebExchange {
+	return nil
+}
+
 func (mc *MockContext) Endpoint() flux.Endpoint {
 	return flux.Endpoint{}
 }
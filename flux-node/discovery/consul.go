@@ -0,0 +1,377 @@
+package discovery
+
+import (
+	"context"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/bytepowered/flux/flux-node/remoting"
+	"github.com/bytepowered/flux/flux-node/remoting/consul"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ConsulId = "consul"
+)
+
+const (
+	consulConfigAddress        = "address"
+	consulConfigScheme         = "scheme"
+	consulConfigToken          = "token"
+	consulConfigDatacenter     = "datacenter"
+	consulConfigPrefixEndpoint = "prefix_endpoint"
+	consulConfigPrefixService  = "prefix_service"
+	consulConfigWaitTime       = "wait_time"
+	consulConfigRetryDelay     = "retry_delay"
+	consulConfigHealthEnable   = "health_enable"
+	consulConfigHealthPassing  = "health_passing"
+)
+
+const (
+	// ConsulAttrTagService 标识HTTP服务对应的Consul服务名称；开启健康检查时，由健康实例生成RemoteHost；
+	ConsulAttrTagService = "consulservice"
+)
+
+var (
+	_ flux.EndpointDiscovery = new(ConsulDiscoveryService)
+	_ flux.Readiness         = new(ConsulDiscoveryService)
+)
+
+type (
+	// ConsulOption 配置函数
+	ConsulOption func(discovery *ConsulDiscoveryService)
+)
+
+// ConsulDiscoveryService 基于Consul KV实现的Endpoint元数据注册中心；
+// Endpoint和Service以JSON格式存储在指定前缀的KV中，通过阻塞查询监听变化；
+type ConsulDiscoveryService struct {
+	id             string
+	client         *consul.Client
+	disabled       bool
+	endpointPrefix string
+	servicePrefix  string
+	waitTime       time.Duration
+	retryDelay     time.Duration
+	healthEnable   bool
+	healthPassing  bool
+	// KV原始数据，以及由健康实例生成的RemoteHost
+	endpoints   map[string]flux.Endpoint
+	services    map[string]flux.BackendService
+	hosts       map[string]string
+	healthWatch map[string]context.CancelFunc
+	epDiffer    *EndpointDiffer
+	srvDiffer   *ServiceDiffer
+	epEvents    chan<- flux.HttpEndpointEvent
+	srvEvents   chan<- flux.BackendServiceEvent
+	mutex       sync.Mutex
+	synced      int32
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+// WithConsulClient 指定Consul客户端
+func WithConsulClient(client *consul.Client) ConsulOption {
+	return func(discovery *ConsulDiscoveryService) {
+		discovery.client = client
+	}
+}
+
+// NewConsulServiceWith returns new a consul discovery service
+func NewConsulServiceWith(id string, opts ...ConsulOption) *ConsulDiscoveryService {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &ConsulDiscoveryService{
+		id:          id,
+		endpoints:   make(map[string]flux.Endpoint, 16),
+		services:    make(map[string]flux.BackendService, 16),
+		hosts:       make(map[string]string, 16),
+		healthWatch: make(map[string]context.CancelFunc, 16),
+		epDiffer:    NewEndpointDiffer(),
+		srvDiffer:   NewServiceDiffer(),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *ConsulDiscoveryService) Id() string {
+	return r.id
+}
+
+// Init 初始化；未配置Consul地址时，不启用此Discovery；
+func (r *ConsulDiscoveryService) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		consulConfigScheme:         "http",
+		consulConfigPrefixEndpoint: "flux-endpoint/",
+		consulConfigPrefixService:  "flux-service/",
+		consulConfigWaitTime:       time.Minute * 5,
+		consulConfigRetryDelay:     time.Second * 3,
+		consulConfigHealthEnable:   false,
+		consulConfigHealthPassing:  true,
+	})
+	address := config.GetString(consulConfigAddress)
+	if "" == address && nil == r.client {
+		logger.Infow("ConsulEndpointDiscovery disabled, address not configured")
+		r.disabled = true
+		return nil
+	}
+	if nil == r.client {
+		r.client = consul.NewClient(consul.ClientConfig{
+			Address:    address,
+			Scheme:     config.GetString(consulConfigScheme),
+			Token:      config.GetString(consulConfigToken),
+			Datacenter: config.GetString(consulConfigDatacenter),
+		})
+	}
+	r.endpointPrefix = config.GetString(consulConfigPrefixEndpoint)
+	r.servicePrefix = config.GetString(consulConfigPrefixService)
+	r.waitTime = config.GetDuration(consulConfigWaitTime)
+	r.retryDelay = config.GetDuration(consulConfigRetryDelay)
+	r.healthEnable = config.GetBool(consulConfigHealthEnable)
+	r.healthPassing = config.GetBool(consulConfigHealthPassing)
+	logger.Infow("ConsulEndpointDiscovery init", "address", address,
+		"prefix-endpoint", r.endpointPrefix, "prefix-service", r.servicePrefix, "health-enable", r.healthEnable)
+	return nil
+}
+
+// WatchEndpoints 监听Endpoint前缀的KV变化
+func (r *ConsulDiscoveryService) WatchEndpoints(events chan<- flux.HttpEndpointEvent) error {
+	if r.disabled {
+		return nil
+	}
+	r.mutex.Lock()
+	r.epEvents = events
+	r.mutex.Unlock()
+	logger.Infow("DISCOVERY:CONSUL:ENDPOINT:WATCH", "prefix", r.endpointPrefix)
+	go r.watchPrefix(r.endpointPrefix, r.onEndpointPairs)
+	return nil
+}
+
+// WatchServices 监听Service前缀的KV变化
+func (r *ConsulDiscoveryService) WatchServices(events chan<- flux.BackendServiceEvent) error {
+	if r.disabled {
+		return nil
+	}
+	r.mutex.Lock()
+	r.srvEvents = events
+	r.mutex.Unlock()
+	logger.Infow("DISCOVERY:CONSUL:SERVICE:WATCH", "prefix", r.servicePrefix)
+	go r.watchPrefix(r.servicePrefix, r.onServicePairs)
+	return nil
+}
+
+// Ready 当Endpoint和Service前缀均完成首次查询后，返回就绪状态
+func (r *ConsulDiscoveryService) Ready() bool {
+	return r.disabled || atomic.LoadInt32(&r.synced) >= 2
+}
+
+// Shutdown 停止全部阻塞查询
+func (r *ConsulDiscoveryService) Shutdown(ctx context.Context) error {
+	logger.Info("ConsulEndpointDiscovery shutdown")
+	r.cancel()
+	return nil
+}
+
+func (r *ConsulDiscoveryService) watchPrefix(prefix string, onPairs func([]consul.KVPair)) {
+	var index uint64
+	first := true
+	for {
+		pairs, next, err := r.client.KVList(r.ctx, prefix, consul.QueryOptions{WaitIndex: index, WaitTime: r.waitTime})
+		if nil != err {
+			if nil != r.ctx.Err() {
+				return
+			}
+			logger.Warnw("DISCOVERY:CONSUL:KV:QUERY", "prefix", prefix, "error", err)
+			if !r.sleep(r.retryDelay) {
+				return
+			}
+			continue
+		}
+		if next != index || first {
+			onPairs(pairs)
+		}
+		if first {
+			first = false
+			atomic.AddInt32(&r.synced, 1)
+		}
+		index = consul.NextIndex(index, next)
+	}
+}
+
+func (r *ConsulDiscoveryService) onEndpointPairs(pairs []consul.KVPair) {
+	endpoints := make(map[string]flux.Endpoint, len(pairs))
+	for _, pair := range pairs {
+		if len(pair.Value) == 0 {
+			continue
+		}
+		if evt, ok := NewEndpointEvent(pair.Value, remoting.EventTypeNodeAdd, pair.Key); ok {
+			endpoints[pair.Key] = evt.Endpoint
+		}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.endpoints = endpoints
+	r.syncHealthWatches()
+	r.publishEndpoints()
+}
+
+func (r *ConsulDiscoveryService) onServicePairs(pairs []consul.KVPair) {
+	services := make(map[string]flux.BackendService, len(pairs))
+	for _, pair := range pairs {
+		if len(pair.Value) == 0 {
+			continue
+		}
+		if evt, ok := NewBackendServiceEvent(pair.Value, remoting.EventTypeNodeAdd, pair.Key); ok {
+			services[pair.Key] = evt.Service
+		}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.services = services
+	r.syncHealthWatches()
+	r.publishServices()
+}
+
+// syncHealthWatches 按引用的Consul服务名称，启动或者停止健康实例的阻塞查询；需要在锁内调用；
+func (r *ConsulDiscoveryService) syncHealthWatches() {
+	if !r.healthEnable {
+		return
+	}
+	names := make(map[string]struct{}, len(r.healthWatch))
+	for _, ep := range r.endpoints {
+		if name := consulServiceName(&ep.Service); "" != name {
+			names[name] = struct{}{}
+		}
+	}
+	for _, srv := range r.services {
+		if name := consulServiceName(&srv); "" != name {
+			names[name] = struct{}{}
+		}
+	}
+	for name := range names {
+		if _, ok := r.healthWatch[name]; !ok {
+			ctx, cancel := context.WithCancel(r.ctx)
+			r.healthWatch[name] = cancel
+			go r.watchHealth(ctx, name)
+		}
+	}
+	for name, cancel := range r.healthWatch {
+		if _, ok := names[name]; !ok {
+			cancel()
+			delete(r.healthWatch, name)
+			delete(r.hosts, name)
+		}
+	}
+}
+
+func (r *ConsulDiscoveryService) watchHealth(ctx context.Context, name string) {
+	logger.Infow("DISCOVERY:CONSUL:HEALTH:WATCH", "service", name)
+	var index uint64
+	for {
+		entries, next, err := r.client.HealthService(ctx, name, r.healthPassing, consul.QueryOptions{WaitIndex: index, WaitTime: r.waitTime})
+		if nil != err {
+			if nil != ctx.Err() {
+				return
+			}
+			logger.Warnw("DISCOVERY:CONSUL:HEALTH:QUERY", "service", name, "error", err)
+			if !r.sleep(r.retryDelay) {
+				return
+			}
+			continue
+		}
+		index = consul.NextIndex(index, next)
+		host := SelectHealthHost(entries)
+		r.mutex.Lock()
+		if nil == ctx.Err() && r.hosts[name] != host {
+			logger.Infow("DISCOVERY:CONSUL:HEALTH:CHANGED", "service", name, "host", host, "instances", len(entries))
+			r.hosts[name] = host
+			r.publishEndpoints()
+			r.publishServices()
+		}
+		r.mutex.Unlock()
+	}
+}
+
+// publishEndpoints 对比Endpoint快照，发送变更事件；需要在锁内调用；
+func (r *ConsulDiscoveryService) publishEndpoints() {
+	if nil == r.epEvents {
+		return
+	}
+	next := make(map[string]flux.Endpoint, len(r.endpoints))
+	for key, ep := range r.endpoints {
+		ep.Service = r.resolveRemoteHost(ep.Service)
+		next[key] = ep
+	}
+	for _, evt := range r.epDiffer.Diff(next) {
+		select {
+		case r.epEvents <- evt:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// publishServices 对比Service快照，发送变更事件；需要在锁内调用；
+func (r *ConsulDiscoveryService) publishServices() {
+	if nil == r.srvEvents {
+		return
+	}
+	next := make(map[string]flux.BackendService, len(r.services))
+	for key, srv := range r.services {
+		next[key] = r.resolveRemoteHost(srv)
+	}
+	for _, evt := range r.srvDiffer.Diff(next) {
+		select {
+		case r.srvEvents <- evt:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *ConsulDiscoveryService) resolveRemoteHost(service flux.BackendService) flux.BackendService {
+	if !r.healthEnable {
+		return service
+	}
+	if name := consulServiceName(&service); "" != name {
+		if host, ok := r.hosts[name]; ok && "" != host {
+			service.RemoteHost = host
+		}
+	}
+	return service
+}
+
+func (r *ConsulDiscoveryService) sleep(d time.Duration) bool {
+	select {
+	case <-r.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// SelectHealthHost 从健康实例中选择RemoteHost；按地址排序后选择第一个，保证结果稳定；
+func SelectHealthHost(entries []consul.ServiceEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	hosts := make([]string, len(entries))
+	for i, e := range entries {
+		hosts[i] = e.HostAddress()
+	}
+	sort.Strings(hosts)
+	return hosts[0]
+}
+
+// 只有HTTP协议的服务，才由Consul健康实例生成RemoteHost
+func consulServiceName(service *flux.BackendService) string {
+	if !strings.EqualFold(flux.ProtoHttp, service.AttrRpcProto()) {
+		return ""
+	}
+	return service.GetAttr(ConsulAttrTagService).GetString()
+}
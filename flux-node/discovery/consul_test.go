@@ -0,0 +1,200 @@
+package discovery
+
+import (
+	"encoding/json"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/remoting/consul"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 模拟Consul HTTP API：Index未变化时，短暂等待后返回相同的Index
+type consulStub struct {
+	index   uint64
+	kv      map[string]string
+	health  map[string][]consul.ServiceEntry
+	queries []string
+	mutex   sync.Mutex
+}
+
+func (s *consulStub) set(update func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	update()
+	s.index++
+}
+
+func (s *consulStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("index") == strconv.FormatUint(s.currentIndex(), 10) {
+		time.Sleep(time.Millisecond * 20)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queries = append(s.queries, r.URL.String())
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(s.index, 10))
+	var out interface{}
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		pairs := make([]consul.KVPair, 0)
+		for k, v := range s.kv {
+			if strings.HasPrefix(k, prefix) {
+				pairs = append(pairs, consul.KVPair{Key: k, Value: []byte(v)})
+			}
+		}
+		if len(pairs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		out = pairs
+	case strings.HasPrefix(r.URL.Path, "/v1/health/service/"):
+		out = s.health[strings.TrimPrefix(r.URL.Path, "/v1/health/service/")]
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	bytes, _ := json.Marshal(out)
+	_, _ = w.Write(bytes)
+}
+
+func (s *consulStub) currentIndex() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.index
+}
+
+func newConsulTestService(t *testing.T, stub *consulStub, health bool) *ConsulDiscoveryService {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	discovery := NewConsulServiceWith(ConsulId)
+	t.Cleanup(func() {
+		_ = discovery.Shutdown(nil)
+	})
+	err := discovery.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		consulConfigAddress:      strings.TrimPrefix(server.URL, "http://"),
+		consulConfigWaitTime:     time.Second,
+		consulConfigRetryDelay:   time.Millisecond * 10,
+		consulConfigHealthEnable: health,
+	}))
+	if nil != err {
+		t.Fatal(err)
+	}
+	return discovery
+}
+
+func TestConsulDiscoveryService_Disabled(t *testing.T) {
+	assert := assert2.New(t)
+	discovery := NewConsulServiceWith(ConsulId)
+	assert.NoError(discovery.Init(flux.NewEmptyConfiguration()))
+	assert.NoError(discovery.WatchEndpoints(make(chan flux.HttpEndpointEvent)))
+	assert.True(discovery.Ready())
+}
+
+func TestConsulDiscoveryService_WatchEndpoints(t *testing.T) {
+	assert := assert2.New(t)
+	stub := &consulStub{index: 1, kv: map[string]string{
+		"flux-endpoint/a": `{"httpMethod":"GET","httpPattern":"/a","service":{"interface":"a","method":"get","rpcProto":"ECHO"}}`,
+		"flux-endpoint/b": `{"httpMethod":"GET","httpPattern":"/b","service":{"interface":"b","method":"get","rpcProto":"ECHO"}}`,
+	}}
+	discovery := newConsulTestService(t, stub, false)
+	events := make(chan flux.HttpEndpointEvent, 16)
+	assert.NoError(discovery.WatchEndpoints(events))
+	for _, pattern := range []string{"/a", "/b"} {
+		evt := receiveEndpointEvent(t, events)
+		assert.Equal(flux.EventType(flux.EventTypeAdded), evt.EventType)
+		assert.Equal(pattern, evt.Endpoint.HttpPattern)
+	}
+	stub.set(func() {
+		stub.kv["flux-endpoint/a"] = `{"httpMethod":"GET","httpPattern":"/a","service":{"interface":"a","method":"post","rpcProto":"ECHO"}}`
+		delete(stub.kv, "flux-endpoint/b")
+	})
+	evt := receiveEndpointEvent(t, events)
+	assert.Equal(flux.EventType(flux.EventTypeRemoved), evt.EventType)
+	assert.Equal("/b", evt.Endpoint.HttpPattern)
+	evt = receiveEndpointEvent(t, events)
+	assert.Equal(flux.EventType(flux.EventTypeUpdated), evt.EventType)
+	assert.Equal("post", evt.Endpoint.Service.Method)
+	assert.NoError(discovery.WatchServices(make(chan flux.BackendServiceEvent, 16)))
+	assert.Eventually(discovery.Ready, time.Second, time.Millisecond*10)
+}
+
+func TestConsulDiscoveryService_HealthRemoteHost(t *testing.T) {
+	assert := assert2.New(t)
+	entry := func(addr string, port int) consul.ServiceEntry {
+		e := consul.ServiceEntry{}
+		e.Node.Address = addr
+		e.Service.Port = port
+		return e
+	}
+	stub := &consulStub{index: 1,
+		kv: map[string]string{
+			"flux-service/user": `{"serviceId":"user","interface":"/user","method":"GET",
+"attributes":[{"name":"rpcproto","value":"HTTP"},{"name":"consulservice","value":"user-api"}]}`,
+		},
+		health: map[string][]consul.ServiceEntry{
+			"user-api": {entry("10.0.0.2", 8080), entry("10.0.0.1", 8080)},
+		},
+	}
+	discovery := newConsulTestService(t, stub, true)
+	events := make(chan flux.BackendServiceEvent, 16)
+	assert.NoError(discovery.WatchServices(events))
+	evt := receiveServiceEvent(t, events)
+	assert.Equal(flux.EventType(flux.EventTypeAdded), evt.EventType)
+	if "" == evt.Service.RemoteHost {
+		// 健康实例查询完成前，先发送了原始数据
+		evt = receiveServiceEvent(t, events)
+		assert.Equal(flux.EventType(flux.EventTypeUpdated), evt.EventType)
+	}
+	assert.Equal("10.0.0.1:8080", evt.Service.RemoteHost)
+	stub.set(func() {
+		stub.health["user-api"] = []consul.ServiceEntry{entry("10.0.0.3", 9090)}
+	})
+	evt = receiveServiceEvent(t, events)
+	assert.Equal(flux.EventType(flux.EventTypeUpdated), evt.EventType)
+	assert.Equal("10.0.0.3:9090", evt.Service.RemoteHost)
+}
+
+func TestNextIndex(t *testing.T) {
+	cases := []struct {
+		last     uint64
+		index    uint64
+		expected uint64
+	}{
+		{last: 0, index: 0, expected: 1},
+		{last: 0, index: 5, expected: 5},
+		{last: 10, index: 5, expected: 0},
+		{last: 5, index: 5, expected: 5},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		assert.Equal(tcase.expected, consul.NextIndex(tcase.last, tcase.index))
+	}
+}
+
+func receiveEndpointEvent(t *testing.T, events <-chan flux.HttpEndpointEvent) flux.HttpEndpointEvent {
+	select {
+	case evt := <-events:
+		return evt
+	case <-time.After(time.Second * 3):
+		t.Fatal("timeout waiting endpoint event")
+	}
+	return flux.HttpEndpointEvent{}
+}
+
+func receiveServiceEvent(t *testing.T, events <-chan flux.BackendServiceEvent) flux.BackendServiceEvent {
+	select {
+	case evt := <-events:
+		return evt
+	case <-time.After(time.Second * 3):
+		t.Fatal("timeout waiting service event")
+	}
+	return flux.BackendServiceEvent{}
+}
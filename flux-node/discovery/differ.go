@@ -0,0 +1,258 @@
+package discovery
+
+import (
+	"encoding/json"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"sort"
+	"strings"
+)
+
+// EndpointDiffer 对比Endpoint全量快照，生成新增、更新和删除事件；
+// 适用于每次只能拉取全量数据的注册中心，例如：Consul KV，Nacos配置，Http拉取；
+type EndpointDiffer struct {
+	items   map[string]flux.Endpoint
+	digests map[string]string
+}
+
+// ServiceDiffer 对比BackendService全量快照，生成新增、更新和删除事件
+type ServiceDiffer struct {
+	items   map[string]flux.BackendService
+	digests map[string]string
+}
+
+func NewEndpointDiffer() *EndpointDiffer {
+	return &EndpointDiffer{
+		items:   make(map[string]flux.Endpoint, 16),
+		digests: make(map[string]string, 16),
+	}
+}
+
+func NewServiceDiffer() *ServiceDiffer {
+	return &ServiceDiffer{
+		items:   make(map[string]flux.BackendService, 16),
+		digests: make(map[string]string, 16),
+	}
+}
+
+// Diff 对比全量快照，返回变更事件，并将快照更新为最新状态；删除事件在新增和更新事件之前；
+func (d *EndpointDiffer) Diff(next map[string]flux.Endpoint) []flux.HttpEndpointEvent {
+	events := make([]flux.HttpEndpointEvent, 0)
+	// 先发送删除事件，再发送新增和更新事件
+	for _, key := range sortedKeys(d.items) {
+		if _, ok := next[key]; !ok {
+			events = append(events, flux.HttpEndpointEvent{EventType: flux.EventTypeRemoved, Endpoint: d.items[key]})
+		}
+	}
+	digests := make(map[string]string, len(next))
+	for _, key := range sortedKeys(next) {
+		ep := next[key]
+		digest := digestOf(ep)
+		digests[key] = digest
+		if old, ok := d.digests[key]; !ok {
+			events = append(events, flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: ep})
+		} else if old != digest {
+			events = append(events, flux.HttpEndpointEvent{EventType: flux.EventTypeUpdated, Endpoint: ep})
+		}
+	}
+	d.items, d.digests = copyEndpoints(next), digests
+	return events
+}
+
+// Diff 对比全量快照，返回变更事件，并将快照更新为最新状态；删除事件在新增和更新事件之前；
+func (d *ServiceDiffer) Diff(next map[string]flux.BackendService) []flux.BackendServiceEvent {
+	events := make([]flux.BackendServiceEvent, 0)
+	// 先发送删除事件，再发送新增和更新事件
+	for _, key := range sortedKeys(d.items) {
+		if _, ok := next[key]; !ok {
+			events = append(events, flux.BackendServiceEvent{EventType: flux.EventTypeRemoved, Service: d.items[key]})
+		}
+	}
+	digests := make(map[string]string, len(next))
+	for _, key := range sortedKeys(next) {
+		srv := next[key]
+		digest := digestOf(srv)
+		digests[key] = digest
+		if old, ok := d.digests[key]; !ok {
+			events = append(events, flux.BackendServiceEvent{EventType: flux.EventTypeAdded, Service: srv})
+		} else if old != digest {
+			events = append(events, flux.BackendServiceEvent{EventType: flux.EventTypeUpdated, Service: srv})
+		}
+	}
+	d.items, d.digests = copyServices(next), digests
+	return events
+}
+
+func copyEndpoints(in map[string]flux.Endpoint) map[string]flux.Endpoint {
+	out := make(map[string]flux.Endpoint, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+func copyServices(in map[string]flux.BackendService) map[string]flux.BackendService {
+	out := make(map[string]flux.BackendService, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0, 16)
+	switch items := m.(type) {
+	case map[string]flux.Endpoint:
+		for k := range items {
+			keys = append(keys, k)
+		}
+	case map[string]flux.BackendService:
+		for k := range items {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 以JSON序列化结果作为数据摘要；不使用DeepEqual，因为参数的函数字段在注册后会被赋值；
+func digestOf(v interface{}) string {
+	bytes, err := json.Marshal(v)
+	if nil != err {
+		return ""
+	}
+	return string(bytes)
+}
+
+// ResourcesPublisher 合并多个来源（DataId，URL等）的Resources文档，对比快照后发送变更事件；
+// 合并结果以 Method+Pattern+Version（Service为ServiceId）为Key，不包含来源：定义在来源之间移动时，只产生更新事件；
+// 多个来源重复定义时，按来源名称排序，使用第一个来源的定义；
+// 非线程安全，由调用方加锁；
+type ResourcesPublisher struct {
+	tag       string
+	sources   map[string]Resources
+	epDiffer  *EndpointDiffer
+	srvDiffer *ServiceDiffer
+	epEvents  chan<- flux.HttpEndpointEvent
+	srvEvents chan<- flux.BackendServiceEvent
+	done      <-chan struct{}
+}
+
+// NewResourcesPublisher 创建Publisher；tag 用于日志标识，done 关闭时停止发送事件；
+func NewResourcesPublisher(tag string, done <-chan struct{}) *ResourcesPublisher {
+	return &ResourcesPublisher{
+		tag:       tag,
+		sources:   make(map[string]Resources, 8),
+		epDiffer:  NewEndpointDiffer(),
+		srvDiffer: NewServiceDiffer(),
+		done:      done,
+	}
+}
+
+// SetEndpointEvents 设置Endpoint事件Channel，并发送当前的全部Endpoint
+func (p *ResourcesPublisher) SetEndpointEvents(events chan<- flux.HttpEndpointEvent) {
+	p.epEvents = events
+	p.PublishEndpoints()
+}
+
+// SetServiceEvents 设置Service事件Channel，并发送当前的全部Service
+func (p *ResourcesPublisher) SetServiceEvents(events chan<- flux.BackendServiceEvent) {
+	p.srvEvents = events
+	p.PublishServices()
+}
+
+// Update 更新指定来源的资源文档，并发送变更事件
+func (p *ResourcesPublisher) Update(source string, resources Resources) {
+	p.sources[source] = resources
+	p.PublishEndpoints()
+	p.PublishServices()
+}
+
+// Remove 删除指定来源的资源文档，并发送变更事件
+func (p *ResourcesPublisher) Remove(source string) {
+	delete(p.sources, source)
+	p.PublishEndpoints()
+	p.PublishServices()
+}
+
+// PublishEndpoints 合并全部来源的Endpoint，对比快照并发送变更事件
+func (p *ResourcesPublisher) PublishEndpoints() {
+	if nil == p.epEvents {
+		return
+	}
+	next := make(map[string]flux.Endpoint, 16)
+	defined := make(map[string]string, 16)
+	for _, source := range p.sortedSources() {
+		res := p.sources[source]
+		for _, ep := range res.Endpoints {
+			if !ep.IsValid() {
+				logger.Warnw("DISCOVERY:"+p.tag+":ENDPOINT:INVALID_VALUES", "source", source, "pattern", ep.HttpPattern)
+				continue
+			}
+			EnsureServiceAttrs(&ep.Service)
+			EnsureServiceAttrs(&ep.Permission)
+			if !VerifyEndpoint(&ep, source) {
+				continue
+			}
+			key := strings.ToUpper(ep.HttpMethod) + "#" + ep.HttpPattern + "#" + ep.Version
+			if prev, ok := defined[key]; ok {
+				logger.Warnw("DISCOVERY:"+p.tag+":ENDPOINT:DUPLICATED", "key", key, "source", source, "effective-source", prev)
+				continue
+			}
+			defined[key] = source
+			next[key] = ep
+		}
+	}
+	for _, evt := range p.epDiffer.Diff(next) {
+		select {
+		case p.epEvents <- evt:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// PublishServices 合并全部来源的Service，对比快照并发送变更事件
+func (p *ResourcesPublisher) PublishServices() {
+	if nil == p.srvEvents {
+		return
+	}
+	next := make(map[string]flux.BackendService, 16)
+	defined := make(map[string]string, 16)
+	for _, source := range p.sortedSources() {
+		res := p.sources[source]
+		for _, srv := range res.Services {
+			if !srv.IsValid() {
+				logger.Warnw("DISCOVERY:"+p.tag+":SERVICE:INVALID_VALUES", "source", source, "service-id", srv.ServiceId)
+				continue
+			}
+			EnsureServiceAttrs(&srv)
+			if !VerifyService(&srv, source) {
+				continue
+			}
+			key := srv.ServiceID() + "#" + srv.ServiceId
+			if prev, ok := defined[key]; ok {
+				logger.Warnw("DISCOVERY:"+p.tag+":SERVICE:DUPLICATED", "key", key, "source", source, "effective-source", prev)
+				continue
+			}
+			defined[key] = source
+			next[key] = srv
+		}
+	}
+	for _, evt := range p.srvDiffer.Diff(next) {
+		select {
+		case p.srvEvents <- evt:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *ResourcesPublisher) sortedSources() []string {
+	sources := make([]string, 0, len(p.sources))
+	for source := range p.sources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
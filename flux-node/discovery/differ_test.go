@@ -0,0 +1,52 @@
+package discovery
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEndpointDiffer_Diff(t *testing.T) {
+	endpoint := func(pattern, method string) flux.Endpoint {
+		return flux.Endpoint{HttpMethod: "GET", HttpPattern: pattern,
+			Service: flux.BackendService{Interface: "a", Method: method}}
+	}
+	cases := []struct {
+		next     map[string]flux.Endpoint
+		expected []flux.EventType
+	}{
+		{
+			next:     map[string]flux.Endpoint{"a": endpoint("/a", "m1"), "b": endpoint("/b", "m1")},
+			expected: []flux.EventType{flux.EventTypeAdded, flux.EventTypeAdded},
+		},
+		{
+			next:     map[string]flux.Endpoint{"a": endpoint("/a", "m1"), "b": endpoint("/b", "m1")},
+			expected: []flux.EventType{},
+		},
+		{
+			next:     map[string]flux.Endpoint{"a": endpoint("/a", "m2"), "c": endpoint("/c", "m1")},
+			expected: []flux.EventType{flux.EventTypeRemoved, flux.EventTypeUpdated, flux.EventTypeAdded},
+		},
+	}
+	assert := assert2.New(t)
+	differ := NewEndpointDiffer()
+	for _, tcase := range cases {
+		types := make([]flux.EventType, 0)
+		for _, evt := range differ.Diff(tcase.next) {
+			types = append(types, evt.EventType)
+		}
+		assert.Equal(tcase.expected, types)
+	}
+}
+
+func TestServiceDiffer_Diff(t *testing.T) {
+	assert := assert2.New(t)
+	differ := NewServiceDiffer()
+	events := differ.Diff(map[string]flux.BackendService{"s1": {ServiceId: "s1"}})
+	assert.Equal(1, len(events))
+	assert.Equal(flux.EventType(flux.EventTypeAdded), events[0].EventType)
+	events = differ.Diff(map[string]flux.BackendService{})
+	assert.Equal(1, len(events))
+	assert.Equal(flux.EventType(flux.EventTypeRemoved), events[0].EventType)
+	assert.Equal("s1", events[0].Service.ServiceId)
+}
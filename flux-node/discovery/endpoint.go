@@ -43,6 +43,10 @@ func NewEndpointEvent(bytes []byte, etype remoting.EventType, node string) (fxEv
 	}
 	EnsureServiceAttrs(&comp.Service)
 	EnsureServiceAttrs(&comp.Permission)
+	// 删除事件不需要校验定义内容
+	if etype != remoting.EventTypeNodeDelete && !VerifyEndpoint(&comp.Endpoint, node) {
+		return invalidHttpEndpointEvent, false
+	}
 
 	event := flux.HttpEndpointEvent{Endpoint: comp.Endpoint}
 	switch etype {
@@ -0,0 +1,234 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	HttpId = "http"
+)
+
+const (
+	httpConfigUrls       = "urls"
+	httpConfigInterval   = "interval"
+	httpConfigTimeout    = "timeout"
+	httpConfigHeaders    = "headers"
+	httpConfigBackoffMax = "backoff_max"
+)
+
+var (
+	_ flux.EndpointDiscovery = new(HttpDiscoveryService)
+	_ flux.Readiness         = new(HttpDiscoveryService)
+)
+
+type (
+	// HttpOption 配置函数
+	HttpOption func(discovery *HttpDiscoveryService)
+)
+
+// HttpDiscoveryService 定时拉取Http地址的Resources文档（JSON/YAML）实现的Endpoint元数据注册中心；
+// 支持ETag/If-None-Match，对比前后两次文档生成变更事件，拉取失败时按指数退避重试；
+type HttpDiscoveryService struct {
+	id         string
+	client     *http.Client
+	disabled   bool
+	urls       []string
+	headers    map[string]string
+	interval   time.Duration
+	backoffMax time.Duration
+	etags      map[string]string
+	publisher  *ResourcesPublisher
+	mutex      sync.Mutex
+	started    sync.Once
+	synced     int32
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// WithHttpClient 指定Http客户端
+func WithHttpClient(client *http.Client) HttpOption {
+	return func(discovery *HttpDiscoveryService) {
+		discovery.client = client
+	}
+}
+
+// NewHttpServiceWith returns new a http polling discovery service
+func NewHttpServiceWith(id string, opts ...HttpOption) *HttpDiscoveryService {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &HttpDiscoveryService{
+		id:        id,
+		etags:     make(map[string]string, 4),
+		publisher: NewResourcesPublisher("HTTP", ctx.Done()),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *HttpDiscoveryService) Id() string {
+	return r.id
+}
+
+// Init 初始化；未配置拉取地址时，不启用此Discovery；
+func (r *HttpDiscoveryService) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		httpConfigInterval:   time.Second * 30,
+		httpConfigTimeout:    time.Second * 10,
+		httpConfigBackoffMax: time.Minute * 5,
+	})
+	r.urls = config.GetStringSlice(httpConfigUrls)
+	if len(r.urls) == 0 {
+		logger.Infow("HttpEndpointDiscovery disabled, urls not configured")
+		r.disabled = true
+		return nil
+	}
+	r.interval = config.GetDuration(httpConfigInterval)
+	r.backoffMax = config.GetDuration(httpConfigBackoffMax)
+	if r.interval <= 0 {
+		return fmt.Errorf("http discovery, invalid interval: %s", r.interval)
+	}
+	r.headers = config.GetStringMapString(httpConfigHeaders)
+	if nil == r.client {
+		r.client = &http.Client{Timeout: config.GetDuration(httpConfigTimeout)}
+	}
+	logger.Infow("HttpEndpointDiscovery init", "urls", r.urls, "interval", r.interval)
+	return nil
+}
+
+// WatchEndpoints 监听拉取文档中的Endpoint变化
+func (r *HttpDiscoveryService) WatchEndpoints(events chan<- flux.HttpEndpointEvent) error {
+	if r.disabled {
+		return nil
+	}
+	r.mutex.Lock()
+	r.publisher.SetEndpointEvents(events)
+	r.mutex.Unlock()
+	r.started.Do(r.startPolling)
+	return nil
+}
+
+// WatchServices 监听拉取文档中的Service变化
+func (r *HttpDiscoveryService) WatchServices(events chan<- flux.BackendServiceEvent) error {
+	if r.disabled {
+		return nil
+	}
+	r.mutex.Lock()
+	r.publisher.SetServiceEvents(events)
+	r.mutex.Unlock()
+	r.started.Do(r.startPolling)
+	return nil
+}
+
+// Ready 当全部地址完成首次拉取后，返回就绪状态
+func (r *HttpDiscoveryService) Ready() bool {
+	return r.disabled || int(atomic.LoadInt32(&r.synced)) >= len(r.urls)
+}
+
+// Shutdown 停止拉取
+func (r *HttpDiscoveryService) Shutdown(ctx context.Context) error {
+	logger.Info("HttpEndpointDiscovery shutdown")
+	r.cancel()
+	return nil
+}
+
+func (r *HttpDiscoveryService) startPolling() {
+	for _, url := range r.urls {
+		go r.poll(url)
+	}
+}
+
+func (r *HttpDiscoveryService) poll(url string) {
+	logger.Infow("DISCOVERY:HTTP:POLL", "url", url)
+	failures := 0
+	first := true
+	for {
+		if err := r.fetch(url); nil != err {
+			if nil != r.ctx.Err() {
+				return
+			}
+			failures++
+			logger.Warnw("DISCOVERY:HTTP:FETCH", "url", url, "failures", failures, "error", err)
+		} else {
+			failures = 0
+			if first {
+				first = false
+				atomic.AddInt32(&r.synced, 1)
+			}
+		}
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(BackoffDelay(r.interval, r.backoffMax, failures)):
+		}
+	}
+}
+
+// fetch 拉取文档；文档未变化（304）时不发送事件
+func (r *HttpDiscoveryService) fetch(url string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if nil != err {
+		return err
+	}
+	req = req.WithContext(r.ctx)
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+	r.mutex.Lock()
+	etag := r.etags[url]
+	r.mutex.Unlock()
+	if "" != etag {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := r.client.Do(req)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil
+	case http.StatusOK:
+		// Next
+	default:
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return err
+	}
+	resources, err := DecodeResources(body)
+	if nil != err {
+		return fmt.Errorf("decode resources, error: %w", err)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.etags[url] = resp.Header.Get("ETag")
+	r.publisher.Update(url, resources)
+	return nil
+}
+
+// BackoffDelay 计算下次拉取的等待时间：失败时按指数退避，最大不超过max
+func BackoffDelay(interval, max time.Duration, failures int) time.Duration {
+	if failures <= 0 {
+		return interval
+	}
+	delay := interval
+	for i := 0; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,156 @@
+package discovery
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 模拟元数据服务：根据文档版本返回ETag，版本未变化时返回304
+type resourcesStub struct {
+	version     int
+	document    string
+	notModified int
+	mutex       sync.Mutex
+}
+
+func (s *resourcesStub) set(document string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.version++
+	s.document = document
+}
+
+func (s *resourcesStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if r.Header.Get("X-Token") != "t1" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	etag := `"v` + strconv.Itoa(s.version) + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	_, _ = w.Write([]byte(s.document))
+}
+
+func TestHttpDiscoveryService_Poll(t *testing.T) {
+	assert := assert2.New(t)
+	stub := &resourcesStub{document: `
+endpoints:
+  - httpMethod: GET
+    httpPattern: /a
+    service: { interface: a, method: get, rpcProto: ECHO }
+services:
+  - serviceId: s1
+    interface: s1
+    method: get
+    rpcProto: ECHO
+`}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	discovery := NewHttpServiceWith(HttpId)
+	defer discovery.Shutdown(nil)
+	assert.NoError(discovery.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		httpConfigUrls:     []string{server.URL},
+		httpConfigInterval: time.Millisecond * 10,
+		httpConfigHeaders:  map[string]string{"X-Token": "t1"},
+	})))
+	endpoints := make(chan flux.HttpEndpointEvent, 16)
+	services := make(chan flux.BackendServiceEvent, 16)
+	assert.NoError(discovery.WatchEndpoints(endpoints))
+	assert.NoError(discovery.WatchServices(services))
+	evt := receiveEndpointEvent(t, endpoints)
+	assert.Equal(flux.EventType(flux.EventTypeAdded), evt.EventType)
+	assert.Equal("/a", evt.Endpoint.HttpPattern)
+	srv := receiveServiceEvent(t, services)
+	assert.Equal("s1", srv.Service.ServiceId)
+	assert.Eventually(discovery.Ready, time.Second, time.Millisecond*10)
+	assert.Eventually(func() bool {
+		stub.mutex.Lock()
+		defer stub.mutex.Unlock()
+		return stub.notModified > 0
+	}, time.Second, time.Millisecond*10)
+	// 文档变更
+	stub.set(`{"endpoints":[{"httpMethod":"GET","httpPattern":"/b","service":{"interface":"b","method":"get","rpcProto":"ECHO"}}]}`)
+	evt = receiveEndpointEvent(t, endpoints)
+	assert.Equal(flux.EventType(flux.EventTypeRemoved), evt.EventType)
+	assert.Equal("/a", evt.Endpoint.HttpPattern)
+	evt = receiveEndpointEvent(t, endpoints)
+	assert.Equal(flux.EventType(flux.EventTypeAdded), evt.EventType)
+	assert.Equal("/b", evt.Endpoint.HttpPattern)
+	srv = receiveServiceEvent(t, services)
+	assert.Equal(flux.EventType(flux.EventTypeRemoved), srv.EventType)
+	select {
+	case evt := <-endpoints:
+		t.Fatalf("unexpected event: %+v", evt)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestHttpDiscoveryService_MoveEndpoint(t *testing.T) {
+	assert := assert2.New(t)
+	const endpointA = `{"httpMethod":"GET","httpPattern":"/a","service":{"interface":"a","method":"get","rpcProto":"ECHO"}}`
+	first := &resourcesStub{document: `{"endpoints":[` + endpointA + `]}`}
+	second := &resourcesStub{document: `{"endpoints":[]}`}
+	server1, server2 := httptest.NewServer(first), httptest.NewServer(second)
+	defer server1.Close()
+	defer server2.Close()
+	discovery := NewHttpServiceWith(HttpId)
+	defer discovery.Shutdown(nil)
+	assert.NoError(discovery.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		httpConfigUrls:     []string{server1.URL, server2.URL},
+		httpConfigInterval: time.Millisecond * 10,
+		httpConfigHeaders:  map[string]string{"X-Token": "t1"},
+	})))
+	endpoints := make(chan flux.HttpEndpointEvent, 16)
+	assert.NoError(discovery.WatchEndpoints(endpoints))
+	assert.Equal("/a", receiveEndpointEvent(t, endpoints).Endpoint.HttpPattern)
+	assert.Eventually(discovery.Ready, time.Second, time.Millisecond*10)
+	// Endpoint从第一个URL移动到第二个URL，并修改定义：只产生更新事件
+	second.set(`{"endpoints":[` + strings.Replace(endpointA, `"get"`, `"query"`, 1) + `]}`)
+	second.mutex.Lock()
+	second.notModified = 0
+	second.mutex.Unlock()
+	// 确认已拉取第二个URL的新文档，再从第一个URL中删除
+	assert.Eventually(func() bool {
+		second.mutex.Lock()
+		defer second.mutex.Unlock()
+		return second.notModified > 0
+	}, time.Second, time.Millisecond*10)
+	first.set(`{"endpoints":[]}`)
+	evt := receiveEndpointEvent(t, endpoints)
+	assert.Equal(flux.EventType(flux.EventTypeUpdated), evt.EventType)
+	assert.Equal("query", evt.Endpoint.Service.Method)
+	select {
+	case evt := <-endpoints:
+		t.Fatalf("unexpected event: %+v", evt)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	cases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 0, expected: time.Second},
+		{failures: 1, expected: time.Second * 2},
+		{failures: 3, expected: time.Second * 8},
+		{failures: 10, expected: time.Second * 30},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		assert.Equal(tcase.expected, BackoffDelay(time.Second, time.Second*30, tcase.failures))
+	}
+}
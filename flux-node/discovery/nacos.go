@@ -0,0 +1,255 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/bytepowered/flux/flux-node/remoting/nacos"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	NacosId = "nacos"
+)
+
+const (
+	nacosConfigAddress         = "address"
+	nacosConfigScheme          = "scheme"
+	nacosConfigContextPath     = "context_path"
+	nacosConfigNamespace       = "namespace"
+	nacosConfigGroup           = "group"
+	nacosConfigUsername        = "username"
+	nacosConfigPassword        = "password"
+	nacosConfigDataIds         = "data_ids"
+	nacosConfigLongPollTimeout = "long_poll_timeout"
+	nacosConfigRetryDelay      = "retry_delay"
+)
+
+var (
+	_ flux.EndpointDiscovery = new(NacosDiscoveryService)
+	_ flux.Readiness         = new(NacosDiscoveryService)
+)
+
+type (
+	// NacosOption 配置函数
+	NacosOption func(discovery *NacosDiscoveryService)
+)
+
+// NacosDiscoveryService 基于Nacos配置中心实现的Endpoint元数据注册中心；
+// 每个应用对应一个DataId，配置内容为包含endpoints和services列表的JSON/YAML文档；
+type NacosDiscoveryService struct {
+	id              string
+	client          *nacos.Client
+	disabled        bool
+	group           string
+	dataIds         []string
+	longPollTimeout time.Duration
+	retryDelay      time.Duration
+	// 各DataId的配置内容MD5和解析结果
+	md5s      map[string]string
+	publisher *ResourcesPublisher
+	mutex     sync.Mutex
+	started   sync.Once
+	synced    int32
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// WithNacosClient 指定Nacos客户端
+func WithNacosClient(client *nacos.Client) NacosOption {
+	return func(discovery *NacosDiscoveryService) {
+		discovery.client = client
+	}
+}
+
+// NewNacosServiceWith returns new a nacos discovery service
+func NewNacosServiceWith(id string, opts ...NacosOption) *NacosDiscoveryService {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &NacosDiscoveryService{
+		id:        id,
+		md5s:      make(map[string]string, 8),
+		publisher: NewResourcesPublisher("NACOS", ctx.Done()),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *NacosDiscoveryService) Id() string {
+	return r.id
+}
+
+// Init 初始化；未配置Nacos地址或者DataId列表时，不启用此Discovery；
+func (r *NacosDiscoveryService) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		nacosConfigScheme:          "http",
+		nacosConfigContextPath:     nacos.DefaultContextPath,
+		nacosConfigGroup:           nacos.DefaultGroup,
+		nacosConfigLongPollTimeout: time.Second * 30,
+		nacosConfigRetryDelay:      time.Second * 3,
+	})
+	address := config.GetString(nacosConfigAddress)
+	r.dataIds = config.GetStringSlice(nacosConfigDataIds)
+	if ("" == address && nil == r.client) || len(r.dataIds) == 0 {
+		logger.Infow("NacosEndpointDiscovery disabled, address or data-ids not configured")
+		r.disabled = true
+		return nil
+	}
+	if nil == r.client {
+		r.client = nacos.NewClient(nacos.ClientConfig{
+			Address:     address,
+			Scheme:      config.GetString(nacosConfigScheme),
+			ContextPath: config.GetString(nacosConfigContextPath),
+			Namespace:   config.GetString(nacosConfigNamespace),
+			Username:    config.GetString(nacosConfigUsername),
+			Password:    config.GetString(nacosConfigPassword),
+		})
+	}
+	r.group = config.GetString(nacosConfigGroup)
+	r.longPollTimeout = config.GetDuration(nacosConfigLongPollTimeout)
+	r.retryDelay = config.GetDuration(nacosConfigRetryDelay)
+	logger.Infow("NacosEndpointDiscovery init", "address", address, "group", r.group, "data-ids", r.dataIds)
+	return nil
+}
+
+// WatchEndpoints 监听各DataId配置中的Endpoint变化
+func (r *NacosDiscoveryService) WatchEndpoints(events chan<- flux.HttpEndpointEvent) error {
+	if r.disabled {
+		return nil
+	}
+	r.mutex.Lock()
+	r.publisher.SetEndpointEvents(events)
+	r.mutex.Unlock()
+	r.started.Do(func() {
+		go r.loop()
+	})
+	return nil
+}
+
+// WatchServices 监听各DataId配置中的Service变化
+func (r *NacosDiscoveryService) WatchServices(events chan<- flux.BackendServiceEvent) error {
+	if r.disabled {
+		return nil
+	}
+	r.mutex.Lock()
+	r.publisher.SetServiceEvents(events)
+	r.mutex.Unlock()
+	r.started.Do(func() {
+		go r.loop()
+	})
+	return nil
+}
+
+// Ready 当全部DataId完成首次读取后，返回就绪状态
+func (r *NacosDiscoveryService) Ready() bool {
+	return r.disabled || atomic.LoadInt32(&r.synced) == 1
+}
+
+// Shutdown 停止长轮询
+func (r *NacosDiscoveryService) Shutdown(ctx context.Context) error {
+	logger.Info("NacosEndpointDiscovery shutdown")
+	r.cancel()
+	return nil
+}
+
+func (r *NacosDiscoveryService) loop() {
+	logger.Infow("DISCOVERY:NACOS:WATCH", "group", r.group, "data-ids", r.dataIds)
+	// 首次全量读取
+	for {
+		if err := r.fetch(r.dataIds); nil == err {
+			atomic.StoreInt32(&r.synced, 1)
+			break
+		} else if nil != r.ctx.Err() {
+			return
+		} else {
+			logger.Warnw("DISCOVERY:NACOS:FETCH", "error", err)
+		}
+		if !r.sleep(r.retryDelay) {
+			return
+		}
+	}
+	// 长轮询监听变化
+	for {
+		changed, err := r.client.ListenConfigs(r.ctx, r.listenItems(), r.longPollTimeout)
+		if nil == err && len(changed) > 0 {
+			logger.Infow("DISCOVERY:NACOS:CHANGED", "data-ids", changed)
+			err = r.fetch(changed)
+		}
+		if nil != err {
+			if nil != r.ctx.Err() {
+				return
+			}
+			logger.Warnw("DISCOVERY:NACOS:LISTEN", "error", err)
+			if !r.sleep(r.retryDelay) {
+				return
+			}
+		}
+	}
+}
+
+// fetch 读取指定DataId的配置内容，并发送与上次内容对比后的变更事件
+func (r *NacosDiscoveryService) fetch(dataIds []string) error {
+	for _, dataId := range dataIds {
+		content, found, err := r.client.GetConfig(r.ctx, dataId, r.group)
+		if nil != err {
+			return err
+		}
+		resources, err := ParseNacosResources(content)
+		if nil != err {
+			// 配置内容格式错误时，保留上次的有效数据
+			logger.Warnw("DISCOVERY:NACOS:ILLEGAL_CONTENT", "data-id", dataId, "error", err)
+			r.mutex.Lock()
+			r.md5s[dataId] = nacos.ContentMD5(content)
+			r.mutex.Unlock()
+			continue
+		}
+		r.mutex.Lock()
+		if found {
+			r.md5s[dataId] = nacos.ContentMD5(content)
+			r.publisher.Update(dataId, resources)
+		} else {
+			delete(r.md5s, dataId)
+			r.publisher.Remove(dataId)
+		}
+		r.mutex.Unlock()
+	}
+	return nil
+}
+
+func (r *NacosDiscoveryService) listenItems() []nacos.ListenItem {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	items := make([]nacos.ListenItem, len(r.dataIds))
+	for i, dataId := range r.dataIds {
+		items[i] = nacos.ListenItem{DataId: dataId, Group: r.group, MD5: r.md5s[dataId]}
+	}
+	return items
+}
+
+func (r *NacosDiscoveryService) sleep(d time.Duration) bool {
+	select {
+	case <-r.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// ParseNacosResources 解析配置内容；支持JSON和YAML格式，内容为空时返回空资源；
+func ParseNacosResources(content string) (Resources, error) {
+	if "" == strings.TrimSpace(content) {
+		return Resources{}, nil
+	}
+	out, err := DecodeResources([]byte(content))
+	if nil != err {
+		return out, fmt.Errorf("decode nacos content, error: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,214 @@
+package discovery
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/remoting/nacos"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 模拟Nacos配置中心Open API
+type nacosStub struct {
+	configs map[string]string
+	tokens  int
+	mutex   sync.Mutex
+}
+
+func (s *nacosStub) set(dataId, content string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if "" == content {
+		delete(s.configs, dataId)
+	} else {
+		s.configs[dataId] = content
+	}
+}
+
+func (s *nacosStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	if r.URL.Path == "/nacos/v1/auth/login" {
+		if r.PostForm.Get("username") != "nacos" || r.PostForm.Get("password") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		s.mutex.Lock()
+		s.tokens++
+		s.mutex.Unlock()
+		_, _ = w.Write([]byte(`{"accessToken":"token-1","tokenTtl":18000}`))
+		return
+	}
+	if r.URL.Query().Get("accessToken") != "token-1" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.URL.Path {
+	case "/nacos/v1/cs/configs":
+		if r.URL.Query().Get("tenant") != "ns-1" || r.URL.Query().Get("group") != "FLUX" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.mutex.Lock()
+		content, ok := s.configs[r.URL.Query().Get("dataId")]
+		s.mutex.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	case "/nacos/v1/cs/configs/listener":
+		changed := make([]string, 0)
+		for _, line := range strings.Split(r.PostForm.Get("Listening-Configs"), "\x01") {
+			words := strings.Split(line, "\x02")
+			if len(words) < 3 {
+				continue
+			}
+			s.mutex.Lock()
+			content := s.configs[words[0]]
+			s.mutex.Unlock()
+			if nacos.ContentMD5(content) != words[2] {
+				changed = append(changed, words[0]+"\x02"+words[1]+"\x02ns-1\x01")
+			}
+		}
+		if len(changed) == 0 {
+			time.Sleep(time.Millisecond * 20)
+		}
+		_, _ = w.Write([]byte(url.QueryEscape(strings.Join(changed, ""))))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestNacosDiscoveryService_Watch(t *testing.T) {
+	assert := assert2.New(t)
+	stub := &nacosStub{configs: map[string]string{
+		"app-a": `
+endpoints:
+  - httpMethod: GET
+    httpPattern: /a
+    service: { interface: a, method: get, rpcProto: ECHO }
+  - httpMethod: GET
+    httpPattern: /b
+    service: { interface: b, method: get, rpcProto: ECHO }
+services:
+  - serviceId: s1
+    interface: s1
+    method: get
+    rpcProto: ECHO
+`,
+		"app-b": `{"endpoints":[{"httpMethod":"POST","httpPattern":"/c","service":{"interface":"c","method":"post","rpcProto":"ECHO"}}]}`,
+	}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	discovery := NewNacosServiceWith(NacosId)
+	defer discovery.Shutdown(nil)
+	assert.NoError(discovery.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		nacosConfigAddress:         strings.TrimPrefix(server.URL, "http://"),
+		nacosConfigNamespace:       "ns-1",
+		nacosConfigGroup:           "FLUX",
+		nacosConfigUsername:        "nacos",
+		nacosConfigPassword:        "secret",
+		nacosConfigDataIds:         []string{"app-a", "app-b"},
+		nacosConfigLongPollTimeout: time.Second,
+		nacosConfigRetryDelay:      time.Millisecond * 10,
+	})))
+	endpoints := make(chan flux.HttpEndpointEvent, 16)
+	services := make(chan flux.BackendServiceEvent, 16)
+	assert.NoError(discovery.WatchEndpoints(endpoints))
+	assert.NoError(discovery.WatchServices(services))
+	patterns := make([]string, 0)
+	for i := 0; i < 3; i++ {
+		evt := receiveEndpointEvent(t, endpoints)
+		assert.Equal(flux.EventType(flux.EventTypeAdded), evt.EventType)
+		patterns = append(patterns, evt.Endpoint.HttpPattern)
+	}
+	assert.ElementsMatch([]string{"/a", "/b", "/c"}, patterns)
+	srv := receiveServiceEvent(t, services)
+	assert.Equal("s1", srv.Service.ServiceId)
+	assert.Eventually(discovery.Ready, time.Second, time.Millisecond*10)
+	// 推送变更
+	stub.set("app-a", `{"endpoints":[{"httpMethod":"GET","httpPattern":"/a","service":{"interface":"a","method":"put","rpcProto":"ECHO"}}]}`)
+	evt := receiveEndpointEvent(t, endpoints)
+	assert.Equal(flux.EventType(flux.EventTypeRemoved), evt.EventType)
+	assert.Equal("/b", evt.Endpoint.HttpPattern)
+	evt = receiveEndpointEvent(t, endpoints)
+	assert.Equal(flux.EventType(flux.EventTypeUpdated), evt.EventType)
+	assert.Equal("put", evt.Endpoint.Service.Method)
+	srv = receiveServiceEvent(t, services)
+	assert.Equal(flux.EventType(flux.EventTypeRemoved), srv.EventType)
+	// 删除配置
+	stub.set("app-b", "")
+	evt = receiveEndpointEvent(t, endpoints)
+	assert.Equal(flux.EventType(flux.EventTypeRemoved), evt.EventType)
+	assert.Equal("/c", evt.Endpoint.HttpPattern)
+	stub.mutex.Lock()
+	assert.Equal(1, stub.tokens)
+	stub.mutex.Unlock()
+}
+
+func TestNacosDiscoveryService_MoveEndpoint(t *testing.T) {
+	assert := assert2.New(t)
+	const (
+		endpointA = `{"httpMethod":"GET","httpPattern":"/a","service":{"interface":"a","method":"get","rpcProto":"ECHO"}}`
+		endpointC = `{"httpMethod":"POST","httpPattern":"/c","service":{"interface":"c","method":"post","rpcProto":"ECHO"}}`
+	)
+	stub := &nacosStub{configs: map[string]string{
+		"app-a": `{"endpoints":[` + endpointA + `]}`,
+		"app-b": `{"endpoints":[` + endpointC + `]}`,
+	}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	discovery := NewNacosServiceWith(NacosId)
+	defer discovery.Shutdown(nil)
+	assert.NoError(discovery.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		nacosConfigAddress:         strings.TrimPrefix(server.URL, "http://"),
+		nacosConfigNamespace:       "ns-1",
+		nacosConfigGroup:           "FLUX",
+		nacosConfigUsername:        "nacos",
+		nacosConfigPassword:        "secret",
+		nacosConfigDataIds:         []string{"app-a", "app-b"},
+		nacosConfigLongPollTimeout: time.Second,
+		nacosConfigRetryDelay:      time.Millisecond * 10,
+	})))
+	endpoints := make(chan flux.HttpEndpointEvent, 16)
+	assert.NoError(discovery.WatchEndpoints(endpoints))
+	for i := 0; i < 2; i++ {
+		assert.Equal(flux.EventType(flux.EventTypeAdded), receiveEndpointEvent(t, endpoints).EventType)
+	}
+	assert.Eventually(discovery.Ready, time.Second, time.Millisecond*10)
+	// Endpoint从 app-a 移动到 app-b：先在 app-b 中定义，再从 app-a 中删除
+	stub.set("app-b", `{"endpoints":[`+endpointA+`,`+endpointC+`]}`)
+	time.Sleep(time.Millisecond * 100)
+	stub.set("app-a", "")
+	time.Sleep(time.Millisecond * 100)
+	// 修改 /c 作为事件标记：在此之前不能有删除 /a 的事件
+	stub.set("app-b", `{"endpoints":[`+endpointA+`,`+strings.Replace(endpointC, `"post"`, `"put"`, 1)+`]}`)
+	evt := receiveEndpointEvent(t, endpoints)
+	assert.Equal(flux.EventType(flux.EventTypeUpdated), evt.EventType)
+	assert.Equal("/c", evt.Endpoint.HttpPattern)
+	select {
+	case evt := <-endpoints:
+		assert.Fail("unexpected endpoint event", "%+v", evt)
+	default:
+	}
+}
+
+func TestParseChangedDataIds(t *testing.T) {
+	cases := []struct {
+		body     string
+		expected []string
+	}{
+		{body: "", expected: nil},
+		{body: url.QueryEscape("app-a\x02FLUX\x02ns\x01"), expected: []string{"app-a"}},
+		{body: url.QueryEscape("app-a\x02FLUX\x01app-b\x02FLUX\x01"), expected: []string{"app-a", "app-b"}},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		assert.Equal(tcase.expected, nacos.ParseChangedDataIds(tcase.body))
+	}
+}
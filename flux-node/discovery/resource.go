@@ -1,19 +1,24 @@
 package discovery
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-node/ext"
 	"github.com/bytepowered/flux/flux-node/logger"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"sync/atomic"
 )
 
 const (
 	ResourceId = "resource"
 )
 
-var _ flux.EndpointDiscovery = new(ResourceDiscoveryService)
+var (
+	_ flux.EndpointDiscovery = new(ResourceDiscoveryService)
+	_ flux.Readiness         = new(ResourceDiscoveryService)
+)
 
 type (
 	// ZookeeperOption 配置函数
@@ -40,6 +45,7 @@ func NewResourceServiceWith(id string, opts ...ResourceOption) *ResourceDiscover
 type ResourceDiscoveryService struct {
 	id        string
 	resources []Resources
+	watched   int32
 }
 
 func (r *ResourceDiscoveryService) Id() string {
@@ -74,27 +80,40 @@ func (r *ResourceDiscoveryService) Init(config *flux.Configuration) error {
 func (r *ResourceDiscoveryService) WatchEndpoints(events chan<- flux.HttpEndpointEvent) error {
 	for _, res := range r.resources {
 		for _, ep := range res.Endpoints {
-			if ep.IsValid() {
-				EnsureServiceAttrs(&ep.Service)
+			if !ep.IsValid() {
+				continue
+			}
+			EnsureServiceAttrs(&ep.Service)
+			if VerifyEndpoint(&ep, r.id) {
 				events <- flux.HttpEndpointEvent{EventType: flux.EventTypeAdded, Endpoint: ep}
 			}
 		}
 	}
+	atomic.AddInt32(&r.watched, 1)
 	return nil
 }
 
 func (r *ResourceDiscoveryService) WatchServices(events chan<- flux.BackendServiceEvent) error {
 	for _, res := range r.resources {
 		for _, srv := range res.Services {
-			if srv.IsValid() {
-				EnsureServiceAttrs(&srv)
+			if !srv.IsValid() {
+				continue
+			}
+			EnsureServiceAttrs(&srv)
+			if VerifyService(&srv, r.id) {
 				events <- flux.BackendServiceEvent{EventType: flux.EventTypeAdded, Service: srv}
 			}
 		}
 	}
+	atomic.AddInt32(&r.watched, 1)
 	return nil
 }
 
+// Ready 本地静态资源在Endpoint和Service全部发送后即为就绪状态
+func (r *ResourceDiscoveryService) Ready() bool {
+	return atomic.LoadInt32(&r.watched) >= 2
+}
+
 func (r *ResourceDiscoveryService) includes(files []string) error {
 	for _, file := range files {
 		bytes, err := ioutil.ReadFile(file)
@@ -110,3 +129,37 @@ func (r *ResourceDiscoveryService) includes(files []string) error {
 	}
 	return nil
 }
+
+// DecodeResources 解析JSON/YAML格式的资源文档；YAML解析的Map统一转换为JSON兼容的结构，
+// 保证扩展信息等字段可以被JSON序列化；
+func DecodeResources(data []byte) (Resources, error) {
+	var out Resources
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); nil != err {
+		return out, err
+	}
+	bytes, err := json.Marshal(toJSONCompatible(doc))
+	if nil != err {
+		return out, err
+	}
+	err = json.Unmarshal(bytes, &out)
+	return out, err
+}
+
+func toJSONCompatible(v interface{}) interface{} {
+	switch items := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(items))
+		for k, v := range items {
+			out[fmt.Sprintf("%v", k)] = toJSONCompatible(v)
+		}
+		return out
+	case []interface{}:
+		for i, v := range items {
+			items[i] = toJSONCompatible(v)
+		}
+		return items
+	default:
+		return v
+	}
+}
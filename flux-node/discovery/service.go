@@ -40,6 +40,10 @@ func NewBackendServiceEvent(bytes []byte, etype remoting.EventType, node string)
 		return invalidBackendServiceEvent, false
 	}
 	EnsureServiceAttrs(&service)
+	// 删除事件不需要校验定义内容
+	if etype != remoting.EventTypeNodeDelete && !VerifyService(&service, node) {
+		return invalidBackendServiceEvent, false
+	}
 
 	event := flux.BackendServiceEvent{Service: service}
 	switch etype {
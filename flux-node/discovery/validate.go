@@ -0,0 +1,47 @@
+package discovery
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/bytepowered/flux/flux-node/validate"
+)
+
+// VerifyEndpoint 按校验规则检查Endpoint定义；存在错误时返回false，警告只记录日志；
+func VerifyEndpoint(endpoint *flux.Endpoint, source string) bool {
+	issues := NewGatewayValidator().ValidateEndpoint(endpoint)
+	return reportIssues("DISCOVERY:ENDPOINT", issues, "source", source,
+		"method", endpoint.HttpMethod, "pattern", endpoint.HttpPattern, "version", endpoint.Version)
+}
+
+// VerifyService 按校验规则检查Service定义；存在错误时返回false，警告只记录日志；
+func VerifyService(service *flux.BackendService, source string) bool {
+	issues := NewGatewayValidator().ValidateService(service)
+	return reportIssues("DISCOVERY:SERVICE", issues, "source", source,
+		"service-id", service.ServiceId, "interface", service.Interface, "method", service.Method)
+}
+
+func reportIssues(tag string, issues validate.Issues, keysAndValues ...interface{}) bool {
+	for _, issue := range issues {
+		if issue.Level != validate.LevelError {
+			logger.Warnw(tag+":LINT_WARNING", append(keysAndValues, "issue", issue.String())...)
+		}
+	}
+	if err := issues.Errors(); nil != err {
+		logger.Warnw(tag+":INVALID_DEFINITION", append(keysAndValues, "error", err)...)
+		return false
+	}
+	return true
+}
+
+// NewGatewayValidator 创建网关运行时使用的校验器：已注册的后端协议也作为有效的rpcproto，权限服务在已注册的Service中查找；
+func NewGatewayValidator() *validate.Validator {
+	protos := make([]string, 0, 4)
+	for proto := range ext.BackendTransports() {
+		protos = append(protos, proto)
+	}
+	return validate.NewValidatorWith(
+		validate.WithProtocols(protos...),
+		validate.WithServiceLookup(ext.HasBackendService),
+	)
+}
@@ -8,12 +8,15 @@ import (
 	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/bytepowered/flux/flux-node/remoting"
 	"github.com/bytepowered/flux/flux-node/remoting/zk"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	// 在ZK注册的根节点。需要与客户端的注册保持一致。
-	zkDiscoveryHttpEndpointPath   = "/flux-endpoint"
-	zkDiscoveryBackendServicePath = "/flux-service"
+	ZkEndpointRootPath = "/flux-endpoint"
+	ZkServiceRootPath  = "/flux-service"
 )
 
 const (
@@ -26,7 +29,14 @@ const (
 	zkConfigRegistrySelector = "registry_selector"
 )
 
-var _ flux.EndpointDiscovery = new(ZookeeperDiscoveryService)
+const (
+	zkSyncRetryInterval = time.Second * 3
+)
+
+var (
+	_ flux.EndpointDiscovery = new(ZookeeperDiscoveryService)
+	_ flux.Readiness         = new(ZookeeperDiscoveryService)
+)
 
 type (
 	// ZookeeperOption 配置函数
@@ -40,6 +50,14 @@ type ZookeeperDiscoveryService struct {
 	endpointPath string
 	servicePath  string
 	retrievers   []*zk.ZookeeperRetriever
+	// 初始快照中尚未接收到数据的节点
+	pending   map[string]struct{}
+	received  map[string]struct{}
+	pendingMu sync.Mutex
+	watched   int32
+	// 尚未成功读取初始子节点列表的根节点数量；注册中心不可用时，后台重试读取
+	syncing int32
+	quit    chan struct{}
 }
 
 // WithGlobalAlias 配置注册中心的配置别名
@@ -52,7 +70,10 @@ func WithGlobalAlias(alias map[string]string) ZookeeperOption {
 // NewZookeeperServiceWith returns new a zookeeper discovery factory
 func NewZookeeperServiceWith(id string, opts ...ZookeeperOption) *ZookeeperDiscoveryService {
 	r := &ZookeeperDiscoveryService{
-		id: id,
+		id:       id,
+		pending:  make(map[string]struct{}, 16),
+		received: make(map[string]struct{}, 16),
+		quit:     make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(r)
@@ -67,8 +88,8 @@ func (r *ZookeeperDiscoveryService) Id() string {
 // Init init discovery
 func (r *ZookeeperDiscoveryService) Init(config *flux.Configuration) error {
 	config.SetDefaults(map[string]interface{}{
-		zkConfigRootpathEndpoint: zkDiscoveryHttpEndpointPath,
-		zkConfigRootpathService:  zkDiscoveryBackendServicePath,
+		zkConfigRootpathEndpoint: ZkEndpointRootPath,
+		zkConfigRootpathService:  ZkServiceRootPath,
 	})
 	selected := config.GetStringSlice(zkConfigRegistrySelector)
 	if len(selected) == 0 {
@@ -121,6 +142,7 @@ func (r *ZookeeperDiscoveryService) WatchEndpoints(events chan<- flux.HttpEndpoi
 			return err
 		}
 	}
+	atomic.AddInt32(&r.watched, 1)
 	return nil
 }
 
@@ -143,26 +165,101 @@ func (r *ZookeeperDiscoveryService) WatchServices(events chan<- flux.BackendServ
 			return err
 		}
 	}
+	atomic.AddInt32(&r.watched, 1)
 	return nil
 }
 
+// Ready 当注册中心已连接，并且Endpoint和Service的初始节点数据全部接收后，返回就绪状态
+func (r *ZookeeperDiscoveryService) Ready() bool {
+	if atomic.LoadInt32(&r.watched) < 2 || atomic.LoadInt32(&r.syncing) > 0 {
+		return false
+	}
+	for _, retriever := range r.retrievers {
+		if !retriever.Connected() {
+			return false
+		}
+	}
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	return len(r.pending) == 0
+}
+
 func (r *ZookeeperDiscoveryService) watch(retriever *zk.ZookeeperRetriever, rootpath string, nodeListener func(remoting.NodeEvent)) error {
-	if exist, _ := retriever.Exists(rootpath); !exist {
+	// 注册中心不可用时，不阻止服务启动：由ZK客户端在后台重试监听，连接恢复后同步数据；
+	if exist, err := retriever.Exists(rootpath); nil != err {
+		logger.Warnw("DISCOVERY:ZOOKEEPER:META:UNAVAILABLE", "path", rootpath, "error", err)
+	} else if !exist {
 		if err := retriever.Create(rootpath); nil != err {
 			return fmt.Errorf("init metadata node: %w", err)
 		}
 	}
 	logger.Infow("DISCOVERY:ZOOKEEPER:META:WATCH", "path", rootpath)
+	// 记录初始快照的节点列表，用于判断初始数据是否已全部接收
+	if children, err := retriever.Children(rootpath); nil == err {
+		r.setPending(children...)
+	} else {
+		logger.Warnw("DISCOVERY:ZOOKEEPER:META:CHILDREN", "path", rootpath, "error", err)
+		atomic.AddInt32(&r.syncing, 1)
+		go r.syncPending(retriever, rootpath)
+	}
+	dataListener := func(event remoting.NodeEvent) {
+		r.donePending(event.Path)
+		nodeListener(event)
+	}
 	return retriever.AddChildrenNodeChangedListener("", rootpath, func(event remoting.NodeEvent) {
 		logger.Infow("DISCOVERY:ZOOKEEPER:META:RECEIVED", "event", event)
-		if event.EventType == remoting.EventTypeChildAdd {
-			if err := retriever.AddNodeChangedListener("", event.Path, nodeListener); nil != err {
+		switch event.EventType {
+		case remoting.EventTypeChildAdd:
+			if err := retriever.AddNodeChangedListener("", event.Path, dataListener); nil != err {
+				r.donePending(event.Path)
 				logger.Warnw("Watch child node data", "error", err)
 			}
+		case remoting.EventTypeChildDelete:
+			r.donePending(event.Path)
 		}
 	})
 }
 
+// syncPending 重试读取初始子节点列表，直到成功或者服务停止
+func (r *ZookeeperDiscoveryService) syncPending(retriever *zk.ZookeeperRetriever, rootpath string) {
+	ticker := time.NewTicker(zkSyncRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.quit:
+			return
+		case <-ticker.C:
+			children, err := retriever.Children(rootpath)
+			if nil != err {
+				logger.Debugw("DISCOVERY:ZOOKEEPER:META:CHILDREN", "path", rootpath, "error", err)
+				continue
+			}
+			logger.Infow("DISCOVERY:ZOOKEEPER:META:SYNCED", "path", rootpath, "children", len(children))
+			r.setPending(children...)
+			atomic.AddInt32(&r.syncing, -1)
+			return
+		}
+	}
+}
+
+func (r *ZookeeperDiscoveryService) setPending(paths ...string) {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	for _, p := range paths {
+		// 忽略已接收到数据的节点
+		if _, ok := r.received[p]; !ok {
+			r.pending[p] = struct{}{}
+		}
+	}
+}
+
+func (r *ZookeeperDiscoveryService) donePending(path string) {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+	delete(r.pending, path)
+	r.received[path] = struct{}{}
+}
+
 // Startup startup discovery service
 func (r *ZookeeperDiscoveryService) Startup() error {
 	logger.Info("ZkEndpointDiscovery startup")
@@ -177,6 +274,11 @@ func (r *ZookeeperDiscoveryService) Startup() error {
 // Shutdown shutdown discovery service
 func (r *ZookeeperDiscoveryService) Shutdown(ctx context.Context) error {
 	logger.Info("ZkEndpointDiscovery shutdown")
+	select {
+	case <-r.quit:
+	default:
+		close(r.quit)
+	}
 	for _, retriever := range r.retrievers {
 		if err := retriever.Shutdown(ctx); nil != err {
 			return err
@@ -0,0 +1,43 @@
+package discovery
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/remoting/zk"
+	zkgo "github.com/dubbogo/go-zookeeper/zk"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestZookeeperNodeDelete(t *testing.T) {
+	assert := assert2.New(t)
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	const path = "/flux-endpoint/a"
+	node := new(zk.NodeData)
+	_, ok := node.Delete(path)
+	assert.False(ok, "node never read")
+	// 节点删除后，删除事件携带最后读取的数据
+	node.Update(path, &zkgo.Stat{Czxid: 1, Version: 0},
+		[]byte(`{"httpMethod":"GET","httpPattern":"/a","service":{"interface":"a","method":"get","rpcProto":"ECHO"}}`))
+	node.Update(path, &zkgo.Stat{Czxid: 1, Version: 1},
+		[]byte(`{"httpMethod":"GET","httpPattern":"/a","service":{"interface":"a","method":"post","rpcProto":"ECHO"}}`))
+	deleted, ok := node.Delete(path)
+	if !assert.True(ok) {
+		return
+	}
+	endpoint, ok := NewEndpointEvent(deleted.Data, deleted.EventType, deleted.Path)
+	if assert.True(ok) {
+		assert.Equal(flux.EventType(flux.EventTypeRemoved), endpoint.EventType)
+		assert.Equal("/a", endpoint.Endpoint.HttpPattern)
+		assert.Equal("post", endpoint.Endpoint.Service.Method)
+	}
+	node = new(zk.NodeData)
+	node.Update("/flux-service/b", &zkgo.Stat{Czxid: 2},
+		[]byte(`{"interface":"b","method":"get","rpcProto":"ECHO"}`))
+	deleted, _ = node.Delete("/flux-service/b")
+	service, ok := NewBackendServiceEvent(deleted.Data, deleted.EventType, deleted.Path)
+	if assert.True(ok) {
+		assert.Equal(flux.EventType(flux.EventTypeRemoved), service.EventType)
+		assert.Equal("b", service.Service.Interface)
+	}
+}
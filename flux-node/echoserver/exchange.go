@@ -138,8 +138,16 @@ func (c *EchoWebExchange) Write(statusCode int, contentType string, bytes []byte
 	return c.echoc.Blob(statusCode, contentType, bytes)
 }
 
+// WriteStream 写入响应状态码，并逐块复制流数据到客户端，每次写入后刷新；客户端取消请求时停止复制；
 func (c *EchoWebExchange) WriteStream(statusCode int, contentType string, reader io.Reader) error {
-	return c.echoc.Stream(statusCode, contentType, reader)
+	resp := c.echoc.Response()
+	if "" != contentType && "" == resp.Header().Get(flux.HeaderContentType) {
+		resp.Header().Set(flux.HeaderContentType, contentType)
+	}
+	resp.WriteHeader(statusCode)
+	resp.Flush()
+	_, err := flux.CopyStream(c.echoc.Request().Context(), resp, reader)
+	return err
 }
 
 func (c *EchoWebExchange) Send(webex flux.WebExchange, header http.Header, status int, data interface{}) error {
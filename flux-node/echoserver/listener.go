@@ -7,11 +7,11 @@ import (
 	"fmt"
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/identity"
 	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/bytepowered/flux/flux-pkg"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/random"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -30,14 +30,25 @@ const (
 	ConfigKeyFeatures    = "features"
 )
 
+// 请求ID配置
+const (
+	ConfigKeyRequestId               = "request_id"
+	ConfigKeyRequestIdGenerator      = "generator"
+	ConfigKeyRequestIdHeader         = "header"
+	ConfigKeyRequestIdResponseHeader = "response_header"
+	ConfigKeyRequestIdTrusted        = "trusted_upstreams"
+)
+
 var _ flux.WebListener = new(EchoWebListener)
 
+var defaultIdGenerator = identity.NewUUIDGenerator()
+
 func init() {
 	ext.SetWebListenerFactory(NewEchoWebListener)
 }
 
 func NewEchoWebListener(listenerId string, config *flux.Configuration) flux.WebListener {
-	return NewEchoWebListenerWith(listenerId, config, DefaultIdentifier, nil)
+	return NewEchoWebListenerWith(listenerId, config, NewRequestIdentifier(config.Sub(ConfigKeyRequestId)), nil)
 }
 
 func NewEchoWebListenerWith(listenerId string, options *flux.Configuration, identifier flux.WebRequestIdentifier, mws *AdaptMiddleware) flux.WebListener {
@@ -139,7 +150,7 @@ func (s *EchoWebListener) Write(webex flux.WebExchange, header http.Header, stat
 }
 
 func (s *EchoWebListener) WriteError(webex flux.WebExchange, err *flux.ServeError) {
-	if err := s.responseWriter.Write(webex, err.Header, err.StatusCode, err); nil != err {
+	if err := s.responseWriter.WriteError(webex, err.Header, err.StatusCode, err); nil != err {
 		logger.Errorw("WebListener write error failed", "error", err, "server-id", s.id)
 	}
 }
@@ -246,15 +257,52 @@ func DefaultRequestBodyResolver(webex flux.WebExchange) url.Values {
 	return form
 }
 
+// DefaultIdentifier 默认请求ID查找函数：接受客户端请求Header中的请求ID，否则生成UUID格式的请求ID；
 func DefaultIdentifier(ctx interface{}) string {
 	echoc, ok := ctx.(echo.Context)
 	fluxpkg.Assert(ok, "<context> must be echo.context")
-	id := echoc.Request().Header.Get(flux.XRequestId)
-	if "" != id {
+	if id := echoc.Request().Header.Get(flux.XRequestId); identity.IsValidRequestId(id) {
+		return id
+	}
+	return defaultIdGenerator.NextId()
+}
+
+// NewRequestIdentifier 根据配置构建请求ID查找函数：
+// 1. 只接受来自可信上游（trusted_upstreams）的请求ID，且请求ID格式必须合法；
+// 2. 否则，使用指定的生成器（generator）生成新的请求ID；
+// 3. 如果配置了响应Header（response_header），将请求ID回写到响应中；
+func NewRequestIdentifier(config *flux.Configuration) flux.WebRequestIdentifier {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyRequestIdGenerator:      ext.TypeNameRequestIdDefault,
+		ConfigKeyRequestIdHeader:         flux.XRequestId,
+		ConfigKeyRequestIdResponseHeader: flux.XRequestId,
+		ConfigKeyRequestIdTrusted:        []string{"*"},
+	})
+	generatorName := config.GetString(ConfigKeyRequestIdGenerator)
+	generator := ext.RequestIdGeneratorByType(generatorName)
+	if nil == generator {
+		generator = defaultIdGenerator
+	}
+	header := config.GetString(ConfigKeyRequestIdHeader)
+	respHeader := config.GetString(ConfigKeyRequestIdResponseHeader)
+	trusted, invalid := identity.NewTrustedUpstreams(config.GetStringSlice(ConfigKeyRequestIdTrusted))
+	if len(invalid) > 0 {
+		logger.Warnw("WebListener request-id, ignore invalid trusted upstreams", "rules", invalid)
+	}
+	logger.Infow("WebListener request-id", "generator", generatorName, "header", header, "response-header", respHeader)
+	return func(ctx interface{}) string {
+		echoc, ok := ctx.(echo.Context)
+		fluxpkg.Assert(ok, "<context> must be echo.context")
+		request := echoc.Request()
+		id := request.Header.Get(header)
+		if !identity.IsValidRequestId(id) || !trusted.IsTrusted(request.RemoteAddr) {
+			id = generator.NextId()
+		}
+		if "" != respHeader {
+			echoc.Response().Header().Set(respHeader, id)
+		}
 		return id
 	}
-	echoc.Request().Header.Set("X-RequestId-By", "flux")
-	return "fxid_" + random.String(32)
 }
 
 // Body缓存，允许通过 GetBody 多次读取Body
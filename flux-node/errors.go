@@ -8,6 +8,7 @@ const (
 	ErrorCodeGatewayEndpoint  = "GATEWAY:ENDPOINT"
 	ErrorCodeGatewayCircuited = "GATEWAY:CIRCUITED"
 	ErrorCodeGatewayCanceled  = "GATEWAY:CANCELED"
+	ErrorCodeGatewayDraining  = "GATEWAY:DRAINING"
 	ErrorCodeRequestInvalid   = "REQUEST:INVALID"
 	ErrorCodeRequestNotFound  = "REQUEST:NOT_FOUND"
 	ErrorCodePermissionDenied = "PERMISSION:ACCESS_DENIED"
@@ -25,12 +26,22 @@ const (
 
 	ErrorMessageHttpInvokeFailed   = "BACKEND:HT:INVOKE"
 	ErrorMessageHttpAssembleFailed = "BACKEND:HT:ASSEMBLE"
+	ErrorMessageHttpHeaderPolicy   = "BACKEND:HT:HEADER_POLICY"
+
+	ErrorMessageTransformInvalid = "BACKEND:TRANSFORM:INVALID"
+
+	ErrorMessageWebSocketUpgradeRequired = "BACKEND:WS:UPGRADE_REQUIRED"
+	ErrorMessageWebSocketNotSupported    = "BACKEND:WS:NOT_SUPPORTED"
+	ErrorMessageWebSocketDialFailed      = "BACKEND:WS:DIAL"
+	ErrorMessageWebSocketUpgradeFailed   = "BACKEND:WS:UPGRADE"
 
 	ErrorMessagePermissionAccessDenied    = "PERMISSION:ACCESS_DENIED"
 	ErrorMessagePermissionServiceNotFound = "PERMISSION:SERVICE:NOT_FOUND"
 	ErrorMessagePermissionVerifyError     = "PERMISSION:VERIFY:ERROR"
 
 	ErrorMessageWebServerRequestNotFound = "SERVER:REQUEST:NOT_FOUND"
+	ErrorMessageWebServerDraining        = "SERVER:DRAINING"
+	ErrorMessageWebServerInternal        = "SERVER:INTERNAL"
 
 	ErrorMessageRequestPrepare = "REQUEST:BODY:PREPARE"
 )
@@ -41,4 +52,10 @@ var (
 		ErrorCode:  ErrorCodeRequestNotFound,
 		Message:    ErrorMessageWebServerRequestNotFound,
 	}
+
+	ErrServerDraining = &ServeError{
+		StatusCode: http.StatusServiceUnavailable,
+		ErrorCode:  ErrorCodeGatewayDraining,
+		Message:    ErrorMessageWebServerDraining,
+	}
 )
@@ -5,11 +5,17 @@ import (
 )
 
 var (
-	endpointDiscoveryMap = make(map[string]flux.EndpointDiscovery, 4)
+	endpointDiscoveryMap   = make(map[string]flux.EndpointDiscovery, 4)
+	endpointDiscoveryOrder = make([]string, 0, 4)
 )
 
+// RegisterEndpointDiscovery 注册Discovery；相同ID重复注册时替换实例，保留首次注册的顺序
 func RegisterEndpointDiscovery(discovery flux.EndpointDiscovery) {
-	endpointDiscoveryMap[discovery.Id()] = discovery
+	id := discovery.Id()
+	if _, ok := endpointDiscoveryMap[id]; !ok {
+		endpointDiscoveryOrder = append(endpointDiscoveryOrder, id)
+	}
+	endpointDiscoveryMap[id] = discovery
 }
 
 func EndpointDiscoveryById(id string) (flux.EndpointDiscovery, bool) {
@@ -17,10 +23,11 @@ func EndpointDiscoveryById(id string) (flux.EndpointDiscovery, bool) {
 	return v, ok
 }
 
+// EndpointDiscoveries 按注册顺序返回Discovery列表
 func EndpointDiscoveries() []flux.EndpointDiscovery {
-	out := make([]flux.EndpointDiscovery, 0, len(endpointDiscoveryMap))
-	for _, d := range endpointDiscoveryMap {
-		out = append(out, d)
+	out := make([]flux.EndpointDiscovery, 0, len(endpointDiscoveryOrder))
+	for _, id := range endpointDiscoveryOrder {
+		out = append(out, endpointDiscoveryMap[id])
 	}
 	return out
 }
@@ -0,0 +1,28 @@
+package ext
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type orderedDiscovery struct {
+	flux.EndpointDiscovery
+	id string
+}
+
+func (d *orderedDiscovery) Id() string {
+	return d.id
+}
+
+func TestEndpointDiscoveries_RegisterOrder(t *testing.T) {
+	assert := assert2.New(t)
+	for _, id := range []string{"zookeeper", "nacos", "consul", "http", "nacos"} {
+		RegisterEndpointDiscovery(&orderedDiscovery{id: id})
+	}
+	ids := make([]string, 0)
+	for _, d := range EndpointDiscoveries() {
+		ids = append(ids, d.Id())
+	}
+	assert.Equal([]string{"zookeeper", "nacos", "consul", "http"}, ids)
+}
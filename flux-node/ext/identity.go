@@ -0,0 +1,36 @@
+package ext
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-pkg"
+	"strings"
+)
+
+// Default name
+const (
+	TypeNameRequestIdDefault   = "default"
+	TypeNameRequestIdUUID      = "uuid"
+	TypeNameRequestIdULID      = "ulid"
+	TypeNameRequestIdSnowflake = "snowflake"
+)
+
+var (
+	typedRequestIdGenerators = make(map[string]flux.RequestIdGenerator, 4)
+)
+
+// RegisterRequestIdGenerator 注册请求ID生成器
+func RegisterRequestIdGenerator(typeName string, generator flux.RequestIdGenerator) {
+	typeName = fluxpkg.MustNotEmpty(typeName, "typeName is empty")
+	typeName = strings.ToLower(typeName)
+	typedRequestIdGenerators[typeName] = fluxpkg.MustNotNil(generator, "RequestIdGenerator is nil").(flux.RequestIdGenerator)
+}
+
+// RequestIdGeneratorByType 获取请求ID生成器；如果指定类型不存在，返回默认生成器；
+func RequestIdGeneratorByType(typeName string) flux.RequestIdGenerator {
+	typeName = strings.ToLower(typeName)
+	if g, ok := typedRequestIdGenerators[typeName]; ok {
+		return g
+	} else {
+		return typedRequestIdGenerators[TypeNameRequestIdDefault]
+	}
+}
@@ -31,3 +31,9 @@ func MTValueResolverByType(typeName string) flux.MTValueResolver {
 		return mediaTypeValueResolvers[DefaultMTValueResolverName]
 	}
 }
+
+// HasMTValueResolver 判断指定类型是否注册了值类型解析函数；不包含默认解析函数；
+func HasMTValueResolver(typeName string) bool {
+	_, ok := mediaTypeValueResolvers[strings.ToLower(typeName)]
+	return ok
+}
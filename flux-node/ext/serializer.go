@@ -4,18 +4,30 @@ import (
 	"errors"
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-pkg"
+	"strings"
 )
 
 // Default name
 const (
-	TypeNameSerializerDefault = "default"
-	TypeNameSerializerJson    = "json"
+	TypeNameSerializerDefault   = "default"
+	TypeNameSerializerJson      = "json"
+	TypeNameSerializerXml       = "xml"
+	TypeNameSerializerMsgPack   = "msgpack"
+	TypeNameSerializerProtoJson = "protojson"
 )
 
 var (
 	typedSerializers = make(map[string]flux.Serializer, 2)
+	// MediaType与Serializer类型的映射，按注册顺序保存，用于响应内容协商
+	mediaTypeSerializers = make([]MediaTypeSerializer, 0, 8)
 )
 
+// MediaTypeSerializer 定义响应MediaType对应的Serializer类型
+type MediaTypeSerializer struct {
+	MediaType string
+	TypeName  string
+}
+
 ////
 
 func RegisterSerializer(typeName string, serializer flux.Serializer) {
@@ -43,3 +55,45 @@ func JSONUnmarshal(data []byte, out interface{}) error {
 	}
 	return json.Unmarshal(data, out)
 }
+
+// RegisterMediaTypeSerializer 注册MediaType对应的Serializer类型；重复注册相同MediaType时，覆盖原有定义；
+func RegisterMediaTypeSerializer(mediaType string, typeName string) {
+	mediaType = strings.ToLower(fluxpkg.MustNotEmpty(mediaType, "mediaType is empty"))
+	typeName = fluxpkg.MustNotEmpty(typeName, "typeName is empty")
+	for i, v := range mediaTypeSerializers {
+		if v.MediaType == mediaType {
+			mediaTypeSerializers[i].TypeName = typeName
+			return
+		}
+	}
+	mediaTypeSerializers = append(mediaTypeSerializers, MediaTypeSerializer{MediaType: mediaType, TypeName: typeName})
+}
+
+// MediaTypeSerializers 返回已注册的MediaType与Serializer类型映射列表
+func MediaTypeSerializers() []MediaTypeSerializer {
+	out := make([]MediaTypeSerializer, len(mediaTypeSerializers))
+	copy(out, mediaTypeSerializers)
+	return out
+}
+
+// SerializerByMediaType 查找MediaType对应的Serializer
+func SerializerByMediaType(mediaType string) (flux.Serializer, bool) {
+	mediaType = strings.ToLower(mediaType)
+	for _, v := range mediaTypeSerializers {
+		if v.MediaType == mediaType {
+			s, ok := typedSerializers[v.TypeName]
+			return s, ok && nil != s
+		}
+	}
+	return nil, false
+}
+
+// MediaTypeOfSerializer 查找Serializer类型对应的首个MediaType
+func MediaTypeOfSerializer(typeName string) (string, bool) {
+	for _, v := range mediaTypeSerializers {
+		if v.TypeName == typeName {
+			return v.MediaType, true
+		}
+	}
+	return "", false
+}
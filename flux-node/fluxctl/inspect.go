@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 网关Admin接口支持查询的资源
+var inspectTargets = map[string]string{
+	"endpoints": "/inspect/endpoints",
+	"services":  "/inspect/services",
+	"logging":   "/inspect/logging",
+	"snapshot":  "/inspect/snapshot",
+	"conflicts": "/inspect/conflicts",
+	"live":      "/health/live",
+	"ready":     "/health/ready",
+}
+
+// Inspect 查询运行中网关的Admin接口，返回格式化的JSON响应
+func Inspect(admin, target string, query url.Values, timeout time.Duration) (int, string, error) {
+	pattern, ok := inspectTargets[target]
+	if !ok {
+		return 0, "", fmt.Errorf("unknown inspect target: %s", target)
+	}
+	u := strings.TrimSuffix(admin, "/") + pattern
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(u)
+	if nil != err {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return resp.StatusCode, "", err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); nil != err {
+		return resp.StatusCode, string(body), nil
+	}
+	return resp.StatusCode, out.String(), nil
+}
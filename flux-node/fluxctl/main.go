@@ -0,0 +1,284 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node/discovery"
+	"github.com/bytepowered/flux/flux-node/validate"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const usage = `fluxctl: manage flux gateway metadata
+
+Usage:
+  fluxctl validate [-strict] [-protocols P1,P2] FILE...
+  fluxctl publish  [registry flags] [-dry-run] FILE...
+  fluxctl delete   [registry flags] [-dry-run] FILE...
+  fluxctl diff     [registry flags] FILE...
+  fluxctl inspect  [-admin URL] [-q key=value] endpoints|services|logging|snapshot|conflicts|live|ready
+
+Registry flags:
+  -zk ADDRESS             zookeeper address, comma separated (default 127.0.0.1:2181)
+  -endpoint-path PATH     endpoint root path (default /flux-endpoint)
+  -service-path PATH      service root path (default /flux-service)
+  -timeout DURATION       connection timeout (default 10s)
+`
+
+// 退出码：0 成功；1 校验错误或者存在差异；2 参数或者执行错误
+const (
+	exitOK      = 0
+	exitProblem = 1
+	exitError   = 2
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitError)
+	}
+	var code int
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "validate":
+		code = runValidate(args)
+	case "publish":
+		code = runPublish(args, false)
+	case "delete":
+		code = runPublish(args, true)
+	case "diff":
+		code = runDiff(args)
+	case "inspect":
+		code = runInspect(args)
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", cmd, usage)
+		code = exitError
+	}
+	os.Exit(code)
+}
+
+type registryFlags struct {
+	address      string
+	endpointPath string
+	servicePath  string
+	timeout      time.Duration
+}
+
+func (r *registryFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&r.address, "zk", "127.0.0.1:2181", "zookeeper address, comma separated")
+	fs.StringVar(&r.endpointPath, "endpoint-path", discovery.ZkEndpointRootPath, "endpoint root path")
+	fs.StringVar(&r.servicePath, "service-path", discovery.ZkServiceRootPath, "service root path")
+	fs.DurationVar(&r.timeout, "timeout", time.Second*10, "connection timeout")
+}
+
+func (r *registryFlags) connect() (*Registry, error) {
+	return NewRegistry(r.address, r.timeout, r.endpointPath, r.servicePath)
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	return fs
+}
+
+func runValidate(args []string) int {
+	fs := newFlagSet("validate")
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	protocols := fs.String("protocols", "", "additional rpc protocols, comma separated")
+	if err := fs.Parse(args); nil != err {
+		return exitError
+	}
+	res, err := loadFiles(fs.Args())
+	if nil != err {
+		return failed(err)
+	}
+	report := validateResources(res, *protocols)
+	printReport(report)
+	if !report.Valid || (*strict && report.Warnings > 0) {
+		return exitProblem
+	}
+	return exitOK
+}
+
+func runPublish(args []string, remove bool) int {
+	name := "publish"
+	if remove {
+		name = "delete"
+	}
+	fs := newFlagSet(name)
+	registry := registryFlags{}
+	registry.bind(fs)
+	dryRun := fs.Bool("dry-run", false, "print the nodes without changing the registry")
+	protocols := fs.String("protocols", "", "additional rpc protocols, comma separated")
+	if err := fs.Parse(args); nil != err {
+		return exitError
+	}
+	res, err := loadFiles(fs.Args())
+	if nil != err {
+		return failed(err)
+	}
+	// 发布前使用与网关相同的规则校验，存在错误时拒绝发布
+	if !remove {
+		if report := validateResources(res, *protocols); !report.Valid {
+			printReport(report)
+			fmt.Fprintln(os.Stderr, "publish aborted: definitions have errors")
+			return exitProblem
+		}
+	}
+	if *dryRun {
+		for _, ep := range res.Endpoints {
+			fmt.Printf("%s endpoint %s\n", name, EndpointKey(&ep))
+		}
+		for _, srv := range res.Services {
+			fmt.Printf("%s service %s\n", name, ServiceKey(&srv))
+		}
+		return exitOK
+	}
+	client, err := registry.connect()
+	if nil != err {
+		return failed(err)
+	}
+	defer client.Close()
+	apply := func(node string, err error) error {
+		if nil != err {
+			return fmt.Errorf("%s node: %s, error: %w", name, node, err)
+		}
+		fmt.Printf("%s %s\n", name, node)
+		return nil
+	}
+	// Service先于Endpoint发布，Endpoint先于Service删除，保证权限服务引用有效
+	if remove {
+		for _, ep := range res.Endpoints {
+			if err := apply(client.DeleteEndpoint(ep)); nil != err {
+				return failed(err)
+			}
+		}
+		for _, srv := range res.Services {
+			if err := apply(client.DeleteService(srv)); nil != err {
+				return failed(err)
+			}
+		}
+	} else {
+		for _, srv := range res.Services {
+			if err := apply(client.PublishService(srv)); nil != err {
+				return failed(err)
+			}
+		}
+		for _, ep := range res.Endpoints {
+			if err := apply(client.PublishEndpoint(ep)); nil != err {
+				return failed(err)
+			}
+		}
+	}
+	return exitOK
+}
+
+func runDiff(args []string) int {
+	fs := newFlagSet("diff")
+	registry := registryFlags{}
+	registry.bind(fs)
+	if err := fs.Parse(args); nil != err {
+		return exitError
+	}
+	res, err := loadFiles(fs.Args())
+	if nil != err {
+		return failed(err)
+	}
+	client, err := registry.connect()
+	if nil != err {
+		return failed(err)
+	}
+	defer client.Close()
+	remote, err := client.Load()
+	if nil != err {
+		return failed(err)
+	}
+	items := Diff(NewDefinitions(res), remote)
+	for _, item := range items {
+		fmt.Println(item.String())
+	}
+	if len(items) > 0 {
+		return exitProblem
+	}
+	return exitOK
+}
+
+func runInspect(args []string) int {
+	fs := newFlagSet("inspect")
+	admin := fs.String("admin", "http://127.0.0.1:9527", "gateway admin address")
+	timeout := fs.Duration("timeout", time.Second*10, "request timeout")
+	query := queryFlag{}
+	fs.Var(&query, "q", "query parameter key=value, repeatable")
+	if err := fs.Parse(args); nil != err {
+		return exitError
+	}
+	if fs.NArg() != 1 {
+		return failed(fmt.Errorf("inspect target is required"))
+	}
+	status, body, err := Inspect(*admin, fs.Arg(0), url.Values(query), *timeout)
+	if nil != err {
+		return failed(err)
+	}
+	fmt.Println(body)
+	if status >= 400 {
+		return exitProblem
+	}
+	return exitOK
+}
+
+func loadFiles(files []string) (discovery.Resources, error) {
+	if len(files) == 0 {
+		return discovery.Resources{}, fmt.Errorf("resource files are required")
+	}
+	return LoadResources(files)
+}
+
+func validateResources(res discovery.Resources, protocols string) validate.Report {
+	opts := make([]validate.Option, 0, 1)
+	if "" != protocols {
+		opts = append(opts, validate.WithProtocols(strings.Split(protocols, ",")...))
+	}
+	return validate.NewValidatorWith(opts...).ValidateResources(res.Endpoints, res.Services)
+}
+
+func printReport(report validate.Report) {
+	for _, def := range report.Definitions {
+		fmt.Printf("%s %s\n", def.Kind, def.Key)
+		for _, issue := range def.Issues {
+			fmt.Printf("  %s\n", issue.String())
+		}
+	}
+	fmt.Printf("%d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+}
+
+func failed(err error) int {
+	fmt.Fprintf(os.Stderr, "error: %s\n", err)
+	return exitError
+}
+
+// queryFlag 可重复的 key=value 查询参数
+type queryFlag url.Values
+
+func (q queryFlag) String() string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func (q queryFlag) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || "" == kv[0] {
+		return fmt.Errorf("invalid query: %s, expected key=value", value)
+	}
+	url.Values(q).Add(kv[0], kv[1])
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/discovery"
+	"github.com/dubbogo/go-zookeeper/zk"
+	"path"
+	"strings"
+	"time"
+)
+
+// Registry 基于ZK的元数据注册中心客户端；节点数据格式与网关的ZK Discovery一致
+type Registry struct {
+	conn         *zk.Conn
+	endpointPath string
+	servicePath  string
+	nodes        map[string][]string // 根路径#定义Key -> 节点路径；由Load加载
+}
+
+type quietLogger struct{}
+
+func (quietLogger) Printf(string, ...interface{}) {}
+
+func NewRegistry(address string, timeout time.Duration, endpointPath, servicePath string) (*Registry, error) {
+	if "" == address {
+		return nil, fmt.Errorf("zookeeper address is required")
+	}
+	conn, _, err := zk.Connect(strings.Split(address, ","), timeout, zk.WithLogger(quietLogger{}))
+	if nil != err {
+		return nil, fmt.Errorf("connect zookeeper: %s, error: %w", address, err)
+	}
+	return &Registry{conn: conn, endpointPath: endpointPath, servicePath: servicePath}, nil
+}
+
+func (r *Registry) Close() {
+	r.conn.Close()
+}
+
+// PublishEndpoint 创建或者更新Endpoint节点；按节点数据中的定义Key查找已存在的节点，不依赖节点名称
+func (r *Registry) PublishEndpoint(ep flux.Endpoint) (string, error) {
+	return r.publish(r.endpointPath, EndpointKey(&ep), ep)
+}
+
+// PublishService 创建或者更新Service节点；按节点数据中的定义Key查找已存在的节点，不依赖节点名称
+func (r *Registry) PublishService(srv flux.BackendService) (string, error) {
+	return r.publish(r.servicePath, ServiceKey(&srv), srv)
+}
+
+// DeleteEndpoint 删除Endpoint节点；注册中心不存在此定义时返回错误
+func (r *Registry) DeleteEndpoint(ep flux.Endpoint) (string, error) {
+	return r.delete(r.endpointPath, EndpointKey(&ep))
+}
+
+// DeleteService 删除Service节点；注册中心不存在此定义时返回错误
+func (r *Registry) DeleteService(srv flux.BackendService) (string, error) {
+	return r.delete(r.servicePath, ServiceKey(&srv))
+}
+
+// Load 读取注册中心的全部Endpoint和Service定义，并记录定义所在的节点
+func (r *Registry) Load() (Definitions, error) {
+	defs := Definitions{
+		Endpoints: make(map[string]flux.Endpoint, 16),
+		Services:  make(map[string]flux.BackendService, 16),
+	}
+	nodes := make(map[string][]string, 16)
+	err := r.each(r.endpointPath, func(node string, data []byte) error {
+		comp := discovery.CompatibleEndpoint{}
+		if err := json.Unmarshal(data, &comp); nil != err {
+			return fmt.Errorf("decode endpoint node: %s, error: %w", node, err)
+		}
+		ep := comp.Endpoint
+		discovery.EnsureServiceAttrs(&ep.Service)
+		discovery.EnsureServiceAttrs(&ep.Permission)
+		key := EndpointKey(&ep)
+		defs.Endpoints[key] = ep
+		nodes[r.endpointPath+"#"+key] = append(nodes[r.endpointPath+"#"+key], node)
+		return nil
+	})
+	if nil != err {
+		return defs, err
+	}
+	err = r.each(r.servicePath, func(node string, data []byte) error {
+		srv := flux.BackendService{}
+		if err := json.Unmarshal(data, &srv); nil != err {
+			return fmt.Errorf("decode service node: %s, error: %w", node, err)
+		}
+		discovery.EnsureServiceAttrs(&srv)
+		key := ServiceKey(&srv)
+		defs.Services[key] = srv
+		nodes[r.servicePath+"#"+key] = append(nodes[r.servicePath+"#"+key], node)
+		return nil
+	})
+	if nil == err {
+		r.nodes = nodes
+	}
+	return defs, err
+}
+
+func (r *Registry) publish(root, key string, v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if nil != err {
+		return "", err
+	}
+	nodes, err := r.lookup(root, key)
+	if nil != err {
+		return "", err
+	}
+	// 其它客户端注册的节点名称可能不同，更新全部匹配的节点
+	if len(nodes) > 0 {
+		for _, node := range nodes {
+			if _, err := r.conn.Set(node, data, -1); nil != err {
+				return node, err
+			}
+		}
+		return strings.Join(nodes, ","), nil
+	}
+	if err := r.ensurePath(root); nil != err {
+		return "", err
+	}
+	node := path.Join(root, NodeName(key))
+	_, err = r.conn.Create(node, data, 0, zk.WorldACL(zk.PermAll))
+	// 同名节点已存在但数据为空（Load时被忽略），直接更新
+	if err == zk.ErrNodeExists {
+		_, err = r.conn.Set(node, data, -1)
+	}
+	if nil != err {
+		return node, err
+	}
+	r.nodes[root+"#"+key] = []string{node}
+	return node, nil
+}
+
+func (r *Registry) delete(root, key string) (string, error) {
+	nodes, err := r.lookup(root, key)
+	if nil != err {
+		return "", err
+	}
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("definition not found in registry: %s", key)
+	}
+	for _, node := range nodes {
+		if err := r.conn.Delete(node, -1); nil != err {
+			return node, err
+		}
+	}
+	delete(r.nodes, root+"#"+key)
+	return strings.Join(nodes, ","), nil
+}
+
+// lookup 返回定义Key所在的节点；首次查找时加载注册中心的全部定义
+func (r *Registry) lookup(root, key string) ([]string, error) {
+	if nil == r.nodes {
+		if _, err := r.Load(); nil != err {
+			return nil, err
+		}
+	}
+	return r.nodes[root+"#"+key], nil
+}
+
+func (r *Registry) each(root string, f func(node string, data []byte) error) error {
+	children, _, err := r.conn.Children(root)
+	if err == zk.ErrNoNode {
+		return nil
+	} else if nil != err {
+		return fmt.Errorf("list children: %s, error: %w", root, err)
+	}
+	for _, child := range children {
+		node := path.Join(root, child)
+		data, _, err := r.conn.Get(node)
+		if err == zk.ErrNoNode {
+			continue
+		} else if nil != err {
+			return fmt.Errorf("read node: %s, error: %w", node, err)
+		}
+		// 忽略空节点
+		if len(data) == 0 {
+			continue
+		}
+		if err := f(node, data); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Registry) ensurePath(root string) error {
+	parts := strings.Split(strings.Trim(root, "/"), "/")
+	current := ""
+	for _, p := range parts {
+		current += "/" + p
+		if _, err := r.conn.Create(current, []byte{}, 0, zk.WorldACL(zk.PermAll)); nil != err && err != zk.ErrNodeExists {
+			return fmt.Errorf("create path: %s, error: %w", current, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/discovery"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	DiffAdded    = "+"
+	DiffRemoved  = "-"
+	DiffModified = "~"
+)
+
+// Definitions 按定义Key索引的Endpoint和Service定义
+type Definitions struct {
+	Endpoints map[string]flux.Endpoint
+	Services  map[string]flux.BackendService
+}
+
+// DiffItem 本地文件与注册中心之间的定义差异
+type DiffItem struct {
+	Op   string
+	Kind string
+	Key  string
+}
+
+func (d DiffItem) String() string {
+	return fmt.Sprintf("%s %s %s", d.Op, d.Kind, d.Key)
+}
+
+// LoadResources 加载并合并多个JSON/YAML资源文档；兼容旧的Service属性格式
+func LoadResources(files []string) (discovery.Resources, error) {
+	out := discovery.Resources{}
+	for _, file := range files {
+		bytes, err := ioutil.ReadFile(file)
+		if nil != err {
+			return out, fmt.Errorf("read file: %s, error: %w", file, err)
+		}
+		res, err := discovery.DecodeResources(bytes)
+		if nil != err {
+			return out, fmt.Errorf("decode file: %s, error: %w", file, err)
+		}
+		out.Endpoints = append(out.Endpoints, res.Endpoints...)
+		out.Services = append(out.Services, res.Services...)
+	}
+	for i := range out.Endpoints {
+		discovery.EnsureServiceAttrs(&out.Endpoints[i].Service)
+		discovery.EnsureServiceAttrs(&out.Endpoints[i].Permission)
+	}
+	for i := range out.Services {
+		discovery.EnsureServiceAttrs(&out.Services[i])
+	}
+	return out, nil
+}
+
+// NewDefinitions 按定义Key索引资源文档中的定义
+func NewDefinitions(res discovery.Resources) Definitions {
+	defs := Definitions{
+		Endpoints: make(map[string]flux.Endpoint, len(res.Endpoints)),
+		Services:  make(map[string]flux.BackendService, len(res.Services)),
+	}
+	for _, ep := range res.Endpoints {
+		defs.Endpoints[EndpointKey(&ep)] = ep
+	}
+	for _, srv := range res.Services {
+		defs.Services[ServiceKey(&srv)] = srv
+	}
+	return defs
+}
+
+// EndpointKey Endpoint定义的标识：METHOD#pattern#version
+func EndpointKey(ep *flux.Endpoint) string {
+	return strings.ToUpper(ep.HttpMethod) + "#" + ep.HttpPattern + "#" + ep.Version
+}
+
+// ServiceKey Service定义的标识：ServiceId；未定义时为 Interface:Method
+func ServiceKey(srv *flux.BackendService) string {
+	if "" != srv.ServiceId {
+		return srv.ServiceId
+	}
+	return srv.ServiceID()
+}
+
+// NodeName 将定义Key转换为ZK节点名称；节点名称不能包含路径分隔符
+func NodeName(key string) string {
+	return url.QueryEscape(key)
+}
+
+// Diff 对比本地定义与注册中心定义：
+// + 只在本地定义，- 只在注册中心定义，~ 两者定义内容不同；
+func Diff(local, remote Definitions) []DiffItem {
+	items := make([]DiffItem, 0)
+	for key, ep := range local.Endpoints {
+		if rep, ok := remote.Endpoints[key]; !ok {
+			items = append(items, DiffItem{Op: DiffAdded, Kind: "endpoint", Key: key})
+		} else if digestOf(ep) != digestOf(rep) {
+			items = append(items, DiffItem{Op: DiffModified, Kind: "endpoint", Key: key})
+		}
+	}
+	for key := range remote.Endpoints {
+		if _, ok := local.Endpoints[key]; !ok {
+			items = append(items, DiffItem{Op: DiffRemoved, Kind: "endpoint", Key: key})
+		}
+	}
+	for key, srv := range local.Services {
+		if rsrv, ok := remote.Services[key]; !ok {
+			items = append(items, DiffItem{Op: DiffAdded, Kind: "service", Key: key})
+		} else if digestOf(srv) != digestOf(rsrv) {
+			items = append(items, DiffItem{Op: DiffModified, Kind: "service", Key: key})
+		}
+	}
+	for key := range remote.Services {
+		if _, ok := local.Services[key]; !ok {
+			items = append(items, DiffItem{Op: DiffRemoved, Kind: "service", Key: key})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Kind != items[j].Kind {
+			return items[i].Kind < items[j].Kind
+		}
+		return items[i].Key < items[j].Key
+	})
+	return items
+}
+
+func digestOf(v interface{}) string {
+	bytes, _ := json.Marshal(v)
+	return string(bytes)
+}
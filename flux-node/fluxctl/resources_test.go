@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadResources(t *testing.T) {
+	assert := assert2.New(t)
+	dir, err := ioutil.TempDir("", "fluxctl")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "app.yml")
+	assert.NoError(ioutil.WriteFile(file, []byte(`
+endpoints:
+  - httpMethod: get
+    httpPattern: /a
+    version: v1
+    service: { interface: a, method: get, rpcProto: ECHO }
+services:
+  - { interface: s, method: get, rpcProto: DUBBO }
+`), 0644))
+	res, err := LoadResources([]string{file})
+	assert.NoError(err)
+	assert.Equal(1, len(res.Endpoints))
+	assert.Equal(flux.ProtoEcho, res.Endpoints[0].Service.AttrRpcProto())
+	defs := NewDefinitions(res)
+	_, ok := defs.Endpoints["GET#/a#v1"]
+	assert.True(ok)
+	_, ok = defs.Services["s:get"]
+	assert.True(ok)
+	_, err = LoadResources([]string{filepath.Join(dir, "missing.yml")})
+	assert.Error(err)
+}
+
+func TestDiff(t *testing.T) {
+	endpoint := func(pattern, iface string) flux.Endpoint {
+		return flux.Endpoint{HttpMethod: "GET", HttpPattern: pattern,
+			Service: flux.BackendService{Interface: iface, Method: "get"}}
+	}
+	local := Definitions{
+		Endpoints: map[string]flux.Endpoint{"GET#/a#": endpoint("/a", "a"), "GET#/b#": endpoint("/b", "b")},
+		Services:  map[string]flux.BackendService{"s": {ServiceId: "s", Interface: "s", Method: "get"}},
+	}
+	remote := Definitions{
+		Endpoints: map[string]flux.Endpoint{"GET#/a#": endpoint("/a", "a"), "GET#/b#": endpoint("/b", "b2"), "GET#/c#": endpoint("/c", "c")},
+		Services:  map[string]flux.BackendService{},
+	}
+	assert2.Equal(t, []DiffItem{
+		{Op: DiffModified, Kind: "endpoint", Key: "GET#/b#"},
+		{Op: DiffRemoved, Kind: "endpoint", Key: "GET#/c#"},
+		{Op: DiffAdded, Kind: "service", Key: "s"},
+	}, Diff(local, remote))
+}
+
+func TestNodeName(t *testing.T) {
+	cases := []struct {
+		key  string
+		node string
+	}{
+		{key: "GET#/users/:id#v1", node: "GET%23%2Fusers%2F%3Aid%23v1"},
+		{key: "com.foo.User:get", node: "com.foo.User%3Aget"},
+	}
+	for _, tc := range cases {
+		assert2.Equal(t, tc.node, NodeName(tc.key))
+	}
+}
@@ -0,0 +1,96 @@
+package identity
+
+import (
+	assert2 "github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestUUIDGenerator(t *testing.T) {
+	assert := assert2.New(t)
+	g := NewUUIDGenerator()
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ids := make(map[string]bool, 100)
+	for i := 0; i < 100; i++ {
+		id := g.NextId()
+		assert.True(pattern.MatchString(id), "must match uuid-v4: "+id)
+		assert.False(ids[id], "must unique")
+		ids[id] = true
+	}
+}
+
+func TestULIDGenerator(t *testing.T) {
+	assert := assert2.New(t)
+	g := NewULIDGenerator()
+	prev := ""
+	for i := 0; i < 100; i++ {
+		id := g.NextId()
+		assert.Equal(26, len(id))
+		assert.True(IsValidRequestId(id))
+		// 时间戳部分单调不减
+		assert.True(strings.Compare(prev[:minInt(len(prev), 10)], id[:10]) <= 0)
+		prev = id
+	}
+}
+
+func TestSnowflakeGenerator(t *testing.T) {
+	assert := assert2.New(t)
+	g := NewSnowflakeGenerator(1)
+	ids := make(map[string]bool, 10000)
+	for i := 0; i < 10000; i++ {
+		id := g.NextId()
+		assert.False(ids[id], "must unique: "+id)
+		ids[id] = true
+	}
+}
+
+func TestTrustedUpstreams(t *testing.T) {
+	assert := assert2.New(t)
+	cases := []struct {
+		rules   []string
+		addr    string
+		trusted bool
+	}{
+		{rules: []string{"*"}, addr: "8.8.8.8:80", trusted: true},
+		{rules: []string{}, addr: "127.0.0.1:80", trusted: false},
+		{rules: []string{"127.0.0.1"}, addr: "127.0.0.1:1234", trusted: true},
+		{rules: []string{"127.0.0.1"}, addr: "127.0.0.2:1234", trusted: false},
+		{rules: []string{"10.0.0.0/8"}, addr: "10.1.2.3:1234", trusted: true},
+		{rules: []string{"10.0.0.0/8"}, addr: "11.1.2.3:1234", trusted: false},
+		{rules: []string{"10.0.0.0/8", "::1"}, addr: "[::1]:1234", trusted: true},
+		{rules: []string{"10.0.0.0/8"}, addr: "bad-addr", trusted: false},
+	}
+	for _, c := range cases {
+		trusted, invalid := NewTrustedUpstreams(c.rules)
+		assert.Equal(0, len(invalid))
+		assert.Equal(c.trusted, trusted.IsTrusted(c.addr), "rules: %v, addr: %s", c.rules, c.addr)
+	}
+	_, invalid := NewTrustedUpstreams([]string{"abc", "1.2.3.4/99"})
+	assert.Equal([]string{"abc", "1.2.3.4/99"}, invalid)
+}
+
+func TestIsValidRequestId(t *testing.T) {
+	assert := assert2.New(t)
+	cases := []struct {
+		id    string
+		valid bool
+	}{
+		{id: "", valid: false},
+		{id: "abc-123_ABC.x:y", valid: true},
+		{id: "abc 123", valid: false},
+		{id: "abc\r\n", valid: false},
+		{id: strings.Repeat("a", RequestIdMaxLength), valid: true},
+		{id: strings.Repeat("a", RequestIdMaxLength+1), valid: false},
+	}
+	for _, c := range cases {
+		assert.Equal(c.valid, IsValidRequestId(c.id), "id: "+c.id)
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,74 @@
+package identity
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeNodeMax      = -1 ^ (-1 << snowflakeNodeBits)
+	snowflakeSequenceMask = -1 ^ (-1 << snowflakeSequenceBits)
+	snowflakeTimeShift    = snowflakeNodeBits + snowflakeSequenceBits
+)
+
+const (
+	// 指定Snowflake节点ID的环境变量
+	EnvKeySnowflakeNodeId = "FLUX_NODE_ID"
+)
+
+var (
+	// 时间起点：2020-01-01 00:00:00 UTC
+	snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+)
+
+var _ flux.RequestIdGenerator = new(SnowflakeGenerator)
+
+// SnowflakeGenerator 生成Snowflake风格的请求ID：41位毫秒时间戳 + 10位节点ID + 12位序列号
+type SnowflakeGenerator struct {
+	nodeId   int64
+	lastMs   int64
+	sequence int64
+	mutex    sync.Mutex
+}
+
+func NewSnowflakeGenerator(nodeId int64) *SnowflakeGenerator {
+	return &SnowflakeGenerator{nodeId: nodeId & snowflakeNodeMax}
+}
+
+func (g *SnowflakeGenerator) NextId() string {
+	g.mutex.Lock()
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	if now <= g.lastMs {
+		// 同一毫秒内或者时钟回拨，沿用上次时间戳，递增序列号
+		now = g.lastMs
+		g.sequence = (g.sequence + 1) & snowflakeSequenceMask
+		if g.sequence == 0 {
+			now++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = now
+	id := ((now - snowflakeEpoch) << snowflakeTimeShift) | (g.nodeId << snowflakeSequenceBits) | g.sequence
+	g.mutex.Unlock()
+	return strconv.FormatInt(id, 10)
+}
+
+// NodeIdOfHost 返回当前节点的Snowflake节点ID；优先读取环境变量，否则根据主机名计算；
+func NodeIdOfHost() int64 {
+	if env, ok := os.LookupEnv(EnvKeySnowflakeNodeId); ok {
+		if id, err := strconv.ParseInt(env, 10, 64); nil == err {
+			return id & snowflakeNodeMax
+		}
+	}
+	host, _ := os.Hostname()
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(host))
+	return int64(h.Sum32()) & snowflakeNodeMax
+}
@@ -0,0 +1,94 @@
+package identity
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	// 请求ID的最大长度；超出长度的请求ID不被接受
+	RequestIdMaxLength = 128
+)
+
+// TrustedUpstreams 定义可信任的上游地址规则。
+// 只有来自可信上游的请求，其携带的请求ID才会被网关接受；
+// 规则支持：'*'（信任全部）、IP地址、CIDR网段；
+type TrustedUpstreams struct {
+	any   bool
+	ips   []net.IP
+	cidrs []*net.IPNet
+}
+
+// NewTrustedUpstreams 解析可信上游规则列表；无法识别的规则被忽略，并返回在invalid列表中；
+func NewTrustedUpstreams(rules []string) (trusted *TrustedUpstreams, invalid []string) {
+	trusted = &TrustedUpstreams{
+		ips:   make([]net.IP, 0, len(rules)),
+		cidrs: make([]*net.IPNet, 0, len(rules)),
+	}
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if "" == rule {
+			continue
+		}
+		if "*" == rule {
+			trusted.any = true
+		} else if strings.Contains(rule, "/") {
+			if _, cidr, err := net.ParseCIDR(rule); nil == err {
+				trusted.cidrs = append(trusted.cidrs, cidr)
+			} else {
+				invalid = append(invalid, rule)
+			}
+		} else if ip := net.ParseIP(rule); nil != ip {
+			trusted.ips = append(trusted.ips, ip)
+		} else {
+			invalid = append(invalid, rule)
+		}
+	}
+	return trusted, invalid
+}
+
+// IsTrusted 判断远程地址是否为可信上游；地址格式为 host 或者 host:port；
+func (t *TrustedUpstreams) IsTrusted(remoteAddr string) bool {
+	if t.any {
+		return true
+	}
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); nil == err {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if nil == ip {
+		return false
+	}
+	for _, tip := range t.ips {
+		if tip.Equal(ip) {
+			return true
+		}
+	}
+	for _, cidr := range t.cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRequestId 检查请求ID格式：非空，长度不超过 RequestIdMaxLength，只包含字母、数字以及 -_.: 字符；
+func IsValidRequestId(id string) bool {
+	size := len(id)
+	if size == 0 || size > RequestIdMaxLength {
+		return false
+	}
+	for i := 0; i < size; i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			continue
+		case c == '-' || c == '_' || c == '.' || c == ':':
+			continue
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package identity
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/bytepowered/flux/flux-node"
+)
+
+var _ flux.RequestIdGenerator = new(UUIDGenerator)
+
+// UUIDGenerator 基于随机数生成 RFC4122 UUIDv4 格式的请求ID
+type UUIDGenerator struct {
+}
+
+func NewUUIDGenerator() *UUIDGenerator {
+	return &UUIDGenerator{}
+}
+
+func (g *UUIDGenerator) NextId() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 // Version 4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // Variant RFC4122
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf[:])
+}
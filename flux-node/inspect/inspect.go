@@ -101,6 +101,21 @@ func ServicesHandler(ctx flux.WebExchange) error {
 	})
 }
 
+// TransportsHandler 查询BackendTransport的运行时状态；可通过 protocol 参数指定协议
+func TransportsHandler(webex flux.WebExchange) error {
+	query := webex.QueryVar(queryKeyProtocol)
+	out := make(map[string]interface{}, 4)
+	for proto, transport := range ext.BackendTransports() {
+		if "" != query && !strings.EqualFold(query, proto) {
+			continue
+		}
+		if inspector, ok := transport.(flux.Inspector); ok {
+			out[proto] = inspector.Inspect()
+		}
+	}
+	return webex.Send(webex, http.Header{}, flux.StatusOK, out)
+}
+
 func queryWithEndpointFilters(data map[string]*flux.MultiEndpoint, filters ...EndpointFilter) []map[string]*flux.Endpoint {
 	items := make([]map[string]*flux.Endpoint, 0, 16)
 DataLoop:
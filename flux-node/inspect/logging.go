@@ -0,0 +1,94 @@
+package inspect
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"net/http"
+	"time"
+)
+
+const (
+	paramKeyLevel            = "level"
+	paramKeyComponent        = "component"
+	paramKeyDebugRequestId   = "debug-request-id"
+	paramKeyDebugApplication = "debug-application"
+	paramKeyDuration         = "duration"
+)
+
+const (
+	defaultDebugDuration = time.Minute * 5
+)
+
+// LoggingHandler 查询运行时日志级别配置
+func LoggingHandler(webex flux.WebExchange) error {
+	return webex.Send(webex, http.Header{}, flux.StatusOK, loggingState())
+}
+
+// LoggingUpdateHandler 修改运行时日志级别配置；参数：
+// level: 全局日志级别；与component一起使用时，设置组件日志级别；
+// component: 组件名称，即日志消息前缀，例如：BACKEND:DUBBO；level为空时，删除组件日志级别；
+// debug-request-id: 对指定请求ID开启调试日志；
+// debug-application: 对指定Application开启调试日志；
+// duration: 调试日志的时间窗口，默认为5m；为0时关闭调试日志；
+func LoggingUpdateHandler(webex flux.WebExchange) error {
+	noheader := http.Header{}
+	failed := func(err error) error {
+		return webex.Send(webex, noheader, flux.StatusBadRequest, map[string]string{
+			"status":  "failed",
+			"message": err.Error(),
+		})
+	}
+	level := paramOf(webex, paramKeyLevel)
+	if component := paramOf(webex, paramKeyComponent); "" != component {
+		if "" == level {
+			logger.RemoveComponentLevel(component)
+		} else if err := logger.SetComponentLevel(component, level); nil != err {
+			return failed(err)
+		}
+		logger.Infow("INSPECT:LOGGING:COMPONENT", "component", component, "level", level)
+	} else if "" != level {
+		if err := logger.SetLevel(level); nil != err {
+			return failed(err)
+		}
+		logger.Infow("INSPECT:LOGGING:LEVEL", "level", level)
+	}
+	duration := defaultDebugDuration
+	if v := paramOf(webex, paramKeyDuration); "" != v {
+		d, err := time.ParseDuration(v)
+		if nil != err {
+			return failed(err)
+		}
+		duration = d
+	}
+	for key, param := range map[string]string{
+		logger.DebugKeyRequestId:   paramKeyDebugRequestId,
+		logger.DebugKeyApplication: paramKeyDebugApplication,
+	} {
+		value := paramOf(webex, param)
+		if "" == value {
+			continue
+		}
+		if duration == 0 {
+			logger.DisableDebug(key, value)
+		} else if err := logger.EnableDebug(key, value, duration); nil != err {
+			return failed(err)
+		}
+		logger.Infow("INSPECT:LOGGING:DEBUG", key, value, "duration", duration.String())
+	}
+	return webex.Send(webex, noheader, flux.StatusOK, loggingState())
+}
+
+func loggingState() map[string]interface{} {
+	return map[string]interface{}{
+		"level":      logger.GetLevel(),
+		"components": logger.ComponentLevels(),
+		"debug":      logger.DebugWindows(),
+	}
+}
+
+func paramOf(webex flux.WebExchange, key string) string {
+	if v := webex.QueryVar(key); "" != v {
+		return v
+	}
+	return webex.FormVar(key)
+}
@@ -0,0 +1,46 @@
+package inspect
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/discovery"
+	"io/ioutil"
+	"net/http"
+)
+
+// ValidateHandler 校验请求Body中的资源文档（JSON/YAML，包含endpoints和services列表），返回校验报告；
+// 权限服务ID可以引用文档中或者网关已注册的Service；
+func ValidateHandler(webex flux.WebExchange) error {
+	noheader := http.Header{}
+	failed := func(err error) error {
+		return webex.Send(webex, noheader, flux.StatusBadRequest, map[string]string{
+			"status":  "failed",
+			"message": err.Error(),
+		})
+	}
+	reader, err := webex.BodyReader()
+	if nil != err {
+		return failed(err)
+	}
+	defer reader.Close()
+	body, err := ioutil.ReadAll(reader)
+	if nil != err {
+		return failed(err)
+	}
+	resources, err := discovery.DecodeResources(body)
+	if nil != err {
+		return failed(err)
+	}
+	for i := range resources.Endpoints {
+		discovery.EnsureServiceAttrs(&resources.Endpoints[i].Service)
+		discovery.EnsureServiceAttrs(&resources.Endpoints[i].Permission)
+	}
+	for i := range resources.Services {
+		discovery.EnsureServiceAttrs(&resources.Services[i])
+	}
+	report := discovery.NewGatewayValidator().ValidateResources(resources.Endpoints, resources.Services)
+	status := flux.StatusOK
+	if !report.Valid {
+		status = flux.StatusBadRequest
+	}
+	return webex.Send(webex, noheader, status, report)
+}
@@ -30,6 +30,14 @@ type (
 	Orderer interface {
 		Order() int // 返回排序顺序
 	}
+	// Readiness 用于报告组件是否已就绪，服务就绪检查（Readiness）将汇总各组件的状态。
+	Readiness interface {
+		Ready() bool // 返回组件是否已就绪
+	}
+	// Inspector 用于报告组件的运行时状态，通过管理接口查询。
+	Inspector interface {
+		Inspect() interface{} // 返回组件的运行时状态
+	}
 )
 
 // 日志Logger接口定义
@@ -4,4 +4,10 @@ const (
 	ContextKeyPrefix        = "__flux.core__"
 	ContextKeyRequestId     = ContextKeyPrefix + "request.id"
 	ContextKeyRouteEndpoint = ContextKeyPrefix + "route.endpoint"
+	// 错误响应：Endpoint所属应用，用于选择错误响应模板
+	ContextKeyRouteApplication = ContextKeyPrefix + "route.application"
+	// 响应内容协商：Endpoint定义的响应格式列表
+	ContextKeyResponseProduces = ContextKeyPrefix + "response.produces"
+	// Http后端服务：请求范围内解析的Header策略，Key后缀为ServiceID
+	ContextKeyHttpHeaderPolicy = ContextKeyPrefix + "http.header.policy:"
 )
@@ -22,12 +22,18 @@ const (
 	MIMEApplicationJSON            = "application/json"
 	MIMEApplicationJSONCharsetUTF8 = MIMEApplicationJSON + "; " + charsetUTF8
 	MIMEApplicationForm            = "application/x-www-form-urlencoded"
+	MIMEApplicationXML             = "application/xml"
+	MIMETextXML                    = "text/xml"
+	MIMEApplicationMsgPack         = "application/msgpack"
+	MIMEApplicationXMsgPack        = "application/x-msgpack"
+	MIMEApplicationProtoJSON       = "application/x-protobuf+json"
 )
 
 // Headers
 const (
 	HeaderAccept              = "Accept"
 	HeaderAcceptEncoding      = "Accept-Encoding"
+	HeaderAcceptLanguage      = "Accept-Language"
 	HeaderAllow               = "Allow"
 	HeaderAuthorization       = "Authorization"
 	HeaderContentDisposition  = "Content-Disposition"
@@ -80,14 +86,15 @@ const (
 
 // Common used status code
 const (
-	StatusOK           = http.StatusOK
-	StatusBadRequest   = http.StatusBadRequest
-	StatusNotFound     = http.StatusNotFound
-	StatusUnauthorized = http.StatusUnauthorized
-	StatusAccessDenied = http.StatusForbidden
-	StatusServerError  = http.StatusInternalServerError
-	StatusBadGateway   = http.StatusBadGateway
-	StatusNoContent    = http.StatusNoContent
+	StatusOK                 = http.StatusOK
+	StatusBadRequest         = http.StatusBadRequest
+	StatusNotFound           = http.StatusNotFound
+	StatusUnauthorized       = http.StatusUnauthorized
+	StatusAccessDenied       = http.StatusForbidden
+	StatusServerError        = http.StatusInternalServerError
+	StatusBadGateway         = http.StatusBadGateway
+	StatusNoContent          = http.StatusNoContent
+	StatusSwitchingProtocols = http.StatusSwitchingProtocols
 )
 
 // Web interfaces defines
@@ -106,6 +113,12 @@ type (
 	// WebRequestIdentifier 查找请求ID的函数
 	WebRequestIdentifier func(shadowContext interface{}) string
 
+	// RequestIdGenerator 请求ID生成器；实现必须是并发安全的；
+	RequestIdGenerator interface {
+		// NextId 生成新的请求ID
+		NextId() string
+	}
+
 	// WebRequestBodyResolver 解析请求体数据
 	WebRequestBodyResolver func(WebExchange) url.Values
 
@@ -29,7 +29,7 @@ func DefaultErrorHandler(webex flux.WebExchange, err error) {
 		webex.SendError(&flux.ServeError{
 			StatusCode: flux.StatusServerError,
 			ErrorCode:  flux.ErrorCodeGatewayInternal,
-			Message:    err.Error(),
+			Message:    flux.ErrorMessageWebServerInternal,
 			Header:     http.Header{},
 			CauseError: err,
 		})
@@ -38,13 +38,45 @@ func DefaultErrorHandler(webex flux.WebExchange, err error) {
 
 var _ flux.WebResponseWriter = new(DefaultResponseWriter)
 
+// DefaultResponseWriter 默认的响应写入实现；错误响应按应用或者Listener配置的模板渲染
 type DefaultResponseWriter struct {
+	envelopes *ErrorEnvelopes
+}
+
+func NewDefaultResponseWriter() *DefaultResponseWriter {
+	return &DefaultResponseWriter{envelopes: NewErrorEnvelopes()}
+}
+
+// NewDefaultResponseWriterWith 根据Listener的 error_envelope 配置创建；配置无效时使用默认错误响应模板
+func NewDefaultResponseWriterWith(id string, config *flux.Configuration) *DefaultResponseWriter {
+	envelopes, err := NewErrorEnvelopesWith(config)
+	if nil != err {
+		logger.Errorw("SERVER:ERROR_ENVELOPE:INVALID", "listener-id", id, "error", err)
+		envelopes = NewErrorEnvelopes()
+	}
+	return &DefaultResponseWriter{envelopes: envelopes}
 }
 
 func (d *DefaultResponseWriter) Write(webex flux.WebExchange, header http.Header, status int, body interface{}) error {
 	fluxpkg.AssertNotNil(body, "<body> is nil, when write body in response writer")
 	d.setDefaults(webex, header)
-	if bytes, err := Serialize(webex.RequestId(), body); nil != err {
+	if stream, ok := body.(*flux.StreamBody); ok {
+		return d.writeStream(webex, status, stream)
+	}
+	// 对象类型的响应数据，按内容协商的格式序列化
+	if !isRawBody(body) {
+		if serializer, mediaType, ok := NegotiateSerializer(webex); ok {
+			webex.AddResponseHeader(flux.HeaderVary, flux.HeaderAccept)
+			bytes, err := serializer.Marshal(body)
+			if nil != err {
+				logger.Trace(webex.RequestId()).Errorw("SERVER:SERIALIZE:WRITE:negotiate",
+					"media-type", mediaType, "body", body, "error", err)
+				return err
+			}
+			return webex.Write(status, mediaType, bytes)
+		}
+	}
+	if bytes, err := Serialize(webex.RequestId(), body); nil == err {
 		return webex.Write(status, flux.MIMEApplicationJSON, bytes)
 	} else {
 		return err
@@ -53,14 +85,22 @@ func (d *DefaultResponseWriter) Write(webex flux.WebExchange, header http.Header
 
 func (d *DefaultResponseWriter) WriteError(webex flux.WebExchange, header http.Header, status int, error *flux.ServeError) error {
 	fluxpkg.AssertNotNil(error, "<error> is nil, when write error in response writer")
-	emap := map[string]interface{}{
-		"status":  "error",
-		"message": error.Message,
+	envelopes := d.envelopes
+	if nil == envelopes {
+		envelopes = NewErrorEnvelopes()
 	}
-	if nil != error.CauseError {
-		emap["error"] = error.CauseError.Error()
+	return d.Write(webex, header, status, envelopes.Render(webex, error))
+}
+
+// writeStream 流式写入响应数据；响应Header已提交，流复制中断（客户端取消、超过最大时长等）时只记录日志；
+func (d *DefaultResponseWriter) writeStream(webex flux.WebExchange, status int, stream *flux.StreamBody) error {
+	defer func() {
+		_ = stream.Close()
+	}()
+	if err := webex.WriteStream(status, stream.ContentType, stream); nil != err {
+		logger.Trace(webex.RequestId()).Infow("SERVER:STREAM:INTERRUPTED", "error", err)
 	}
-	return d.Write(webex, header, status, emap)
+	return nil
 }
 
 func (d *DefaultResponseWriter) setDefaults(webex flux.WebExchange, header http.Header) {
@@ -73,6 +113,16 @@ func (d *DefaultResponseWriter) setDefaults(webex flux.WebExchange, header http.
 	}
 }
 
+// isRawBody 判断响应数据是否为无需序列化的原始数据
+func isRawBody(body interface{}) bool {
+	switch body.(type) {
+	case []byte, string, io.Reader:
+		return true
+	default:
+		return false
+	}
+}
+
 func Serialize(id string, body interface{}) ([]byte, error) {
 	if bytes, ok := body.([]byte); ok {
 		return bytes, nil
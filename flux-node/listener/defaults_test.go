@@ -0,0 +1,53 @@
+package listener
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type writerWebExchange struct {
+	flux.WebExchange
+	headers     http.Header
+	status      int
+	contentType string
+	body        []byte
+}
+
+func (w *writerWebExchange) RequestId() string {
+	return "req-1"
+}
+
+func (w *writerWebExchange) SetResponseHeader(name, value string) {
+	w.headers.Set(name, value)
+}
+
+func (w *writerWebExchange) AddResponseHeader(name, value string) {
+	w.headers.Add(name, value)
+}
+
+func (w *writerWebExchange) Write(statusCode int, contentType string, bytes []byte) error {
+	w.status, w.contentType, w.body = statusCode, contentType, bytes
+	return nil
+}
+
+func TestDefaultResponseWriter_Write(t *testing.T) {
+	cases := []struct {
+		body     interface{}
+		expected string
+	}{
+		{body: []byte("bytes"), expected: "bytes"},
+		{body: "text", expected: "text"},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		webex := &writerWebExchange{headers: http.Header{}}
+		writer := new(DefaultResponseWriter)
+		err := writer.Write(webex, http.Header{"X-Test": []string{"1"}}, flux.StatusOK, tcase.body)
+		assert.NoError(err)
+		assert.Equal(flux.StatusOK, webex.status)
+		assert.Equal(tcase.expected, string(webex.body), "serialized body must be written")
+		assert.Equal("1", webex.headers.Get("X-Test"))
+	}
+}
@@ -0,0 +1,249 @@
+package listener
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal"
+	"github.com/spf13/cast"
+	"regexp"
+	"strings"
+)
+
+const (
+	ConfigKeyErrorEnvelope             = "error_envelope"
+	ConfigKeyErrorEnvelopeDebug        = "debug"
+	ConfigKeyErrorEnvelopeTemplate     = "template"
+	ConfigKeyErrorEnvelopeCodes        = "codes"
+	ConfigKeyErrorEnvelopeMessages     = "messages"
+	ConfigKeyErrorEnvelopeLocale       = "default_locale"
+	ConfigKeyErrorEnvelopeApplications = "applications"
+)
+
+// 错误响应模板变量：${status}, ${code}, ${errorCode}, ${message}, ${requestId}, ${error}
+const (
+	EnvelopeVarStatus    = "status"
+	EnvelopeVarCode      = "code"
+	EnvelopeVarErrorCode = "errorCode"
+	EnvelopeVarMessage   = "message"
+	EnvelopeVarRequestId = "requestId"
+	EnvelopeVarError     = "error"
+)
+
+var (
+	envelopeVarPattern = regexp.MustCompile(`\$\{(\w+)}`)
+)
+
+// DefaultErrorEnvelopeTemplate 默认错误响应模板；内部错误原因仅在调试模式下输出
+func DefaultErrorEnvelopeTemplate() map[string]interface{} {
+	return map[string]interface{}{
+		"status":  "error",
+		"message": "${" + EnvelopeVarMessage + "}",
+		"error":   "${" + EnvelopeVarError + "}",
+	}
+}
+
+// ErrorEnvelope 错误响应模板；
+// 模板的字符串值中，可以使用 ${name} 引用模板变量；值为单个变量引用时，保留变量的原始类型；
+// ErrorCode 通过 Codes 映射为客户端错误码，错误消息通过 Messages 按请求的 Accept-Language 本地化；
+// 内部错误原因 ${error} 仅在 Debug 开启时输出，否则移除该字段；
+type ErrorEnvelope struct {
+	Template      map[string]interface{}
+	Codes         map[string]interface{}       // ErrorCode -> 客户端错误码
+	Messages      map[string]map[string]string // Locale -> ErrorCode -> 本地化消息
+	DefaultLocale string
+	Debug         bool
+}
+
+// Render 根据错误对象渲染错误响应
+func (e *ErrorEnvelope) Render(webex flux.WebExchange, serr *flux.ServeError) map[string]interface{} {
+	errorCode := serr.GetErrorCode()
+	vars := map[string]interface{}{
+		EnvelopeVarStatus:    serr.StatusCode,
+		EnvelopeVarCode:      e.clientCode(errorCode),
+		EnvelopeVarErrorCode: errorCode,
+		EnvelopeVarMessage:   e.localize(webex.HeaderVar(flux.HeaderAcceptLanguage), errorCode, serr.Message),
+		EnvelopeVarRequestId: webex.RequestId(),
+	}
+	if e.Debug && nil != serr.CauseError {
+		vars[EnvelopeVarError] = serr.CauseError.Error()
+	}
+	return renderEnvelopeMap(e.Template, vars)
+}
+
+func (e *ErrorEnvelope) clientCode(errorCode string) interface{} {
+	if code, ok := e.Codes[strings.ToLower(errorCode)]; ok {
+		return code
+	}
+	return errorCode
+}
+
+// localize 按Accept-Language的顺序查找本地化消息；找不到时使用默认Locale，最后使用原始错误消息
+func (e *ErrorEnvelope) localize(acceptLanguage string, errorCode, message string) string {
+	if len(e.Messages) == 0 {
+		return message
+	}
+	locales := make([]string, 0, 4)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		if "" == tag || "*" == tag {
+			continue
+		}
+		locales = append(locales, tag)
+		if idx := strings.IndexAny(tag, "-_"); idx > 0 {
+			locales = append(locales, tag[:idx])
+		}
+	}
+	if "" != e.DefaultLocale {
+		locales = append(locales, strings.ToLower(e.DefaultLocale))
+	}
+	code := strings.ToLower(errorCode)
+	for _, locale := range locales {
+		if catalog, ok := e.Messages[locale]; ok {
+			if msg, ok := catalog[code]; ok {
+				return msg
+			}
+		}
+	}
+	return message
+}
+
+// ErrorEnvelopes 按应用选择的错误响应模板；未配置应用模板时，使用Listener的默认模板
+type ErrorEnvelopes struct {
+	defaults     *ErrorEnvelope
+	applications map[string]*ErrorEnvelope
+}
+
+// NewErrorEnvelopes 创建使用默认模板的ErrorEnvelopes
+func NewErrorEnvelopes() *ErrorEnvelopes {
+	return &ErrorEnvelopes{
+		defaults:     &ErrorEnvelope{Template: DefaultErrorEnvelopeTemplate()},
+		applications: make(map[string]*ErrorEnvelope, 0),
+	}
+}
+
+// NewErrorEnvelopesWith 根据Listener的 error_envelope 配置创建；
+// applications 下按应用名称配置的模板，未配置的项继承Listener的默认配置；
+func NewErrorEnvelopesWith(config *flux.Configuration) (*ErrorEnvelopes, error) {
+	envelopes := NewErrorEnvelopes()
+	defaults, err := loadErrorEnvelope(config, envelopes.defaults)
+	if nil != err {
+		return nil, err
+	}
+	envelopes.defaults = defaults
+	for app := range config.GetStringMap(ConfigKeyErrorEnvelopeApplications) {
+		appenv, err := loadErrorEnvelope(config.Sub(ConfigKeyErrorEnvelopeApplications+"."+app), defaults)
+		if nil != err {
+			return nil, fmt.Errorf("application: %s, %w", app, err)
+		}
+		envelopes.applications[strings.ToLower(app)] = appenv
+	}
+	return envelopes, nil
+}
+
+// Lookup 查找应用的错误响应模板
+func (e *ErrorEnvelopes) Lookup(application string) *ErrorEnvelope {
+	if env, ok := e.applications[strings.ToLower(application)]; ok {
+		return env
+	}
+	return e.defaults
+}
+
+// Render 根据请求所属的应用，渲染错误响应
+func (e *ErrorEnvelopes) Render(webex flux.WebExchange, serr *flux.ServeError) map[string]interface{} {
+	application, _ := webex.Variable(internal.ContextKeyRouteApplication).(string)
+	return e.Lookup(application).Render(webex, serr)
+}
+
+func loadErrorEnvelope(config *flux.Configuration, parent *ErrorEnvelope) (*ErrorEnvelope, error) {
+	out := *parent
+	if config.IsSet(ConfigKeyErrorEnvelopeDebug) {
+		out.Debug = config.GetBool(ConfigKeyErrorEnvelopeDebug)
+	}
+	if config.IsSet(ConfigKeyErrorEnvelopeLocale) {
+		out.DefaultLocale = config.GetString(ConfigKeyErrorEnvelopeLocale)
+	}
+	if config.IsSet(ConfigKeyErrorEnvelopeTemplate) {
+		template, err := toEnvelopeTemplate(config.Get(ConfigKeyErrorEnvelopeTemplate))
+		if nil != err {
+			return nil, err
+		}
+		out.Template = template
+	}
+	if config.IsSet(ConfigKeyErrorEnvelopeCodes) {
+		codes := make(map[string]interface{})
+		for k, v := range config.GetStringMap(ConfigKeyErrorEnvelopeCodes) {
+			codes[strings.ToLower(k)] = v
+		}
+		out.Codes = codes
+	}
+	if config.IsSet(ConfigKeyErrorEnvelopeMessages) {
+		messages := make(map[string]map[string]string)
+		for locale, v := range config.GetStringMap(ConfigKeyErrorEnvelopeMessages) {
+			catalog := make(map[string]string)
+			for code, msg := range cast.ToStringMapString(v) {
+				catalog[strings.ToLower(code)] = msg
+			}
+			messages[strings.ToLower(locale)] = catalog
+		}
+		out.Messages = messages
+	}
+	return &out, nil
+}
+
+// toEnvelopeTemplate 解析模板配置：Map结构，或者JSON文本（Map结构的配置键会被转换为小写，需要保留大小写时使用JSON文本）
+func toEnvelopeTemplate(value interface{}) (map[string]interface{}, error) {
+	if text, ok := value.(string); ok {
+		var out map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &out); nil != err {
+			return nil, fmt.Errorf("invalid error envelope template, json: %s, error: %w", text, err)
+		}
+		return out, nil
+	}
+	out, err := cast.ToStringMapE(value)
+	if nil != err {
+		return nil, fmt.Errorf("invalid error envelope template: %+v, error: %w", value, err)
+	}
+	return out, nil
+}
+
+func renderEnvelopeMap(template map[string]interface{}, vars map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(template))
+	for k, v := range template {
+		if rv, ok := renderEnvelopeValue(v, vars); ok {
+			out[k] = rv
+		}
+	}
+	return out
+}
+
+// renderEnvelopeValue 渲染模板值；返回false表示引用的变量不存在，需要移除该字段
+func renderEnvelopeValue(value interface{}, vars map[string]interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		if m := envelopeVarPattern.FindStringSubmatch(v); nil != m && m[0] == v {
+			rv, ok := vars[m[1]]
+			return rv, ok
+		}
+		return envelopeVarPattern.ReplaceAllStringFunc(v, func(expr string) string {
+			if rv, ok := vars[expr[2:len(expr)-1]]; ok {
+				return cast.ToString(rv)
+			}
+			return ""
+		}), true
+	case map[string]interface{}:
+		return renderEnvelopeMap(v, vars), true
+	case map[interface{}]interface{}:
+		return renderEnvelopeMap(cast.ToStringMap(v), vars), true
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if rv, ok := renderEnvelopeValue(item, vars); ok {
+				out = append(out, rv)
+			}
+		}
+		return out, true
+	default:
+		return value, true
+	}
+}
@@ -0,0 +1,97 @@
+package listener
+
+import (
+	"errors"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// envelopeWebExchange 只实现错误响应渲染所需的方法
+type envelopeWebExchange struct {
+	flux.WebExchange
+	headers   map[string]string
+	variables map[string]interface{}
+}
+
+func (w *envelopeWebExchange) RequestId() string {
+	return "req-1"
+}
+
+func (w *envelopeWebExchange) HeaderVar(name string) string {
+	return w.headers[name]
+}
+
+func (w *envelopeWebExchange) Variable(key string) interface{} {
+	return w.variables[key]
+}
+
+func TestErrorEnvelopes_Render(t *testing.T) {
+	config := flux.NewConfigurationOfMap(map[string]interface{}{
+		"default_locale": "en",
+		"codes": map[string]interface{}{
+			flux.ErrorCodeRequestNotFound: 40400,
+		},
+		"messages": map[string]interface{}{
+			"en":    map[string]interface{}{flux.ErrorCodeRequestNotFound: "Not found"},
+			"zh-CN": map[string]interface{}{flux.ErrorCodeRequestNotFound: "资源不存在"},
+		},
+		"applications": map[string]interface{}{
+			"shop": map[string]interface{}{
+				"template": `{"code":"${code}","msg":"${message}","data":null,"traceId":"req:${requestId}"}`,
+			},
+			"debug": map[string]interface{}{
+				"debug": true,
+			},
+		},
+	})
+	envelopes, err := NewErrorEnvelopesWith(config)
+	if !assert2.NoError(t, err) {
+		return
+	}
+	notfound := &flux.ServeError{
+		StatusCode: flux.StatusNotFound,
+		ErrorCode:  flux.ErrorCodeRequestNotFound,
+		Message:    flux.ErrorMessageWebServerRequestNotFound,
+		CauseError: errors.New("internal: no route"),
+	}
+	internalError := &flux.ServeError{
+		StatusCode: flux.StatusServerError,
+		ErrorCode:  flux.ErrorCodeGatewayInternal,
+		Message:    flux.ErrorMessageWebServerInternal,
+		CauseError: errors.New("internal: db down"),
+	}
+	cases := []struct {
+		application    string
+		acceptLanguage string
+		error          *flux.ServeError
+		expected       map[string]interface{}
+	}{
+		{error: notfound, expected: map[string]interface{}{"status": "error", "message": "Not found"}},
+		{error: notfound, acceptLanguage: "zh-CN,zh;q=0.9", expected: map[string]interface{}{"status": "error", "message": "资源不存在"}},
+		{error: notfound, acceptLanguage: "zh-TW", expected: map[string]interface{}{"status": "error", "message": "Not found"}},
+		{error: internalError, expected: map[string]interface{}{"status": "error", "message": flux.ErrorMessageWebServerInternal}},
+		{application: "shop", error: notfound, acceptLanguage: "zh-cn",
+			expected: map[string]interface{}{"code": 40400, "msg": "资源不存在", "data": nil, "traceId": "req:req-1"}},
+		{application: "SHOP", error: internalError,
+			expected: map[string]interface{}{"code": flux.ErrorCodeGatewayInternal, "msg": flux.ErrorMessageWebServerInternal, "data": nil, "traceId": "req:req-1"}},
+		{application: "debug", error: internalError,
+			expected: map[string]interface{}{"status": "error", "message": flux.ErrorMessageWebServerInternal, "error": "internal: db down"}},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		webex := &envelopeWebExchange{
+			headers:   map[string]string{flux.HeaderAcceptLanguage: tcase.acceptLanguage},
+			variables: map[string]interface{}{internal.ContextKeyRouteApplication: tcase.application},
+		}
+		assert.Equal(tcase.expected, envelopes.Render(webex, tcase.error), "application: %s", tcase.application)
+	}
+}
+
+func TestNewErrorEnvelopesWith_InvalidTemplate(t *testing.T) {
+	_, err := NewErrorEnvelopesWith(flux.NewConfigurationOfMap(map[string]interface{}{
+		"template": "{invalid",
+	}))
+	assert2.Error(t, err)
+}
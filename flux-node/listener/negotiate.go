@@ -0,0 +1,98 @@
+package listener
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/internal"
+	"github.com/spf13/cast"
+	"mime"
+	"sort"
+	"strings"
+)
+
+// acceptRange Accept请求头中的单个MediaRange
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// NegotiateMediaType 根据请求Accept和Endpoint定义的响应格式，选择响应的MediaType；
+// produces 为Serializer名称或者MediaType列表，为空时只使用JSON；XML、MessagePack等格式需要Endpoint显式定义；
+// Accept为空或者没有匹配的格式时，返回首个响应格式；
+func NegotiateMediaType(accept string, produces []string) string {
+	if len(produces) == 0 {
+		return flux.MIMEApplicationJSON
+	}
+	candidates := resolveProduces(produces)
+	if len(candidates) == 0 {
+		return flux.MIMEApplicationJSON
+	}
+	for _, ar := range parseAccept(accept) {
+		for _, candidate := range candidates {
+			if matchMediaRange(ar.mediaType, candidate) {
+				return candidate
+			}
+		}
+	}
+	return candidates[0]
+}
+
+// NegotiateSerializer 根据请求的Accept和Endpoint定义的响应格式，选择响应的Serializer和MediaType
+func NegotiateSerializer(webex flux.WebExchange) (flux.Serializer, string, bool) {
+	produces, _ := webex.Variable(internal.ContextKeyResponseProduces).([]string)
+	mediaType := NegotiateMediaType(webex.HeaderVar(flux.HeaderAccept), produces)
+	serializer, ok := ext.SerializerByMediaType(mediaType)
+	return serializer, mediaType, ok
+}
+
+// resolveProduces 将Serializer名称转换为MediaType，忽略未注册Serializer的格式
+func resolveProduces(produces []string) []string {
+	out := make([]string, 0, len(produces))
+	for _, v := range produces {
+		if strings.Contains(v, "/") {
+			v = strings.ToLower(v)
+			if _, ok := ext.SerializerByMediaType(v); ok {
+				out = append(out, v)
+			}
+		} else if mt, ok := ext.MediaTypeOfSerializer(v); ok {
+			out = append(out, mt)
+		}
+	}
+	return out
+}
+
+// parseAccept 解析Accept请求头，按q值从高到低排序；忽略q=0的MediaRange
+func parseAccept(accept string) []acceptRange {
+	if "" == accept {
+		return nil
+	}
+	out := make([]acceptRange, 0, 4)
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if nil != err {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality = cast.ToFloat64(q)
+		}
+		if quality <= 0 {
+			continue
+		}
+		out = append(out, acceptRange{mediaType: mt, quality: quality})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].quality > out[j].quality
+	})
+	return out
+}
+
+func matchMediaRange(mediaRange, mediaType string) bool {
+	if "*/*" == mediaRange || mediaRange == mediaType {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package listener
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	fxserializer "github.com/bytepowered/flux/flux-node/serializer"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	ext.RegisterSerializer(ext.TypeNameSerializerXml, fxserializer.NewXmlSerializer())
+	ext.RegisterSerializer(ext.TypeNameSerializerMsgPack, fxserializer.NewMsgPackSerializer())
+	ext.RegisterSerializer(ext.TypeNameSerializerProtoJson, fxserializer.NewProtoJsonSerializer())
+	ext.RegisterMediaTypeSerializer(flux.MIMEApplicationJSON, ext.TypeNameSerializerJson)
+	ext.RegisterMediaTypeSerializer(flux.MIMEApplicationXML, ext.TypeNameSerializerXml)
+	ext.RegisterMediaTypeSerializer(flux.MIMETextXML, ext.TypeNameSerializerXml)
+	ext.RegisterMediaTypeSerializer(flux.MIMEApplicationMsgPack, ext.TypeNameSerializerMsgPack)
+	ext.RegisterMediaTypeSerializer(flux.MIMEApplicationProtoJSON, ext.TypeNameSerializerProtoJson)
+}
+
+func TestNegotiateMediaType(t *testing.T) {
+	cases := []struct {
+		accept   string
+		produces []string
+		expected string
+	}{
+		// Endpoint未定义响应格式时，只使用JSON
+		{accept: "", expected: flux.MIMEApplicationJSON},
+		{accept: "*/*", expected: flux.MIMEApplicationJSON},
+		{accept: "application/xml", expected: flux.MIMEApplicationJSON},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", expected: flux.MIMEApplicationJSON},
+		// Endpoint定义响应格式
+		{accept: "text/*", produces: []string{"json", "text/xml"}, expected: flux.MIMETextXML},
+		{accept: "application/xml;q=0.5, application/msgpack", produces: []string{"json", "xml", "msgpack"}, expected: flux.MIMEApplicationMsgPack},
+		{accept: "application/msgpack;q=0, */*;q=0.1", produces: []string{"json", "msgpack"}, expected: flux.MIMEApplicationJSON},
+		{accept: "text/html", produces: []string{"json", "xml"}, expected: flux.MIMEApplicationJSON},
+		{accept: "", produces: []string{"xml", "json"}, expected: flux.MIMEApplicationXML},
+		{accept: "application/json", produces: []string{"xml", "json"}, expected: flux.MIMEApplicationJSON},
+		{accept: "application/msgpack", produces: []string{"xml"}, expected: flux.MIMEApplicationXML},
+		{accept: "text/xml", produces: []string{"TEXT/XML"}, expected: flux.MIMETextXML},
+		{accept: "application/x-protobuf+json", produces: []string{"json", "protojson"}, expected: flux.MIMEApplicationProtoJSON},
+		{accept: "", produces: []string{"yaml"}, expected: flux.MIMEApplicationJSON},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		assert.Equal(tcase.expected, NegotiateMediaType(tcase.accept, tcase.produces),
+			"accept: %s, produces: %v", tcase.accept, tcase.produces)
+	}
+}
@@ -15,7 +15,7 @@ func New(id string, config *flux.Configuration, wis []flux.WebInterceptor, opts
 	opts = append([]Option{
 		WithErrorHandler(DefaultErrorHandler),
 		WithNotfoundHandler(DefaultNotfoundHandler),
-		WithResponseWriter(new(DefaultResponseWriter)),
+		WithResponseWriter(NewDefaultResponseWriterWith(id, config.Sub(ConfigKeyErrorEnvelope))),
 		WithInterceptors(wis),
 	}, opts...)
 	return NewWith(id, config, opts...)
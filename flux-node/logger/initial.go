@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"os"
 	"reflect"
 	"runtime"
@@ -69,7 +70,14 @@ func LoadConfig(logfile string) (zap.Config, error) {
 }
 
 func NewZapLogger(config zap.Config) *zap.SugaredLogger {
-	zLogger, err := config.Build()
+	// 日志级别由运行时配置控制：配置的级别作为全局级别，原始Core开启全部级别
+	if config.Level != (zap.AtomicLevel{}) {
+		levels.update(func(s *levelState) {
+			s.global = config.Level.Level()
+		})
+	}
+	config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	zLogger, err := config.Build(wrapLevelCore())
 	if nil != err {
 		panic(err)
 	}
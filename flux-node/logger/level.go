@@ -0,0 +1,283 @@
+package logger
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 按请求ID开启调试日志
+	DebugKeyRequestId = "request-id"
+	// 按Application开启调试日志
+	DebugKeyApplication = "application"
+)
+
+const (
+	fieldKeyAppId = "appid"
+)
+
+var (
+	levels = newLevelController(zapcore.InfoLevel)
+)
+
+type (
+	// ComponentLevel 组件日志级别；组件由日志消息前缀标识，例如：BACKEND:DUBBO，DISCOVERY:ZOOKEEPER
+	ComponentLevel struct {
+		Component string `json:"component"`
+		Level     string `json:"level"`
+	}
+	// DebugWindow 在指定时间窗口内，对指定请求ID或者Application开启调试日志
+	DebugWindow struct {
+		Key      string    `json:"key"`
+		Value    string    `json:"value"`
+		ExpireAt time.Time `json:"expireAt"`
+	}
+)
+
+type levelState struct {
+	global     zapcore.Level
+	minimal    zapcore.Level
+	components []componentLevel
+	windows    map[string]time.Time
+}
+
+type componentLevel struct {
+	prefix string
+	level  zapcore.Level
+}
+
+// levelController 管理运行时日志级别；状态采用写时复制，读取无锁；
+type levelController struct {
+	state atomic.Value
+	mutex sync.Mutex
+}
+
+func newLevelController(global zapcore.Level) *levelController {
+	c := new(levelController)
+	c.state.Store(&levelState{global: global, minimal: global, windows: map[string]time.Time{}})
+	return c
+}
+
+func (c *levelController) load() *levelState {
+	return c.state.Load().(*levelState)
+}
+
+func (c *levelController) update(f func(s *levelState)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	old := c.load()
+	next := &levelState{
+		global:     old.global,
+		components: make([]componentLevel, len(old.components)),
+		windows:    make(map[string]time.Time, len(old.windows)),
+	}
+	copy(next.components, old.components)
+	now := time.Now()
+	for k, v := range old.windows {
+		if now.Before(v) {
+			next.windows[k] = v
+		}
+	}
+	f(next)
+	// 前缀最长的组件优先匹配
+	sort.SliceStable(next.components, func(i, j int) bool {
+		return len(next.components[i].prefix) > len(next.components[j].prefix)
+	})
+	next.minimal = next.global
+	for _, cl := range next.components {
+		if cl.level < next.minimal {
+			next.minimal = cl.level
+		}
+	}
+	if len(next.windows) > 0 {
+		next.minimal = zapcore.DebugLevel
+	}
+	c.state.Store(next)
+}
+
+func (c *levelController) enabled(level zapcore.Level) bool {
+	return level >= c.load().minimal
+}
+
+func (c *levelController) check(ent zapcore.Entry, traceId, appId string) bool {
+	s := c.load()
+	if len(s.windows) > 0 {
+		now := time.Now()
+		if "" != traceId {
+			if exp, ok := s.windows[debugKeyOf(DebugKeyRequestId, traceId)]; ok && now.Before(exp) {
+				return true
+			}
+		}
+		if "" != appId {
+			if exp, ok := s.windows[debugKeyOf(DebugKeyApplication, appId)]; ok && now.Before(exp) {
+				return true
+			}
+		}
+	}
+	for _, cl := range s.components {
+		if strings.HasPrefix(ent.Message, cl.prefix) {
+			return ent.Level >= cl.level
+		}
+	}
+	return ent.Level >= s.global
+}
+
+// SetLevel 设置全局日志级别
+func SetLevel(level string) error {
+	l, err := parseLevel(level)
+	if nil != err {
+		return err
+	}
+	levels.update(func(s *levelState) {
+		s.global = l
+	})
+	return nil
+}
+
+// GetLevel 返回全局日志级别
+func GetLevel() string {
+	return levels.load().global.String()
+}
+
+// SetComponentLevel 设置组件日志级别；组件为日志消息的前缀，例如：BACKEND:DUBBO
+func SetComponentLevel(component, level string) error {
+	if "" == component {
+		return fmt.Errorf("logger component is empty")
+	}
+	l, err := parseLevel(level)
+	if nil != err {
+		return err
+	}
+	levels.update(func(s *levelState) {
+		for i := range s.components {
+			if s.components[i].prefix == component {
+				s.components[i].level = l
+				return
+			}
+		}
+		s.components = append(s.components, componentLevel{prefix: component, level: l})
+	})
+	return nil
+}
+
+// RemoveComponentLevel 删除组件日志级别，组件日志恢复使用全局日志级别
+func RemoveComponentLevel(component string) {
+	levels.update(func(s *levelState) {
+		for i := range s.components {
+			if s.components[i].prefix == component {
+				s.components = append(s.components[:i], s.components[i+1:]...)
+				return
+			}
+		}
+	})
+}
+
+// ComponentLevels 返回全部组件日志级别
+func ComponentLevels() []ComponentLevel {
+	s := levels.load()
+	out := make([]ComponentLevel, len(s.components))
+	for i, cl := range s.components {
+		out[i] = ComponentLevel{Component: cl.prefix, Level: cl.level.String()}
+	}
+	return out
+}
+
+// EnableDebug 在指定时间窗口内，对指定请求ID或者Application开启调试日志；
+// key 可选：request-id，application；
+func EnableDebug(key, value string, duration time.Duration) error {
+	if key != DebugKeyRequestId && key != DebugKeyApplication {
+		return fmt.Errorf("unsupported debug key: %s", key)
+	}
+	if "" == value {
+		return fmt.Errorf("debug value is empty, key: %s", key)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("debug duration must be positive, was: %s", duration)
+	}
+	levels.update(func(s *levelState) {
+		s.windows[debugKeyOf(key, value)] = time.Now().Add(duration)
+	})
+	return nil
+}
+
+// DisableDebug 关闭指定请求ID或者Application的调试日志
+func DisableDebug(key, value string) {
+	levels.update(func(s *levelState) {
+		delete(s.windows, debugKeyOf(key, value))
+	})
+}
+
+// DebugWindows 返回全部生效中的调试日志窗口
+func DebugWindows() []DebugWindow {
+	s := levels.load()
+	now := time.Now()
+	out := make([]DebugWindow, 0, len(s.windows))
+	for k, exp := range s.windows {
+		if now.After(exp) {
+			continue
+		}
+		kv := strings.SplitN(k, "=", 2)
+		out = append(out, DebugWindow{Key: kv[0], Value: kv[1], ExpireAt: exp})
+	}
+	return out
+}
+
+func debugKeyOf(key, value string) string {
+	return key + "=" + value
+}
+
+func parseLevel(level string) (zapcore.Level, error) {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(level))); nil != err {
+		return l, fmt.Errorf("invalid logger level: %s", level)
+	}
+	return l, nil
+}
+
+// 根据运行时日志级别配置，过滤日志的Core
+type levelCore struct {
+	zapcore.Core
+	traceId string
+	appId   string
+}
+
+// wrapLevelCore 包装Core，使其日志级别由运行时配置控制
+func wrapLevelCore() zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &levelCore{Core: core}
+	})
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return levels.enabled(level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &levelCore{Core: c.Core.With(fields), traceId: c.traceId, appId: c.appId}
+	for _, f := range fields {
+		if f.Type != zapcore.StringType {
+			continue
+		}
+		switch f.Key {
+		case TraceId:
+			clone.traceId = f.String
+		case fieldKeyAppId:
+			clone.appId = f.String
+		}
+	}
+	return clone
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if levels.check(ent, c.traceId, c.appId) {
+		// 交由原始Core检查，保留其采样等特性
+		return c.Core.Check(ent, ce)
+	}
+	return ce
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"github.com/bytepowered/flux/flux-node/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+	"time"
+)
+
+func TestLevelCore(t *testing.T) {
+	assert := assert2.New(t)
+	defer func() {
+		levels = newLevelController(zapcore.InfoLevel)
+	}()
+	levels = newLevelController(zapcore.InfoLevel)
+	core, logs := observer.New(zapcore.DebugLevel)
+	sugar := zap.New(core, wrapLevelCore()).Sugar()
+	// Global
+	sugar.Debugw("SERVER:ROUTE:START")
+	sugar.Infow("SERVER:ROUTE:START")
+	assert.Equal(1, len(logs.TakeAll()))
+	assert.NoError(SetLevel("debug"))
+	sugar.Debugw("SERVER:ROUTE:START")
+	assert.Equal(1, len(logs.TakeAll()))
+	assert.Error(SetLevel("bad"))
+	assert.NoError(SetLevel("warn"))
+	// Component
+	assert.NoError(SetComponentLevel("BACKEND:DUBBO", "debug"))
+	assert.NoError(SetComponentLevel("BACKEND:DUBBO:RPC", "error"))
+	sugar.Debugw("BACKEND:DUBBO:INVOKE")
+	sugar.Warnw("BACKEND:DUBBO:RPC_ERROR")
+	sugar.Infow("DISCOVERY:ZOOKEEPER:META:WATCH")
+	assert.Equal(1, len(logs.TakeAll()))
+	assert.Equal(2, len(ComponentLevels()))
+	RemoveComponentLevel("BACKEND:DUBBO")
+	RemoveComponentLevel("BACKEND:DUBBO:RPC")
+	assert.Equal(0, len(ComponentLevels()))
+	// Debug window
+	assert.NoError(EnableDebug(DebugKeyRequestId, "rid-1", time.Minute))
+	assert.NoError(EnableDebug(DebugKeyApplication, "app-1", time.Minute))
+	assert.Error(EnableDebug("unknown", "x", time.Minute))
+	// 通过生产环境使用的Factory创建请求日志，appid 由 Extras 字段附加
+	ext.SetLoggerFactory(SugaredFactory(sugar))
+	defer ext.SetLoggerFactory(DefaultFactory)
+	Trace("rid-1").Debugw("SERVER:ROUTE:START")
+	TraceExtras("rid-2", map[string]string{"appid": "app-1"}).Debugw("SERVER:ROUTE:START")
+	TraceExtras("rid-3", map[string]string{"appid": "app-2"}).Debugw("SERVER:ROUTE:START")
+	assert.Equal(2, len(logs.TakeAll()))
+	assert.Equal(2, len(DebugWindows()))
+	DisableDebug(DebugKeyRequestId, "rid-1")
+	Trace("rid-1").Debugw("SERVER:ROUTE:START")
+	assert.Equal(0, len(logs.TakeAll()))
+}
@@ -5,6 +5,7 @@ import (
 	_ "github.com/bytepowered/flux/flux-node/backend/dubbo"
 	_ "github.com/bytepowered/flux/flux-node/backend/echo"
 	_ "github.com/bytepowered/flux/flux-node/backend/http"
+	_ "github.com/bytepowered/flux/flux-node/backend/mock"
 	"github.com/bytepowered/flux/flux-node/boot"
 	_ "github.com/bytepowered/flux/flux-node/echoserver"
 )
@@ -4,6 +4,7 @@ import (
 	"github.com/spf13/cast"
 	"strings"
 	"sync"
+	"time"
 )
 
 type (
@@ -63,6 +64,7 @@ const (
 	ProtoGRPC  = "GRPC"
 	ProtoHttp  = "HTTP"
 	ProtoEcho  = "ECHO"
+	ProtoMock  = "MOCK"
 )
 
 // ServiceAttributes
@@ -77,10 +79,14 @@ const (
 
 // EndpointAttributes
 const (
-	EndpointAttrTagNotDefined = ""          // 默认的，未定义的属性
-	EndpointAttrTagAuthorize  = "authorize" // 标识Endpoint访问是否需要授权
-	EndpointAttrTagServerId   = "serverid"  // 标识Endpoint绑定到哪个ListenServer服务
-	EndpointAttrTagBizId      = "bizid"     // 标识Endpoint绑定到业务标识
+	EndpointAttrTagNotDefined    = ""              // 默认的，未定义的属性
+	EndpointAttrTagAuthorize     = "authorize"     // 标识Endpoint访问是否需要授权
+	EndpointAttrTagServerId      = "serverid"      // 标识Endpoint绑定到哪个ListenServer服务
+	EndpointAttrTagBizId         = "bizid"         // 标识Endpoint绑定到业务标识
+	EndpointAttrTagWebSocket     = "websocket"     // 标识Endpoint为WebSocket代理，握手后双向转发消息帧
+	EndpointAttrTagStream        = "stream"        // 标识Endpoint的分块传输（chunked）响应按流式响应透传；未定义时chunked响应按普通响应处理
+	EndpointAttrTagStreamTimeout = "streamtimeout" // 流式响应（SSE、chunked）的最大持续时间
+	EndpointAttrTagProduces      = "produces"      // 响应支持的格式（Serializer名称或MediaType），首个为默认格式
 )
 
 type (
@@ -276,6 +282,31 @@ func (e Endpoint) AttrAuthorize() bool {
 	return e.GetAttr(EndpointAttrTagAuthorize).GetBool()
 }
 
+func (e Endpoint) AttrWebSocket() bool {
+	return e.GetAttr(EndpointAttrTagWebSocket).GetBool()
+}
+
+func (e Endpoint) AttrStream() bool {
+	return e.GetAttr(EndpointAttrTagStream).GetBool()
+}
+
+func (e Endpoint) AttrStreamTimeout() time.Duration {
+	return cast.ToDuration(e.GetAttr(EndpointAttrTagStreamTimeout).Value)
+}
+
+// AttrProduces 返回响应支持的格式列表；属性可重复定义，或者使用逗号分隔多个格式
+func (e Endpoint) AttrProduces() []string {
+	out := make([]string, 0, 2)
+	for _, attr := range e.GetAttrs(EndpointAttrTagProduces) {
+		for _, v := range strings.Split(attr.GetString(), ",") {
+			if v = strings.TrimSpace(v); "" != v {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
 // Multi version Endpoint
 type MultiEndpoint struct {
 	endpoint      map[string]*Endpoint // 各版本数据
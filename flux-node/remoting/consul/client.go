@@ -0,0 +1,154 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerConsulIndex = "X-Consul-Index"
+	headerConsulToken = "X-Consul-Token"
+)
+
+// KVPair Consul KV数据项
+type KVPair struct {
+	Key         string `json:"Key"`
+	Value       []byte `json:"Value"`
+	CreateIndex uint64 `json:"CreateIndex"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+// ServiceEntry Consul健康检查接口返回的服务实例
+type ServiceEntry struct {
+	Node struct {
+		Node    string `json:"Node"`
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		ID      string `json:"ID"`
+		Service string `json:"Service"`
+		Address string `json:"Address"`
+		Port    int    `json:"Port"`
+	} `json:"Service"`
+}
+
+// HostAddress 返回服务实例的访问地址；服务未指定地址时，使用节点地址；
+func (e ServiceEntry) HostAddress() string {
+	addr := e.Service.Address
+	if "" == addr {
+		addr = e.Node.Address
+	}
+	return addr + ":" + strconv.Itoa(e.Service.Port)
+}
+
+// QueryOptions 阻塞查询参数
+type QueryOptions struct {
+	// WaitIndex 上次查询返回的Index；为0时立即返回
+	WaitIndex uint64
+	// WaitTime 阻塞查询的最长等待时间
+	WaitTime time.Duration
+}
+
+// ClientConfig Consul客户端配置
+type ClientConfig struct {
+	Address    string
+	Scheme     string
+	Token      string
+	Datacenter string
+}
+
+// Client 基于Consul HTTP API的轻量客户端，支持KV和健康检查的阻塞查询
+type Client struct {
+	config ClientConfig
+	http   *http.Client
+}
+
+func NewClient(config ClientConfig) *Client {
+	if "" == config.Scheme {
+		config.Scheme = "http"
+	}
+	return &Client{
+		config: config,
+		http:   &http.Client{},
+	}
+}
+
+// KVList 查询指定前缀的全部KV数据；前缀不存在时返回空列表；
+func (c *Client) KVList(ctx context.Context, prefix string, opts QueryOptions) ([]KVPair, uint64, error) {
+	query := url.Values{}
+	query.Set("recurse", "true")
+	out := make([]KVPair, 0)
+	index, err := c.query(ctx, "/v1/kv/"+strings.TrimPrefix(prefix, "/"), query, opts, &out)
+	return out, index, err
+}
+
+// HealthService 查询指定服务的实例列表；passing 为true时只返回健康检查通过的实例；
+func (c *Client) HealthService(ctx context.Context, service string, passing bool, opts QueryOptions) ([]ServiceEntry, uint64, error) {
+	query := url.Values{}
+	if passing {
+		query.Set("passing", "true")
+	}
+	out := make([]ServiceEntry, 0)
+	index, err := c.query(ctx, "/v1/health/service/"+url.PathEscape(service), query, opts, &out)
+	return out, index, err
+}
+
+func (c *Client) query(ctx context.Context, path string, query url.Values, opts QueryOptions, out interface{}) (uint64, error) {
+	if "" != c.config.Datacenter {
+		query.Set("dc", c.config.Datacenter)
+	}
+	if opts.WaitIndex > 0 {
+		query.Set("index", strconv.FormatUint(opts.WaitIndex, 10))
+		if opts.WaitTime > 0 {
+			query.Set("wait", strconv.FormatInt(opts.WaitTime.Milliseconds(), 10)+"ms")
+		}
+	}
+	u := url.URL{Scheme: c.config.Scheme, Host: c.config.Address, Path: path, RawQuery: query.Encode()}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if nil != err {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	if "" != c.config.Token {
+		req.Header.Set(headerConsulToken, c.config.Token)
+	}
+	resp, err := c.http.Do(req)
+	if nil != err {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	index, _ := strconv.ParseUint(resp.Header.Get(headerConsulIndex), 10, 64)
+	// KV前缀不存在
+	if resp.StatusCode == http.StatusNotFound {
+		return index, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("consul query failed, path: %s, status: %d, body: %s", path, resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, out); nil != err {
+		return 0, fmt.Errorf("consul decode response, path: %s, error: %w", path, err)
+	}
+	return index, nil
+}
+
+// NextIndex 根据Consul阻塞查询的约定计算下次查询的Index：Index回退时重置为0，并保证Index大于0；
+func NextIndex(last, index uint64) uint64 {
+	if index < last {
+		return 0
+	}
+	if index == 0 {
+		return 1
+	}
+	return index
+}
@@ -0,0 +1,219 @@
+package nacos
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultGroup       = "DEFAULT_GROUP"
+	DefaultContextPath = "/nacos"
+)
+
+const (
+	// Nacos长轮询协议的分隔符
+	wordSeparator = "\x02"
+	lineSeparator = "\x01"
+)
+
+// ClientConfig Nacos客户端配置
+type ClientConfig struct {
+	// Address 服务地址，格式：host:port
+	Address     string
+	Scheme      string
+	ContextPath string
+	// Namespace 命名空间ID，即Nacos API的tenant参数
+	Namespace string
+	Username  string
+	Password  string
+}
+
+// ListenItem 长轮询监听的配置项
+type ListenItem struct {
+	DataId string
+	Group  string
+	// MD5 客户端当前配置内容的MD5；配置不存在时为空
+	MD5 string
+}
+
+// Client 基于Nacos Open API的轻量配置中心客户端，支持鉴权、读取配置和长轮询监听
+type Client struct {
+	config   ClientConfig
+	http     *http.Client
+	token    string
+	expireAt time.Time
+	mutex    sync.Mutex
+}
+
+func NewClient(config ClientConfig) *Client {
+	if "" == config.Scheme {
+		config.Scheme = "http"
+	}
+	if "" == config.ContextPath {
+		config.ContextPath = DefaultContextPath
+	}
+	return &Client{
+		config: config,
+		http:   &http.Client{},
+	}
+}
+
+// GetConfig 读取配置内容；配置不存在时，返回found为false；
+func (c *Client) GetConfig(ctx context.Context, dataId, group string) (content string, found bool, err error) {
+	query := url.Values{}
+	query.Set("dataId", dataId)
+	query.Set("group", group)
+	if "" != c.config.Namespace {
+		query.Set("tenant", c.config.Namespace)
+	}
+	if err := c.authorize(ctx, query); nil != err {
+		return "", false, err
+	}
+	req, err := http.NewRequest(http.MethodGet, c.urlOf("/v1/cs/configs", query), nil)
+	if nil != err {
+		return "", false, err
+	}
+	status, body, err := c.do(ctx, req)
+	if nil != err {
+		return "", false, err
+	}
+	switch status {
+	case http.StatusOK:
+		return string(body), true, nil
+	case http.StatusNotFound:
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("nacos get config failed, data-id: %s, status: %d, body: %s", dataId, status, string(body))
+	}
+}
+
+// ListenConfigs 长轮询监听配置变化，返回内容已变化的DataId列表；在超时时间内没有变化时返回空列表；
+func (c *Client) ListenConfigs(ctx context.Context, items []ListenItem, timeout time.Duration) ([]string, error) {
+	query := url.Values{}
+	if err := c.authorize(ctx, query); nil != err {
+		return nil, err
+	}
+	var lines strings.Builder
+	for _, item := range items {
+		lines.WriteString(item.DataId + wordSeparator + item.Group + wordSeparator + item.MD5)
+		if "" != c.config.Namespace {
+			lines.WriteString(wordSeparator + c.config.Namespace)
+		}
+		lines.WriteString(lineSeparator)
+	}
+	form := url.Values{}
+	form.Set("Listening-Configs", lines.String())
+	req, err := http.NewRequest(http.MethodPost, c.urlOf("/v1/cs/configs/listener", query), strings.NewReader(form.Encode()))
+	if nil != err {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Long-Pulling-Timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+	status, body, err := c.do(ctx, req)
+	if nil != err {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("nacos listen configs failed, status: %d, body: %s", status, string(body))
+	}
+	return ParseChangedDataIds(string(body)), nil
+}
+
+// authorize 配置了用户名时，登录获取AccessToken，并在过期前复用
+func (c *Client) authorize(ctx context.Context, query url.Values) error {
+	if "" == c.config.Username {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if "" == c.token || time.Now().After(c.expireAt) {
+		form := url.Values{}
+		form.Set("username", c.config.Username)
+		form.Set("password", c.config.Password)
+		req, err := http.NewRequest(http.MethodPost, c.urlOf("/v1/auth/login", url.Values{}), strings.NewReader(form.Encode()))
+		if nil != err {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		status, body, err := c.do(ctx, req)
+		if nil != err {
+			return err
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("nacos login failed, username: %s, status: %d", c.config.Username, status)
+		}
+		var out struct {
+			AccessToken string `json:"accessToken"`
+			TokenTtl    int64  `json:"tokenTtl"`
+		}
+		if err := json.Unmarshal(body, &out); nil != err {
+			return fmt.Errorf("nacos decode login response, error: %w", err)
+		}
+		c.token = out.AccessToken
+		// 提前刷新，避免Token在请求过程中过期
+		c.expireAt = time.Now().Add(time.Duration(out.TokenTtl) * time.Second * 9 / 10)
+	}
+	query.Set("accessToken", c.token)
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request) (int, []byte, error) {
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if nil != err {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+func (c *Client) urlOf(path string, query url.Values) string {
+	u := url.URL{
+		Scheme:   c.config.Scheme,
+		Host:     c.config.Address,
+		Path:     strings.TrimSuffix(c.config.ContextPath, "/") + path,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// ParseChangedDataIds 解析长轮询的响应：URL编码的 dataId^2group^2tenant^1 列表
+func ParseChangedDataIds(body string) []string {
+	body = strings.TrimSpace(body)
+	if "" == body {
+		return nil
+	}
+	if decoded, err := url.QueryUnescape(body); nil == err {
+		body = decoded
+	}
+	out := make([]string, 0, 4)
+	for _, line := range strings.Split(body, lineSeparator) {
+		if "" == line {
+			continue
+		}
+		out = append(out, strings.Split(line, wordSeparator)[0])
+	}
+	return out
+}
+
+// ContentMD5 计算配置内容的MD5；内容为空时返回空字符串
+func ContentMD5(content string) string {
+	if "" == content {
+		return ""
+	}
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -13,6 +13,7 @@ import (
 	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,9 +22,12 @@ func NewZookeeperRetriever(id string) *ZookeeperRetriever {
 		Id:          id,
 		listenerMap: make(map[string][]remoting.NodeChangedListener),
 		quit:        make(chan struct{}),
+		reconnect:   make(chan struct{}),
 	}
 }
 
+// RetrieverConfig ZK客户端配置；
+// RetryMax 为连续重试的最大次数，超过后等待会话重新建立；小于等于0表示不限制；
 type RetrieverConfig struct {
 	ConnTimeout time.Duration
 	RetryMax    int
@@ -36,8 +40,11 @@ type ZookeeperRetriever struct {
 	listenerMap map[string][]remoting.NodeChangedListener
 	listenerMu  sync.RWMutex
 	quit        chan struct{}
+	reconnect   chan struct{}
+	reconnectMu sync.RWMutex
 	address     []string
 	config      RetrieverConfig
+	connected   int32
 }
 
 // Init 初始化
@@ -54,6 +61,8 @@ func (r *ZookeeperRetriever) Init(config *flux.Configuration) error {
 	})
 	r.config = RetrieverConfig{
 		ConnTimeout: config.GetDuration("timeout"),
+		RetryMax:    config.GetInt("retry-max"),
+		RetryDelay:  config.GetDuration("retry-delay"),
 	}
 	return nil
 }
@@ -61,13 +70,14 @@ func (r *ZookeeperRetriever) Init(config *flux.Configuration) error {
 // Startup 启动ZK客户端
 func (r *ZookeeperRetriever) Startup() error {
 	r.newLogger().Info("Zookeeper retriever startup")
-	conn, _, err := zk.Connect(r.address, r.config.ConnTimeout,
+	conn, events, err := zk.Connect(r.address, r.config.ConnTimeout,
 		zk.WithLogger(new(zkLogger)),
 	)
 	if err != nil {
 		return fmt.Errorf("zookeeper connection failed, id: %s, address: %s, err: %w", r.Id, r.address, err)
 	}
 	r.conn = conn
+	go r.watchConnState(events)
 	return nil
 }
 
@@ -79,10 +89,18 @@ func (r *ZookeeperRetriever) Shutdown(ctx context.Context) error {
 	default:
 		r.newLogger().Info("Zookeeper retriever shutdown")
 		close(r.quit)
+		if nil != r.conn {
+			r.conn.Close()
+		}
 	}
 	return nil
 }
 
+// Connected 返回当前是否已与ZK建立会话
+func (r *ZookeeperRetriever) Connected() bool {
+	return atomic.LoadInt32(&r.connected) == 1
+}
+
 // Exists 判定指定Path是否存在。注意Path是完整路径。
 func (r *ZookeeperRetriever) Exists(path string) (bool, error) {
 	b, _, err := r.conn.Exists(path)
@@ -95,6 +113,18 @@ func (r *ZookeeperRetriever) Create(path string) error {
 	return err
 }
 
+// Children 返回指定Path的子节点完整路径列表
+func (r *ZookeeperRetriever) Children(parentNodePath string) ([]string, error) {
+	children, _, err := r.conn.Children(parentNodePath)
+	if nil != err {
+		return nil, err
+	}
+	for i, p := range children {
+		children[i] = path.Join(parentNodePath, p)
+	}
+	return children, nil
+}
+
 func (r *ZookeeperRetriever) AddChildrenNodeChangedListener(groupId, parentNodePath string, nodeChangedListener remoting.NodeChangedListener) error {
 	if init, err := r.setupListener(groupId, parentNodePath, nodeChangedListener); nil != err {
 		return err
@@ -119,21 +149,10 @@ func (r *ZookeeperRetriever) watchChildrenChanged(parentNodePath string) {
 	defer func() {
 		r.newLogger().Infow("Zookeeper retriever stop watching children, purge listeners",
 			"parent-path", parentNodePath)
-		r.listenerMu.Lock()
-		delete(r.listenerMap, parentNodePath)
-		r.listenerMu.Unlock()
+		r.purgeListeners(parentNodePath)
 	}()
-	handleChildChanged := func(event remoting.NodeEvent) {
-		r.listenerMu.RLock()
-		listeners, ok := r.listenerMap[parentNodePath]
-		r.listenerMu.RUnlock()
-		if !ok || 0 == len(listeners) {
-			return
-		}
-		for _, listener := range listeners {
-			listener(event)
-		}
-	}
+	// 缓存已知的子节点列表；每次重新监听时，与实时状态对比，生成新增和删除事件；
+	// 会话过期重连后，通过对比补偿在断线期间丢失的事件；
 	cachedChildren := make([]string, 0)
 	retries := 0
 	for {
@@ -141,67 +160,39 @@ func (r *ZookeeperRetriever) watchChildrenChanged(parentNodePath string) {
 		if nil != err {
 			r.newLogger().Infow("Zookeeper retriever watching children",
 				"parent-path", parentNodePath, "error", err)
-			// retry
-			select {
-			case <-r.quit:
-				return
-			case <-time.After(r.config.RetryDelay):
-				retries++
-				r.newLogger().Infow("Zookeeper retriever retry watch children",
-					"parent-path", parentNodePath, "retry", retries)
-				if retries > r.config.RetryMax {
-					return
-				} else {
-					continue
-				}
+			if r.awaitRetry(&retries, parentNodePath) {
+				continue
 			}
+			return
 		}
-		// New: notify
-		if len(newChildren) > 0 && len(cachedChildren) == 0 {
-			for _, p := range newChildren {
-				newChild := path.Join(parentNodePath, p)
-				cachedChildren = append(cachedChildren, newChild)
-				handleChildChanged(remoting.NodeEvent{
-					Path:      newChild,
-					EventType: remoting.EventTypeChildAdd,
-				})
-			}
+		retries = 0
+		for i, p := range newChildren {
+			newChildren[i] = path.Join(parentNodePath, p) // Update full path
 		}
-
+		added, deleted := DiffChildren(cachedChildren, newChildren)
+		for _, p := range added {
+			r.notifyListeners(parentNodePath, remoting.NodeEvent{
+				Path:      p,
+				EventType: remoting.EventTypeChildAdd,
+			})
+		}
+		for _, p := range deleted {
+			r.notifyListeners(parentNodePath, remoting.NodeEvent{
+				Path:      p,
+				EventType: remoting.EventTypeChildDelete,
+			})
+		}
+		cachedChildren = newChildren
 		select {
 		case <-r.quit:
 			return
 
 		case zkEvent := <-w.EvtCh:
+			// 任何事件（包括会话过期导致的监听失效）都重新监听，并对比子节点状态
 			r.newLogger().Debugw("Zookeeper retriever receive event", "event", zkEvent)
-			if zkEvent.Type == zk.EventNodeChildrenChanged {
-				newChildren, _, err := r.conn.Children(zkEvent.Path)
-				if nil != err {
-					r.newLogger().Errorw("Zookeeper retriever get children data",
-						"parent-path", parentNodePath, "error", err)
-					return
-				}
-				// Add
-				for i, p := range newChildren {
-					newChildren[i] = path.Join(parentNodePath, p) // Update full path
-					if !fluxpkg.StringSliceContains(cachedChildren, newChildren[i]) {
-						handleChildChanged(remoting.NodeEvent{
-							Path:      newChildren[i],
-							EventType: remoting.EventTypeChildAdd,
-						})
-					}
-				}
-				// Deleted
-				for _, p := range cachedChildren {
-					if !fluxpkg.StringSliceContains(newChildren, p) {
-						handleChildChanged(remoting.NodeEvent{
-							Path:      p,
-							EventType: remoting.EventTypeChildDelete,
-						})
-					}
-				}
-				cachedChildren = newChildren
-			}
+
+		case <-r.reconnectSignal():
+			r.newLogger().Infow("Zookeeper retriever resync children after reconnected", "parent-path", parentNodePath)
 		}
 	}
 }
@@ -211,24 +202,37 @@ func (r *ZookeeperRetriever) watchDataNodeChanged(nodePath string) {
 	defer func() {
 		r.newLogger().Infow("Zookeeper retriever stop watching node data, purge listeners",
 			"node-path", nodePath)
-		r.listenerMu.Lock()
-		delete(r.listenerMap, nodePath)
-		r.listenerMu.Unlock()
+		r.purgeListeners(nodePath)
 	}()
-	inited := false
+	// 缓存节点状态；每次重新监听时，与实时状态对比，生成新增、更新和删除事件；
+	cached := new(NodeData)
+	retries := 0
 	for {
-		_, _, w, err := r.conn.ExistsW(nodePath)
+		exists, stat, w, err := r.conn.ExistsW(nodePath)
 		if nil != err {
-			r.newLogger().Errorw("Zookeeper retriever watching node data", "node-path", nodePath, "error", err)
+			r.newLogger().Infow("Zookeeper retriever watching node data", "node-path", nodePath, "error", err)
+			if r.awaitRetry(&retries, nodePath) {
+				continue
+			}
 			return
 		}
-		if !inited {
-			w.EvtCh <- zk.Event{
-				Type:  zk.EventNodeCreated,
-				State: zk.StateUnknown,
-				Path:  nodePath,
+		retries = 0
+		if exists {
+			if cached.Changed(stat) {
+				data, newStat, err := r.conn.Get(nodePath)
+				if nil != err {
+					r.newLogger().Errorw("Zookeeper retriever get node data", "node-path", nodePath, "error", err)
+					if err == zk.ErrNoNode || r.awaitRetry(&retries, nodePath) {
+						continue
+					}
+					return
+				}
+				r.notifyListeners(nodePath, cached.Update(nodePath, newStat, data))
 			}
-			inited = true
+		} else if event, ok := cached.Delete(nodePath); ok {
+			// 节点已删除：通知后停止监听；节点重新创建时，由子节点监听重新注册；
+			r.notifyListeners(nodePath, event)
+			return
 		}
 		select {
 		case <-r.quit:
@@ -236,51 +240,135 @@ func (r *ZookeeperRetriever) watchDataNodeChanged(nodePath string) {
 
 		case zkEvent := <-w.EvtCh:
 			r.newLogger().Debugw("Zookeeper retriever receive data event", "event", zkEvent)
-			var (
-				eventType remoting.EventType
-				eventData []byte
-			)
-			r.listenerMu.RLock()
-			listeners, ok := r.listenerMap[zkEvent.Path]
-			r.listenerMu.RUnlock()
-			if !ok || 0 == len(listeners) {
-				continue
+
+		case <-r.reconnectSignal():
+			r.newLogger().Infow("Zookeeper retriever resync node data after reconnected", "node-path", nodePath)
+		}
+	}
+}
+
+// NodeData 缓存数据节点的状态和数据；
+// 节点删除后无法再读取数据，删除事件携带最后一次读取的数据，以便监听方解析被删除的定义；
+type NodeData struct {
+	stat *zk.Stat
+	data []byte
+}
+
+// Changed 判断节点状态是否与缓存不同；未缓存时返回true
+func (n *NodeData) Changed(stat *zk.Stat) bool {
+	return nil == n.stat || isNodeChanged(n.stat, stat)
+}
+
+// Update 缓存节点状态和数据，返回节点新增或更新事件
+func (n *NodeData) Update(nodePath string, stat *zk.Stat, data []byte) remoting.NodeEvent {
+	eventType := remoting.EventType(remoting.EventTypeNodeUpdate)
+	if nil == n.stat {
+		eventType = remoting.EventTypeNodeAdd
+	}
+	n.stat, n.data = stat, data
+	return remoting.NodeEvent{
+		Path:      nodePath,
+		EventType: eventType,
+		Data:      data,
+	}
+}
+
+// Delete 返回携带最后数据的节点删除事件；节点未缓存时返回false
+func (n *NodeData) Delete(nodePath string) (remoting.NodeEvent, bool) {
+	if nil == n.stat {
+		return remoting.NodeEvent{}, false
+	}
+	return remoting.NodeEvent{
+		Path:      nodePath,
+		EventType: remoting.EventTypeNodeDelete,
+		Data:      n.data,
+	}, true
+}
+
+// watchConnState 监听ZK连接状态；当会话断开或者过期后重新建立会话，通知全部监听重新同步状态；
+func (r *ZookeeperRetriever) watchConnState(events <-chan zk.Event) {
+	lost := false
+	for {
+		select {
+		case <-r.quit:
+			return
+
+		case event, ok := <-events:
+			if !ok {
+				return
 			}
-			const msgErrGetNodeData = "Zookeeper retriever get node data"
-			switch zkEvent.Type {
-			case zk.EventNodeDataChanged:
-				data, _, err := r.conn.Get(zkEvent.Path)
-				if nil != err {
-					r.newLogger().Errorw(msgErrGetNodeData, "node-path", nodePath, "error", err)
-					return
+			switch event.State {
+			case zk.StateDisconnected, zk.StateExpired:
+				atomic.StoreInt32(&r.connected, 0)
+				if !lost {
+					r.newLogger().Warnw("Zookeeper retriever connection lost", "state", event.State.String())
 				}
-				eventType = remoting.EventTypeNodeUpdate
-				eventData = data
-			case zk.EventNodeCreated:
-				data, _, err := r.conn.Get(zkEvent.Path)
-				if nil != err {
-					r.newLogger().Errorf(msgErrGetNodeData, "node-path", nodePath, "error", err)
-					return
+				lost = true
+			case zk.StateHasSession:
+				atomic.StoreInt32(&r.connected, 1)
+				if lost {
+					r.newLogger().Infow("Zookeeper retriever session re-established, resync all watches")
+					r.signalReconnect()
 				}
-				eventType = remoting.EventTypeNodeAdd
-				eventData = data
-			case zk.EventNodeDeleted:
-				eventType = remoting.EventTypeNodeDelete
-			default:
-				continue
-			}
-			event := remoting.NodeEvent{
-				Path:      zkEvent.Path,
-				EventType: eventType,
-				Data:      eventData,
-			}
-			for _, listener := range listeners {
-				listener(event)
+				lost = false
 			}
 		}
 	}
 }
 
+// awaitRetry 等待重试；超过最大重试次数后，等待重新建立会话；返回false表示退出监听；
+func (r *ZookeeperRetriever) awaitRetry(retries *int, nodePath string) bool {
+	*retries++
+	if r.config.RetryMax > 0 && *retries > r.config.RetryMax {
+		r.newLogger().Warnw("Zookeeper retriever retry exhausted, waiting for reconnect",
+			"node-path", nodePath, "retry", *retries)
+		select {
+		case <-r.quit:
+			return false
+		case <-r.reconnectSignal():
+			*retries = 0
+			return true
+		}
+	}
+	select {
+	case <-r.quit:
+		return false
+	case <-r.reconnectSignal():
+		return true
+	case <-time.After(r.config.RetryDelay):
+		r.newLogger().Infow("Zookeeper retriever retry watch", "node-path", nodePath, "retry", *retries)
+		return true
+	}
+}
+
+func (r *ZookeeperRetriever) reconnectSignal() <-chan struct{} {
+	r.reconnectMu.RLock()
+	defer r.reconnectMu.RUnlock()
+	return r.reconnect
+}
+
+func (r *ZookeeperRetriever) signalReconnect() {
+	r.reconnectMu.Lock()
+	defer r.reconnectMu.Unlock()
+	close(r.reconnect)
+	r.reconnect = make(chan struct{})
+}
+
+func (r *ZookeeperRetriever) notifyListeners(nodeKey string, event remoting.NodeEvent) {
+	r.listenerMu.RLock()
+	listeners := r.listenerMap[nodeKey]
+	r.listenerMu.RUnlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+func (r *ZookeeperRetriever) purgeListeners(nodeKey string) {
+	r.listenerMu.Lock()
+	delete(r.listenerMap, nodeKey)
+	r.listenerMu.Unlock()
+}
+
 func (r *ZookeeperRetriever) setupListener(groupId, nodeKey string, listener remoting.NodeChangedListener) (bool, error) {
 	if groupId != "" {
 		r.newLogger().Warnw("Zookeeper retriever not support groupId", "groupId", groupId)
@@ -313,3 +401,23 @@ type zkLogger int
 func (zkLogger) Printf(format string, args ...interface{}) {
 	logger.Debugf(format, args...)
 }
+
+// DiffChildren 对比缓存与实时的子节点列表，返回新增和删除的子节点
+func DiffChildren(cached, live []string) (added, deleted []string) {
+	for _, p := range live {
+		if !fluxpkg.StringSliceContains(cached, p) {
+			added = append(added, p)
+		}
+	}
+	for _, p := range cached {
+		if !fluxpkg.StringSliceContains(live, p) {
+			deleted = append(deleted, p)
+		}
+	}
+	return added, deleted
+}
+
+// 节点重新创建（Czxid变化）或者数据版本变化，均视为节点已变更
+func isNodeChanged(cached, live *zk.Stat) bool {
+	return cached.Czxid != live.Czxid || cached.Version != live.Version
+}
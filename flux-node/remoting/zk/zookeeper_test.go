@@ -0,0 +1,60 @@
+package zk
+
+import (
+	"github.com/dubbogo/go-zookeeper/zk"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiffChildren(t *testing.T) {
+	cases := []struct {
+		cached  []string
+		live    []string
+		added   []string
+		deleted []string
+	}{
+		{
+			cached: []string{},
+			live:   []string{"/a", "/b"},
+			added:  []string{"/a", "/b"},
+		},
+		{
+			cached:  []string{"/a", "/b"},
+			live:    []string{"/b", "/c"},
+			added:   []string{"/c"},
+			deleted: []string{"/a"},
+		},
+		{
+			cached:  []string{"/a", "/b"},
+			live:    []string{},
+			deleted: []string{"/a", "/b"},
+		},
+		{
+			cached: []string{"/a"},
+			live:   []string{"/a"},
+		},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		added, deleted := DiffChildren(tcase.cached, tcase.live)
+		assert.Equal(tcase.added, added)
+		assert.Equal(tcase.deleted, deleted)
+	}
+}
+
+func TestIsNodeChanged(t *testing.T) {
+	cases := []struct {
+		cached   *zk.Stat
+		live     *zk.Stat
+		expected bool
+	}{
+		{cached: &zk.Stat{Czxid: 1, Version: 1}, live: &zk.Stat{Czxid: 1, Version: 1}, expected: false},
+		{cached: &zk.Stat{Czxid: 1, Version: 1}, live: &zk.Stat{Czxid: 1, Version: 2}, expected: true},
+		// 节点被删除后重新创建
+		{cached: &zk.Stat{Czxid: 1, Version: 0}, live: &zk.Stat{Czxid: 5, Version: 0}, expected: true},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		assert.Equal(tcase.expected, isNodeChanged(tcase.cached, tcase.live))
+	}
+}
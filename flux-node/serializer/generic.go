@@ -0,0 +1,55 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// toGeneric 将结构体等任意值转换为通用结构：map[string]interface{}, []interface{}, json.Number, string, bool, nil；
+// 结构体字段按json标签命名，与JSON序列化的字段一致；
+func toGeneric(v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case nil, bool, string, json.Number:
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if nil != err {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out interface{}
+	if err := decoder.Decode(&out); nil != err {
+		return nil, err
+	}
+	return out, nil
+}
+
+// fromGeneric 将通用结构赋值给目标对象；目标为 *interface{} 时直接赋值，其它类型通过JSON转换；
+func fromGeneric(value interface{}, out interface{}) error {
+	if ptr, ok := out.(*interface{}); ok {
+		*ptr = value
+		return nil
+	}
+	if ptr, ok := out.(*map[string]interface{}); ok {
+		if m, ok := value.(map[string]interface{}); ok {
+			*ptr = m
+			return nil
+		}
+	}
+	data, err := json.Marshal(value)
+	if nil != err {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// isStructValue 判断是否为结构体或者结构体指针
+func isStructValue(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	for nil != t && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return nil != t && t.Kind() == reflect.Struct
+}
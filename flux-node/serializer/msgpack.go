@@ -0,0 +1,406 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+)
+
+var _ flux.Serializer = new(MsgPackSerializer)
+
+// MsgPackSerializer MessagePack序列化实现；
+// 支持nil、布尔、整数、浮点数、字符串、二进制、列表和Map；结构体按json标签转换为Map后序列化；
+type MsgPackSerializer struct {
+}
+
+func NewMsgPackSerializer() flux.Serializer {
+	return new(MsgPackSerializer)
+}
+
+func (s *MsgPackSerializer) Marshal(v interface{}) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	if err := encodeMsgPack(buffer, v); nil != err {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *MsgPackSerializer) Unmarshal(data []byte, out interface{}) error {
+	value, err := decodeMsgPack(bytes.NewReader(data))
+	if nil != err {
+		return err
+	}
+	return fromGeneric(value, out)
+}
+
+func encodeMsgPack(w *bytes.Buffer, v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		w.WriteByte(0xc0)
+	case bool:
+		if x {
+			w.WriteByte(0xc3)
+		} else {
+			w.WriteByte(0xc2)
+		}
+	case int:
+		encodeMsgPackInt(w, int64(x))
+	case int8:
+		encodeMsgPackInt(w, int64(x))
+	case int16:
+		encodeMsgPackInt(w, int64(x))
+	case int32:
+		encodeMsgPackInt(w, int64(x))
+	case int64:
+		encodeMsgPackInt(w, x)
+	case uint:
+		encodeMsgPackUint(w, uint64(x))
+	case uint8:
+		encodeMsgPackUint(w, uint64(x))
+	case uint16:
+		encodeMsgPackUint(w, uint64(x))
+	case uint32:
+		encodeMsgPackUint(w, uint64(x))
+	case uint64:
+		encodeMsgPackUint(w, x)
+	case float32:
+		w.WriteByte(0xca)
+		_ = binary.Write(w, binary.BigEndian, math.Float32bits(x))
+	case float64:
+		w.WriteByte(0xcb)
+		_ = binary.Write(w, binary.BigEndian, math.Float64bits(x))
+	case json.Number:
+		if i, err := x.Int64(); nil == err {
+			encodeMsgPackInt(w, i)
+		} else if f, err := x.Float64(); nil == err {
+			return encodeMsgPack(w, f)
+		} else {
+			encodeMsgPackString(w, x.String())
+		}
+	case string:
+		encodeMsgPackString(w, x)
+	case []byte:
+		size := len(x)
+		switch {
+		case size <= math.MaxUint8:
+			w.Write([]byte{0xc4, byte(size)})
+		case size <= math.MaxUint16:
+			w.WriteByte(0xc5)
+			_ = binary.Write(w, binary.BigEndian, uint16(size))
+		default:
+			w.WriteByte(0xc6)
+			_ = binary.Write(w, binary.BigEndian, uint32(size))
+		}
+		w.Write(x)
+	case []interface{}:
+		encodeMsgPackHeader(w, len(x), 0x90, 0xdc, 0xdd)
+		for _, item := range x {
+			if err := encodeMsgPack(w, item); nil != err {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		encodeMsgPackHeader(w, len(x), 0x80, 0xde, 0xdf)
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			encodeMsgPackString(w, k)
+			if err := encodeMsgPack(w, x[k]); nil != err {
+				return err
+			}
+		}
+	default:
+		return encodeMsgPackReflect(w, v)
+	}
+	return nil
+}
+
+// encodeMsgPackReflect 序列化其它类型的列表和Map；结构体等复杂类型，转换为通用结构后序列化
+func encodeMsgPackReflect(w *bytes.Buffer, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return encodeMsgPack(w, nil)
+		}
+		if rv.Elem().Kind() != reflect.Struct {
+			return encodeMsgPack(w, rv.Elem().Interface())
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return encodeMsgPack(w, nil)
+		}
+		encodeMsgPackHeader(w, rv.Len(), 0x90, 0xdc, 0xdd)
+		for i := 0; i < rv.Len(); i++ {
+			if err := encodeMsgPack(w, rv.Index(i).Interface()); nil != err {
+				return err
+			}
+		}
+		return nil
+	case reflect.String:
+		encodeMsgPackString(w, rv.String())
+		return nil
+	}
+	generic, err := toGeneric(v)
+	if nil != err {
+		return err
+	}
+	switch generic.(type) {
+	case map[string]interface{}, []interface{}, string, bool, json.Number, nil:
+		return encodeMsgPack(w, generic)
+	default:
+		return fmt.Errorf("msgpack: unsupported type: %T", v)
+	}
+}
+
+func encodeMsgPackInt(w *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0:
+		encodeMsgPackUint(w, uint64(i))
+	case i >= -32:
+		w.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		w.Write([]byte{0xd0, byte(i)})
+	case i >= math.MinInt16:
+		w.WriteByte(0xd1)
+		_ = binary.Write(w, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		w.WriteByte(0xd2)
+		_ = binary.Write(w, binary.BigEndian, int32(i))
+	default:
+		w.WriteByte(0xd3)
+		_ = binary.Write(w, binary.BigEndian, i)
+	}
+}
+
+func encodeMsgPackUint(w *bytes.Buffer, u uint64) {
+	switch {
+	case u <= 0x7f:
+		w.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		w.Write([]byte{0xcc, byte(u)})
+	case u <= math.MaxUint16:
+		w.WriteByte(0xcd)
+		_ = binary.Write(w, binary.BigEndian, uint16(u))
+	case u <= math.MaxUint32:
+		w.WriteByte(0xce)
+		_ = binary.Write(w, binary.BigEndian, uint32(u))
+	default:
+		w.WriteByte(0xcf)
+		_ = binary.Write(w, binary.BigEndian, u)
+	}
+}
+
+func encodeMsgPackString(w *bytes.Buffer, s string) {
+	size := len(s)
+	switch {
+	case size <= 31:
+		w.WriteByte(0xa0 | byte(size))
+	case size <= math.MaxUint8:
+		w.Write([]byte{0xd9, byte(size)})
+	case size <= math.MaxUint16:
+		w.WriteByte(0xda)
+		_ = binary.Write(w, binary.BigEndian, uint16(size))
+	default:
+		w.WriteByte(0xdb)
+		_ = binary.Write(w, binary.BigEndian, uint32(size))
+	}
+	w.WriteString(s)
+}
+
+// encodeMsgPackHeader 写入列表或Map的长度头：fix格式最多15个元素，否则使用16位或32位长度
+func encodeMsgPackHeader(w *bytes.Buffer, size int, fix, b16, b32 byte) {
+	switch {
+	case size <= 15:
+		w.WriteByte(fix | byte(size))
+	case size <= math.MaxUint16:
+		w.WriteByte(b16)
+		_ = binary.Write(w, binary.BigEndian, uint16(size))
+	default:
+		w.WriteByte(b32)
+		_ = binary.Write(w, binary.BigEndian, uint32(size))
+	}
+}
+
+// decodeMsgPack 解析MessagePack数据为通用结构：Map解析为 map[string]interface{}，整数解析为int64/uint64
+func decodeMsgPack(r *bytes.Reader) (interface{}, error) {
+	code, err := r.ReadByte()
+	if nil != err {
+		return nil, err
+	}
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return decodeMsgPackMap(r, int(code&0x0f))
+	case code&0xf0 == 0x90:
+		return decodeMsgPackArray(r, int(code&0x0f))
+	case code&0xe0 == 0xa0:
+		return decodeMsgPackString(r, int(code&0x1f))
+	}
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		size, err := readMsgPackSize(r, code-0xc4)
+		if nil != err {
+			return nil, err
+		}
+		return readMsgPackBytes(r, size)
+	case 0xca:
+		var bits uint32
+		err := binary.Read(r, binary.BigEndian, &bits)
+		return float64(math.Float32frombits(bits)), err
+	case 0xcb:
+		var bits uint64
+		err := binary.Read(r, binary.BigEndian, &bits)
+		return math.Float64frombits(bits), err
+	case 0xcc:
+		var v uint8
+		err := binary.Read(r, binary.BigEndian, &v)
+		return uint64(v), err
+	case 0xcd:
+		var v uint16
+		err := binary.Read(r, binary.BigEndian, &v)
+		return uint64(v), err
+	case 0xce:
+		var v uint32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return uint64(v), err
+	case 0xcf:
+		var v uint64
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case 0xd0:
+		var v int8
+		err := binary.Read(r, binary.BigEndian, &v)
+		return int64(v), err
+	case 0xd1:
+		var v int16
+		err := binary.Read(r, binary.BigEndian, &v)
+		return int64(v), err
+	case 0xd2:
+		var v int32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return int64(v), err
+	case 0xd3:
+		var v int64
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, err
+	case 0xd9, 0xda, 0xdb:
+		size, err := readMsgPackSize(r, code-0xd9)
+		if nil != err {
+			return nil, err
+		}
+		return decodeMsgPackString(r, size)
+	case 0xdc, 0xdd:
+		size, err := readMsgPackSize(r, code-0xdc+1)
+		if nil != err {
+			return nil, err
+		}
+		return decodeMsgPackArray(r, size)
+	case 0xde, 0xdf:
+		size, err := readMsgPackSize(r, code-0xde+1)
+		if nil != err {
+			return nil, err
+		}
+		return decodeMsgPackMap(r, size)
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported format code: 0x%x", code)
+	}
+}
+
+// readMsgPackSize 读取长度字段；width: 0=8位，1=16位，2=32位
+func readMsgPackSize(r *bytes.Reader, width byte) (int, error) {
+	switch width {
+	case 0:
+		var v uint8
+		err := binary.Read(r, binary.BigEndian, &v)
+		return int(v), err
+	case 1:
+		var v uint16
+		err := binary.Read(r, binary.BigEndian, &v)
+		return int(v), err
+	default:
+		var v uint32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return int(v), err
+	}
+}
+
+func readMsgPackBytes(r *bytes.Reader, size int) ([]byte, error) {
+	if size > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, size)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+func decodeMsgPackString(r *bytes.Reader, size int) (interface{}, error) {
+	data, err := readMsgPackBytes(r, size)
+	if nil != err {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func decodeMsgPackArray(r *bytes.Reader, size int) (interface{}, error) {
+	if size > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	out := make([]interface{}, 0, size)
+	for i := 0; i < size; i++ {
+		item, err := decodeMsgPack(r)
+		if nil != err {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+func decodeMsgPackMap(r *bytes.Reader, size int) (interface{}, error) {
+	if size > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	out := make(map[string]interface{}, size)
+	for i := 0; i < size; i++ {
+		key, err := decodeMsgPack(r)
+		if nil != err {
+			return nil, err
+		}
+		value, err := decodeMsgPack(r)
+		if nil != err {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case string:
+			out[k] = value
+		case []byte:
+			out[string(k)] = value
+		default:
+			if nil == k {
+				return nil, errors.New("msgpack: nil map key")
+			}
+			out[fmt.Sprintf("%v", k)] = value
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,43 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+var _ flux.Serializer = new(ProtoJsonSerializer)
+
+// ProtoJsonSerializer Protobuf-JSON序列化实现；
+// proto.Message 类型按Protobuf的JSON映射规则序列化（字段使用lowerCamelCase，输出默认值）；其它类型使用标准JSON序列化；
+type ProtoJsonSerializer struct {
+	marshaler   *jsonpb.Marshaler
+	unmarshaler *jsonpb.Unmarshaler
+}
+
+func NewProtoJsonSerializer() flux.Serializer {
+	return &ProtoJsonSerializer{
+		marshaler:   &jsonpb.Marshaler{EmitDefaults: true},
+		unmarshaler: &jsonpb.Unmarshaler{AllowUnknownFields: true},
+	}
+}
+
+func (s *ProtoJsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	if msg, ok := v.(proto.Message); ok {
+		buffer := new(bytes.Buffer)
+		if err := s.marshaler.Marshal(buffer, msg); nil != err {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+	return json.Marshal(v)
+}
+
+func (s *ProtoJsonSerializer) Unmarshal(data []byte, out interface{}) error {
+	if msg, ok := out.(proto.Message); ok {
+		return s.unmarshaler.Unmarshal(bytes.NewReader(data), msg)
+	}
+	return json.Unmarshal(data, out)
+}
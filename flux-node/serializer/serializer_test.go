@@ -0,0 +1,107 @@
+package serializer
+
+import (
+	"encoding/xml"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	assert2 "github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type user struct {
+	XMLName xml.Name `json:"-" xml:"user"`
+	Id      int      `json:"id" xml:"id"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+func TestXmlSerializer_Marshal(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{value: map[string]interface{}{"status": "error", "message": "NOT_FOUND"},
+			expected: "<response><message>NOT_FOUND</message><status>error</status></response>"},
+		{value: map[string]interface{}{"ids": []int{1, 2}, "1st key": nil},
+			expected: "<response><_1st_key></_1st_key><ids><item>1</item><item>2</item></ids></response>"},
+		{value: []string{"a"}, expected: "<response><item>a</item></response>"},
+		{value: &user{Id: 1, Name: "flux"}, expected: "<user><id>1</id><name>flux</name></user>"},
+	}
+	assert := assert2.New(t)
+	s := NewXmlSerializer()
+	for _, tcase := range cases {
+		data, err := s.Marshal(tcase.value)
+		assert.NoError(err)
+		assert.Equal(tcase.expected, strings.TrimPrefix(string(data), xml.Header))
+	}
+}
+
+func TestXmlSerializer_Unmarshal(t *testing.T) {
+	assert := assert2.New(t)
+	s := NewXmlSerializer()
+	var out map[string]interface{}
+	assert.NoError(s.Unmarshal([]byte("<response><name>flux</name><ids><item>1</item><item>2</item></ids></response>"), &out))
+	assert.Equal(map[string]interface{}{"name": "flux", "ids": []interface{}{"1", "2"}}, out)
+	var u user
+	assert.NoError(s.Unmarshal([]byte("<user><id>2</id><name>go</name></user>"), &u))
+	assert.Equal(2, u.Id)
+	assert.Equal("go", u.Name)
+}
+
+func TestMsgPackSerializer(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected []byte
+	}{
+		{value: nil, expected: []byte{0xc0}},
+		{value: true, expected: []byte{0xc3}},
+		{value: 1, expected: []byte{0x01}},
+		{value: -1, expected: []byte{0xff}},
+		{value: 300, expected: []byte{0xcd, 0x01, 0x2c}},
+		{value: "ab", expected: []byte{0xa2, 'a', 'b'}},
+		{value: []int{1, 2}, expected: []byte{0x92, 0x01, 0x02}},
+		{value: map[string]interface{}{"a": 1}, expected: []byte{0x81, 0xa1, 'a', 0x01}},
+		{value: user{Id: 1, Name: "x"}, expected: []byte{0x82, 0xa2, 'i', 'd', 0x01, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'x'}},
+	}
+	assert := assert2.New(t)
+	s := NewMsgPackSerializer()
+	for _, tcase := range cases {
+		data, err := s.Marshal(tcase.value)
+		assert.NoError(err)
+		assert.Equal(tcase.expected, data, "value: %v", tcase.value)
+	}
+	// 反序列化
+	data, err := s.Marshal(map[string]interface{}{"id": 7, "name": "flux", "score": 1.5, "tags": []string{"a"}})
+	assert.NoError(err)
+	var out map[string]interface{}
+	assert.NoError(s.Unmarshal(data, &out))
+	assert.Equal(map[string]interface{}{"id": int64(7), "name": "flux", "score": 1.5, "tags": []interface{}{"a"}}, out)
+	var u user
+	assert.NoError(s.Unmarshal([]byte{0x82, 0xa2, 'i', 'd', 0x05, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'y'}, &u))
+	assert.Equal(user{Id: 5, Name: "y"}, u)
+	assert.Error(s.Unmarshal([]byte{0x92, 0x01}, &out))
+}
+
+func TestProtoJsonSerializer(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{value: &wrappers.StringValue{Value: "flux"}, expected: `"flux"`},
+		{value: &wrappers.Int32Value{}, expected: `0`},
+		// 非Protobuf类型使用标准JSON序列化
+		{value: &user{Id: 1, Name: "flux"}, expected: `{"id":1,"name":"flux"}`},
+	}
+	assert := assert2.New(t)
+	s := NewProtoJsonSerializer()
+	for _, tcase := range cases {
+		data, err := s.Marshal(tcase.value)
+		assert.NoError(err)
+		assert.Equal(tcase.expected, string(data))
+	}
+	var str wrappers.StringValue
+	assert.NoError(s.Unmarshal([]byte(`"go"`), &str))
+	assert.Equal("go", str.Value)
+	var u user
+	assert.NoError(s.Unmarshal([]byte(`{"id":2,"name":"go"}`), &u))
+	assert.Equal(2, u.Id)
+}
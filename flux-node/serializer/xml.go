@@ -0,0 +1,180 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// 通用结构序列化时的根节点和列表元素名称
+	xmlRootElement = "response"
+	xmlItemElement = "item"
+)
+
+var _ flux.Serializer = new(XmlSerializer)
+
+// XmlSerializer XML序列化实现；
+// 带有xml标签的结构体使用标准库序列化；Map、列表等通用结构，以 <response> 为根节点，Map的键作为元素名称，
+// 列表的每个元素使用 <item> 元素；
+type XmlSerializer struct {
+}
+
+func NewXmlSerializer() flux.Serializer {
+	return new(XmlSerializer)
+}
+
+func (s *XmlSerializer) Marshal(v interface{}) ([]byte, error) {
+	if isStructValue(v) {
+		if data, err := xml.Marshal(v); nil == err {
+			return append([]byte(xml.Header), data...), nil
+		}
+	}
+	generic, err := toGeneric(v)
+	if nil != err {
+		return nil, err
+	}
+	buffer := bytes.NewBufferString(xml.Header)
+	encoder := xml.NewEncoder(buffer)
+	if err := encodeXmlElement(encoder, xmlRootElement, generic); nil != err {
+		return nil, err
+	}
+	if err := encoder.Flush(); nil != err {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *XmlSerializer) Unmarshal(data []byte, out interface{}) error {
+	switch out.(type) {
+	case *interface{}, *map[string]interface{}:
+		value, err := decodeXmlDocument(xml.NewDecoder(bytes.NewReader(data)))
+		if nil != err {
+			return err
+		}
+		return fromGeneric(value, out)
+	default:
+		return xml.Unmarshal(data, out)
+	}
+}
+
+func encodeXmlElement(encoder *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlElementName(name)}}
+	switch v := value.(type) {
+	case nil:
+		return encoder.EncodeElement("", start)
+	case map[string]interface{}:
+		if err := encoder.EncodeToken(start); nil != err {
+			return err
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := encodeXmlElement(encoder, k, v[k]); nil != err {
+				return err
+			}
+		}
+		return encoder.EncodeToken(start.End())
+	case []interface{}:
+		if err := encoder.EncodeToken(start); nil != err {
+			return err
+		}
+		for _, item := range v {
+			if err := encodeXmlElement(encoder, xmlItemElement, item); nil != err {
+				return err
+			}
+		}
+		return encoder.EncodeToken(start.End())
+	case json.Number:
+		return encoder.EncodeElement(v.String(), start)
+	default:
+		return encoder.EncodeElement(fmt.Sprintf("%v", v), start)
+	}
+}
+
+// xmlElementName 将Map的键转换为合法的XML元素名称
+func xmlElementName(name string) string {
+	if "" == name {
+		return "_"
+	}
+	out := []rune(name)
+	for i, r := range out {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || '_' == r || '-' == r || '.' == r) {
+			out[i] = '_'
+		}
+	}
+	if !(unicode.IsLetter(out[0]) || '_' == out[0]) || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return "_" + string(out)
+	}
+	return string(out)
+}
+
+// decodeXmlDocument 解析XML文档为通用结构：包含子元素的节点解析为Map，重复的子元素解析为列表，叶子节点解析为字符串；
+func decodeXmlDocument(decoder *xml.Decoder) (interface{}, error) {
+	for {
+		token, err := decoder.Token()
+		if nil != err {
+			if io.EOF == err {
+				return nil, errors.New("xml: root element not found")
+			}
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return decodeXmlElement(decoder, start)
+		}
+	}
+}
+
+func decodeXmlElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	children := make(map[string]interface{})
+	names := make([]string, 0, 4)
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if nil != err {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			value, err := decodeXmlElement(decoder, t)
+			if nil != err {
+				return nil, err
+			}
+			name := t.Name.Local
+			if exists, ok := children[name]; ok {
+				if list, ok := exists.([]interface{}); ok {
+					children[name] = append(list, value)
+				} else {
+					children[name] = []interface{}{exists, value}
+				}
+			} else {
+				children[name] = value
+				names = append(names, name)
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(children) == 0 {
+				return strings.TrimSpace(text.String()), nil
+			}
+			// 只包含 <item> 元素的节点，解析为列表
+			if len(names) == 1 && xmlItemElement == names[0] {
+				if list, ok := children[xmlItemElement].([]interface{}); ok {
+					return list, nil
+				}
+				return []interface{}{children[xmlItemElement]}, nil
+			}
+			return children, nil
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package flux
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	MIMETextEventStream = "text/event-stream"
+)
+
+const (
+	// 流式复制的缓冲区大小
+	streamBufferSize = 32 * 1024
+)
+
+// StreamBody 流式响应数据体；WebResponseWriter 不缓存全部数据，而是边读取边写入客户端并刷新；
+// 用于SSE（text/event-stream）和分块传输（chunked）的后端响应透传；
+type StreamBody struct {
+	io.ReadCloser
+	// 响应的ContentType
+	ContentType string
+}
+
+// IsStreamResponse 判断后端Http响应是否为流式响应：text/event-stream 总是流式响应；
+// chunked 分块传输只在 streamChunked 为true（Endpoint定义了stream属性）时为流式响应；
+// 注意：这是有意与“chunked 响应全部流式透传”不同的行为。很多后端框架对普通的JSON响应也使用chunked编码，
+// 全部透传会绕过响应解码、Header过滤和内容协商，因此chunked流式透传需要Endpoint显式开启；
+func IsStreamResponse(header http.Header, transferEncoding []string, streamChunked bool) bool {
+	if mt, _, err := mime.ParseMediaType(header.Get(HeaderContentType)); nil == err && MIMETextEventStream == mt {
+		return true
+	}
+	if !streamChunked {
+		return false
+	}
+	for _, te := range transferEncoding {
+		if strings.EqualFold("chunked", te) {
+			return true
+		}
+	}
+	return false
+}
+
+// CopyStream 从Reader逐块读取数据写入Writer，每次写入后刷新到客户端；
+// 写入阻塞时不再读取上游数据，由连接的流量控制实现背压；Context被取消时停止复制，并关闭Reader；
+func CopyStream(ctx context.Context, w io.Writer, r io.Reader) (int64, error) {
+	if c, ok := r.(io.Closer); ok {
+		// 上游读取可能阻塞，通过关闭Reader中断读取
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = c.Close()
+			case <-done:
+			}
+		}()
+	}
+	flusher, _ := w.(http.Flusher)
+	buffer := make([]byte, streamBufferSize)
+	var written int64
+	for {
+		if err := ctx.Err(); nil != err {
+			return written, err
+		}
+		n, rerr := r.Read(buffer)
+		if n > 0 {
+			wn, werr := w.Write(buffer[:n])
+			written += int64(wn)
+			if nil != werr {
+				return written, werr
+			}
+			if nil != flusher {
+				flusher.Flush()
+			}
+		}
+		if io.EOF == rerr {
+			return written, nil
+		} else if nil != rerr {
+			if err := ctx.Err(); nil != err {
+				return written, err
+			}
+			return written, rerr
+		}
+	}
+}
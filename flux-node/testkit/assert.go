@@ -0,0 +1,104 @@
+package testkit
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// Response 网关返回的Http响应
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// BodyString 返回响应Body文本
+func (r *Response) BodyString() string {
+	return string(r.Body)
+}
+
+// DecodeJSON 解析JSON格式的响应Body
+func (r *Response) DecodeJSON(out interface{}) error {
+	return json.Unmarshal(r.Body, out)
+}
+
+// AssertStatus 断言响应状态码
+func (r *Response) AssertStatus(t testing.TB, expected int) *Response {
+	t.Helper()
+	assert.Equal(t, expected, r.StatusCode, "status code, body: %s", r.BodyString())
+	return r
+}
+
+// AssertHeader 断言响应Header
+func (r *Response) AssertHeader(t testing.TB, name, expected string) *Response {
+	t.Helper()
+	assert.Equal(t, expected, r.Header.Get(name), "header: %s", name)
+	return r
+}
+
+// AssertBodyContains 断言响应Body包含指定文本
+func (r *Response) AssertBodyContains(t testing.TB, expected string) *Response {
+	t.Helper()
+	assert.Contains(t, r.BodyString(), expected)
+	return r
+}
+
+// AssertJSON 断言响应Body与期望值的JSON结构相等；期望值可以是结构体、Map或者JSON文本；
+func (r *Response) AssertJSON(t testing.TB, expected interface{}) *Response {
+	t.Helper()
+	var bytes []byte
+	switch v := expected.(type) {
+	case string:
+		bytes = []byte(v)
+	case []byte:
+		bytes = v
+	default:
+		b, err := json.Marshal(v)
+		if !assert.NoError(t, err, "marshal expected value") {
+			return r
+		}
+		bytes = b
+	}
+	assert.JSONEq(t, string(bytes), r.BodyString())
+	return r
+}
+
+// AssertInvoked 断言最后一次后端调用的服务接口和方法，返回调用记录
+func (g *Gateway) AssertInvoked(iface, method string) Invocation {
+	g.T.Helper()
+	inv, ok := g.Transport.LastInvocation()
+	if !assert.True(g.T, ok, "backend not invoked") {
+		return inv
+	}
+	assert.Equal(g.T, iface, inv.Service.Interface, "invoked interface")
+	assert.Equal(g.T, method, inv.Service.Method, "invoked method")
+	return inv
+}
+
+// AssertNotInvoked 断言后端服务没有被调用
+func (g *Gateway) AssertNotInvoked() {
+	g.T.Helper()
+	assert.Empty(g.T, g.Transport.Invocations(), "backend invoked")
+}
+
+// AssertArgument 断言已解析的参数值
+func (i Invocation) AssertArgument(t testing.TB, name string, expected interface{}) Invocation {
+	t.Helper()
+	value, ok := i.Arguments[name]
+	if assert.True(t, ok, "argument not found: %s", name) {
+		assert.Equal(t, expected, value, "argument: %s", name)
+	}
+	return i
+}
+
+// AssertAttachment 断言调用时的Attachment值
+func (i Invocation) AssertAttachment(t testing.TB, key string, expected interface{}) Invocation {
+	t.Helper()
+	value, ok := i.Attachments[key]
+	if assert.True(t, ok, "attachment not found: %s", key) {
+		assert.Equal(t, expected, value, "attachment: %s", key)
+	}
+	return i
+}
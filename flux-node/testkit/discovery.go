@@ -0,0 +1,89 @@
+package testkit
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"sync"
+)
+
+const (
+	MemoryDiscoveryId = "memory"
+)
+
+var (
+	_ flux.EndpointDiscovery = new(MemoryDiscovery)
+	_ flux.Readiness         = new(MemoryDiscovery)
+)
+
+// MemoryDiscovery 基于内存的Discovery；在Server启动前发布的定义，在开始监听时发送；
+type MemoryDiscovery struct {
+	id        string
+	endpoints chan<- flux.HttpEndpointEvent
+	services  chan<- flux.BackendServiceEvent
+	pendingEp []flux.HttpEndpointEvent
+	pendingSv []flux.BackendServiceEvent
+	mutex     sync.Mutex
+}
+
+func NewMemoryDiscovery(id string) *MemoryDiscovery {
+	return &MemoryDiscovery{id: id}
+}
+
+func (d *MemoryDiscovery) Id() string {
+	return d.id
+}
+
+func (d *MemoryDiscovery) Ready() bool {
+	return true
+}
+
+func (d *MemoryDiscovery) WatchEndpoints(events chan<- flux.HttpEndpointEvent) error {
+	d.mutex.Lock()
+	d.endpoints = events
+	pending := d.pendingEp
+	d.pendingEp = nil
+	d.mutex.Unlock()
+	for _, evt := range pending {
+		events <- evt
+	}
+	return nil
+}
+
+func (d *MemoryDiscovery) WatchServices(events chan<- flux.BackendServiceEvent) error {
+	d.mutex.Lock()
+	d.services = events
+	pending := d.pendingSv
+	d.pendingSv = nil
+	d.mutex.Unlock()
+	for _, evt := range pending {
+		events <- evt
+	}
+	return nil
+}
+
+// PublishEndpoint 发布Endpoint定义
+func (d *MemoryDiscovery) PublishEndpoint(etype flux.EventType, endpoint flux.Endpoint) {
+	evt := flux.HttpEndpointEvent{EventType: etype, Endpoint: endpoint}
+	d.mutex.Lock()
+	events := d.endpoints
+	if nil == events {
+		d.pendingEp = append(d.pendingEp, evt)
+	}
+	d.mutex.Unlock()
+	if nil != events {
+		events <- evt
+	}
+}
+
+// PublishService 发布Service定义
+func (d *MemoryDiscovery) PublishService(etype flux.EventType, service flux.BackendService) {
+	evt := flux.BackendServiceEvent{EventType: etype, Service: service}
+	d.mutex.Lock()
+	events := d.services
+	if nil == events {
+		d.pendingSv = append(d.pendingSv, evt)
+	}
+	d.mutex.Unlock()
+	if nil != events {
+		events <- evt
+	}
+}
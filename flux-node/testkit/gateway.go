@@ -0,0 +1,286 @@
+package testkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/boot"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	defaultWaitTimeout = time.Second * 5
+)
+
+type (
+	// GatewayOption 配置函数
+	GatewayOption func(gw *Gateway)
+)
+
+// Gateway 基于内存Discovery、FakeTransport和httptest监听的网关，用于端到端测试Filter和Endpoint定义；
+// 注意：网关使用ext全局注册表，同一测试进程中不能并行运行多个Gateway；
+type Gateway struct {
+	T         testing.TB
+	Server    *boot.BootstrapServer
+	Listener  *HttptestListener
+	Discovery *MemoryDiscovery
+	Transport *FakeTransport
+	client    *http.Client
+	timeout   time.Duration
+	options   []boot.Option
+	routes    map[string]flux.Endpoint
+	services  map[string]struct{}
+	closed    sync.Once
+}
+
+// WithServerOptions 追加BootstrapServer的配置函数，例如WebExchangeHook等
+func WithServerOptions(opts ...boot.Option) GatewayOption {
+	return func(gw *Gateway) {
+		gw.options = append(gw.options, opts...)
+	}
+}
+
+// WithWaitTimeout 设置等待网关启动、路由生效的超时时间
+func WithWaitTimeout(timeout time.Duration) GatewayOption {
+	return func(gw *Gateway) {
+		gw.timeout = timeout
+	}
+}
+
+// NewGateway 创建并启动测试网关；测试结束时自动关闭；
+func NewGateway(t testing.TB, opts ...GatewayOption) *Gateway {
+	t.Helper()
+	gw := &Gateway{
+		T:         t,
+		Listener:  NewHttptestListener(boot.ListenerIdDefault),
+		Discovery: NewMemoryDiscovery(MemoryDiscoveryId),
+		Transport: NewFakeTransport(),
+		client:    &http.Client{},
+		timeout:   defaultWaitTimeout,
+		routes:    make(map[string]flux.Endpoint, 8),
+		services:  make(map[string]struct{}, 8),
+	}
+	for _, opt := range opts {
+		opt(gw)
+	}
+	ext.RegisterBackendTransport(ProtoFake, gw.Transport)
+	gw.Server = boot.NewBootstrapServerWith(append([]boot.Option{
+		boot.WithServerBanner(""),
+		boot.WithVersionLookupFunc(func(webex flux.WebExchange) string {
+			return webex.HeaderVar(boot.DefaultHttpHeaderVersion)
+		}),
+		boot.WithWebListener(gw.Listener),
+		boot.WithEndpointDiscoveries(gw.Discovery),
+	}, gw.options...)...)
+	if err := gw.start(); nil != err {
+		t.Fatalf("testkit: start gateway, error: %s", err)
+	}
+	t.Cleanup(gw.Close)
+	return gw
+}
+
+func (g *Gateway) start() error {
+	if err := g.Server.Prepare(); nil != err {
+		return err
+	}
+	if err := g.Server.Initial(); nil != err {
+		return err
+	}
+	errch := make(chan error, 1)
+	go func() {
+		errch <- g.Server.Startup(flux.Build{})
+	}()
+	select {
+	case <-g.Listener.Started():
+		return nil
+	case err := <-errch:
+		return err
+	case <-time.After(g.timeout):
+		return fmt.Errorf("timeout waiting gateway startup")
+	}
+}
+
+// URL 返回网关的访问地址
+func (g *Gateway) URL() string {
+	return g.Listener.URL()
+}
+
+// AddEndpoint 发布Endpoint并等待路由生效；未指定rpcproto时，使用FakeTransport处理请求；
+func (g *Gateway) AddEndpoint(endpoint flux.Endpoint) {
+	g.T.Helper()
+	if "" == endpoint.Service.AttrRpcProto() {
+		endpoint.Service.Attributes = withFakeProto(endpoint.Service.Attributes)
+	}
+	key := routeKeyOf(&endpoint)
+	g.routes[key+"#"+endpoint.Version] = endpoint
+	g.Discovery.PublishEndpoint(flux.EventTypeAdded, endpoint)
+	expected := digestOf(endpoint)
+	// 等待路由已添加到Listener，避免请求与路由注册并发
+	g.waitFor("endpoint "+key, func() bool {
+		if !g.Listener.HasHandler(endpoint.HttpMethod, endpoint.HttpPattern) {
+			return false
+		}
+		mve, ok := ext.EndpointByKey(key)
+		if !ok {
+			return false
+		}
+		ep, ok := mve.ToSerializable()[endpoint.Version]
+		return ok && digestOf(*ep) == expected
+	})
+}
+
+// RemoveEndpoint 删除Endpoint并等待生效
+func (g *Gateway) RemoveEndpoint(endpoint flux.Endpoint) {
+	g.T.Helper()
+	key := routeKeyOf(&endpoint)
+	delete(g.routes, key+"#"+endpoint.Version)
+	if !g.removeEndpoint(endpoint) {
+		g.T.Fatalf("testkit: timeout waiting remove endpoint %s", key)
+	}
+}
+
+// AddService 发布Service并等待生效
+func (g *Gateway) AddService(service flux.BackendService) {
+	g.T.Helper()
+	if "" == service.AttrRpcProto() {
+		service.Attributes = withFakeProto(service.Attributes)
+	}
+	id := service.ServiceId
+	if "" == id {
+		id = service.ServiceID()
+	}
+	g.services[id] = struct{}{}
+	g.Discovery.PublishService(flux.EventTypeAdded, service)
+	expected := digestOf(service)
+	g.waitFor("service "+id, func() bool {
+		srv, ok := ext.BackendServiceById(id)
+		return ok && digestOf(srv) == expected
+	})
+}
+
+// Do 发送请求；请求URL为相对路径时，使用网关地址；
+func (g *Gateway) Do(req *http.Request) *Response {
+	g.T.Helper()
+	if !req.URL.IsAbs() {
+		base, _ := url.Parse(g.URL())
+		req.URL = base.ResolveReference(req.URL)
+		req.Host = req.URL.Host
+	}
+	resp, err := g.client.Do(req)
+	if nil != err {
+		g.T.Fatalf("testkit: send request, error: %s", err)
+		return nil
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		g.T.Fatalf("testkit: read response, error: %s", err)
+		return nil
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+}
+
+// Request 发送指定Method、路径和Body的请求
+func (g *Gateway) Request(method, path string, header http.Header, body io.Reader) *Response {
+	g.T.Helper()
+	req, err := http.NewRequest(method, g.URL()+path, body)
+	if nil != err {
+		g.T.Fatalf("testkit: new request, error: %s", err)
+		return nil
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	return g.Do(req)
+}
+
+// Get 发送GET请求
+func (g *Gateway) Get(path string) *Response {
+	g.T.Helper()
+	return g.Request(http.MethodGet, path, nil, nil)
+}
+
+// PostForm 发送表单POST请求
+func (g *Gateway) PostForm(path string, values url.Values) *Response {
+	g.T.Helper()
+	return g.Request(http.MethodPost, path, http.Header{
+		"Content-Type": []string{"application/x-www-form-urlencoded"},
+	}, strings.NewReader(values.Encode()))
+}
+
+// Close 关闭网关，并清除注册到全局注册表的Endpoint和Service；
+// Endpoint在关闭前通过Discovery删除，避免后续的Gateway路由到已删除的版本；
+func (g *Gateway) Close() {
+	g.closed.Do(func() {
+		for key, endpoint := range g.routes {
+			if !g.removeEndpoint(endpoint) {
+				g.T.Logf("testkit: timeout waiting remove endpoint %s", key)
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+		defer cancel()
+		if err := g.Server.Shutdown(ctx); nil != err {
+			g.T.Logf("testkit: shutdown gateway, error: %s", err)
+		}
+		for id := range g.services {
+			ext.RemoveBackendService(id)
+		}
+	})
+}
+
+func (g *Gateway) removeEndpoint(endpoint flux.Endpoint) bool {
+	key := routeKeyOf(&endpoint)
+	g.Discovery.PublishEndpoint(flux.EventTypeRemoved, endpoint)
+	return g.waitUntil(func() bool {
+		mve, ok := ext.EndpointByKey(key)
+		if !ok {
+			return true
+		}
+		_, ok = mve.ToSerializable()[endpoint.Version]
+		return !ok
+	})
+}
+
+func (g *Gateway) waitFor(what string, cond func() bool) {
+	g.T.Helper()
+	if !g.waitUntil(cond) {
+		g.T.Fatalf("testkit: timeout waiting %s", what)
+	}
+}
+
+func (g *Gateway) waitUntil(cond func() bool) bool {
+	deadline := time.Now().Add(g.timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+	return true
+}
+
+func withFakeProto(attrs []flux.Attribute) []flux.Attribute {
+	out := make([]flux.Attribute, 0, len(attrs)+1)
+	out = append(out, attrs...)
+	return append(out, flux.Attribute{Name: flux.ServiceAttrTagRpcProto, Value: ProtoFake})
+}
+
+func routeKeyOf(endpoint *flux.Endpoint) string {
+	return fmt.Sprintf("%s#%s", strings.ToUpper(endpoint.HttpMethod), endpoint.HttpPattern)
+}
+
+func digestOf(v interface{}) string {
+	bytes, _ := json.Marshal(v)
+	return string(bytes)
+}
@@ -0,0 +1,164 @@
+package testkit
+
+import (
+	"encoding/json"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/boot"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// 测试使用标准库JSON序列化，避免依赖jsoniter的运行时反射实现
+type stdJsonSerializer struct{}
+
+func (stdJsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdJsonSerializer) Unmarshal(d []byte, v interface{}) error {
+	return json.Unmarshal(d, v)
+}
+
+func init() {
+	ext.RegisterSerializer(ext.TypeNameSerializerDefault, stdJsonSerializer{})
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, stdJsonSerializer{})
+}
+
+func TestGateway_RouteEndpoint(t *testing.T) {
+	gw := NewGateway(t, WithServerOptions(boot.WithWebExchangeHooks(func(webex flux.WebExchange, ctx flux.Context) {
+		ctx.SetAttribute("tenant", webex.HeaderVar("X-Tenant"))
+	})))
+	gw.AddEndpoint(flux.Endpoint{
+		HttpMethod: "POST", HttpPattern: "/users/:id", Version: "v1",
+		Service: flux.BackendService{
+			Interface: "com.foo.UserService", Method: "update",
+			Arguments: []flux.Argument{
+				ext.NewLongArgument("id"),
+				ext.NewStringArgument("name"),
+			},
+		},
+	})
+	gw.PostForm("/users/123", url.Values{"name": []string{"flux"}}).
+		AssertStatus(t, http.StatusOK).
+		AssertJSON(t, map[string]interface{}{"id": 123, "name": "flux"})
+	gw.AssertInvoked("com.foo.UserService", "update").
+		AssertArgument(t, "id", int64(123)).
+		AssertArgument(t, "name", "flux")
+
+	gw.Transport.Reset()
+	gw.Transport.Respond(http.StatusAccepted, http.Header{"X-Result": []string{"ok"}}, map[string]string{"status": "accepted"})
+	gw.Request(http.MethodPost, "/users/456", http.Header{"X-Tenant": []string{"t1"}}, nil).
+		AssertStatus(t, http.StatusAccepted).
+		AssertHeader(t, "X-Result", "ok").
+		AssertJSON(t, `{"status":"accepted"}`)
+	gw.AssertInvoked("com.foo.UserService", "update").
+		AssertArgument(t, "id", int64(456)).
+		AssertAttachment(t, "tenant", "t1")
+}
+
+func TestGateway_RemoveEndpoint(t *testing.T) {
+	gw := NewGateway(t)
+	endpoint := flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/ping", Version: "v1",
+		Service: flux.BackendService{Interface: "com.foo.Ping", Method: "ping"},
+	}
+	gw.AddEndpoint(endpoint)
+	gw.Get("/ping").AssertStatus(t, http.StatusOK)
+	gw.RemoveEndpoint(endpoint)
+	gw.Transport.Reset()
+	gw.Get("/ping").AssertStatus(t, http.StatusNotFound)
+	gw.AssertNotInvoked()
+}
+
+func TestGateway_Sequential(t *testing.T) {
+	endpoint := flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/seq", Version: "v1",
+		Service: flux.BackendService{Interface: "com.foo.Seq", Method: "get"},
+	}
+	first := NewGateway(t)
+	first.AddEndpoint(endpoint)
+	first.Get("/seq").AssertStatus(t, http.StatusOK)
+	first.Close()
+	// 全局注册表中已存在相同的路由Key，新的网关仍需绑定路由
+	second := NewGateway(t)
+	second.AddEndpoint(endpoint)
+	second.Get("/seq").AssertStatus(t, http.StatusOK)
+	second.AssertInvoked("com.foo.Seq", "get")
+}
+
+func TestGateway_NegotiateResponse(t *testing.T) {
+	gw := NewGateway(t)
+	gw.AddEndpoint(flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/users/:id", Version: "v1",
+		Service: flux.BackendService{
+			Interface: "com.foo.UserService", Method: "get",
+			Arguments: []flux.Argument{ext.NewLongArgument("id")},
+		},
+	})
+	gw.AddEndpoint(flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/orders/:id", Version: "v1",
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+			{Name: flux.EndpointAttrTagProduces, Value: "xml,json"},
+		}},
+		Service: flux.BackendService{
+			Interface: "com.foo.OrderService", Method: "get",
+			Arguments: []flux.Argument{ext.NewLongArgument("id")},
+		},
+	})
+	gw.Get("/users/1").
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, flux.HeaderContentType, flux.MIMEApplicationJSON).
+		AssertJSON(t, `{"id":1}`)
+	// 未定义响应格式的Endpoint只响应JSON
+	gw.Request(http.MethodGet, "/users/1", http.Header{flux.HeaderAccept: []string{"application/xml"}}, nil).
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, flux.HeaderContentType, flux.MIMEApplicationJSON).
+		AssertJSON(t, `{"id":1}`)
+	gw.Request(http.MethodGet, "/orders/1", http.Header{flux.HeaderAccept: []string{"application/xml"}}, nil).
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, flux.HeaderContentType, flux.MIMEApplicationXML).
+		AssertHeader(t, flux.HeaderVary, flux.HeaderAccept).
+		AssertBodyContains(t, "<response><id>1</id></response>")
+	// Endpoint定义的默认格式
+	gw.Get("/orders/2").
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, flux.HeaderContentType, flux.MIMEApplicationXML).
+		AssertBodyContains(t, "<response><id>2</id></response>")
+	gw.Request(http.MethodGet, "/orders/2", http.Header{flux.HeaderAccept: []string{"application/json"}}, nil).
+		AssertHeader(t, flux.HeaderContentType, flux.MIMEApplicationJSON).
+		AssertJSON(t, `{"id":2}`)
+	// 错误响应按协商格式输出
+	gw.Transport.SetResponder(func(inv *Invocation) (*flux.BackendResponse, *flux.ServeError) {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadRequest,
+			ErrorCode:  flux.ErrorCodeRequestInvalid,
+			Message:    "INVALID_ORDER",
+		}
+	})
+	gw.Get("/orders/3").
+		AssertStatus(t, http.StatusBadRequest).
+		AssertHeader(t, flux.HeaderContentType, flux.MIMEApplicationXML).
+		AssertBodyContains(t, "<message>INVALID_ORDER</message>")
+}
+
+func TestGateway_TransformResponse(t *testing.T) {
+	gw := NewGateway(t)
+	gw.AddEndpoint(flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/profile", Version: "v1",
+		EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: map[string]interface{}{
+			"transform": map[string]interface{}{
+				"stripClass": true, "unwrap": "data", "exclude": []string{"password"}, "wrap": "result",
+			},
+		}},
+		Service: flux.BackendService{Interface: "com.foo.ProfileService", Method: "get"},
+	})
+	gw.Transport.Respond(http.StatusOK, nil, map[interface{}]interface{}{
+		"class": "com.foo.Result",
+		"data":  map[interface{}]interface{}{"class": "com.foo.Profile", "name": "flux", "password": "secret"},
+	})
+	gw.Get("/profile").
+		AssertStatus(t, http.StatusOK).
+		AssertJSON(t, `{"result":{"name":"flux"}}`)
+}
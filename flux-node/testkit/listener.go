@@ -0,0 +1,101 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/echoserver"
+	"github.com/bytepowered/flux/flux-node/listener"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+var (
+	_ flux.WebListener = new(HttptestListener)
+)
+
+// HttptestListener 基于httptest.Server的WebListener；路由和响应处理由默认的echo实现完成，
+// 只替换监听端口的启动和关闭；
+type HttptestListener struct {
+	flux.WebListener
+	server   *httptest.Server
+	started  chan struct{}
+	closed   chan struct{}
+	once     sync.Once
+	handlers map[string]struct{}
+	mutex    sync.Mutex
+}
+
+func NewHttptestListener(id string) *HttptestListener {
+	return &HttptestListener{
+		WebListener: listener.New(id, flux.NewEmptyConfiguration(), nil),
+		started:     make(chan struct{}),
+		closed:      make(chan struct{}),
+		handlers:    make(map[string]struct{}, 8),
+	}
+}
+
+// AddHandler 添加路由处理函数，并记录已添加的路由
+func (l *HttptestListener) AddHandler(method, pattern string, h flux.WebHandler, m ...flux.WebInterceptor) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.WebListener.AddHandler(method, pattern, h, m...)
+	l.handlers[strings.ToUpper(method)+"#"+pattern] = struct{}{}
+}
+
+// HasHandler 返回路由是否已添加；返回true之后发送的请求，可以路由到此处理函数
+func (l *HttptestListener) HasHandler(method, pattern string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, ok := l.handlers[strings.ToUpper(method)+"#"+pattern]
+	return ok
+}
+
+// Init 忽略监听地址配置，由httptest分配本地端口
+func (l *HttptestListener) Init(_ *flux.Configuration) error {
+	return l.WebListener.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		echoserver.ConfigKeyAddress:  "127.0.0.1",
+		echoserver.ConfigKeyBindPort: 0,
+	}))
+}
+
+// Listen 启动httptest.Server，阻塞直到关闭
+func (l *HttptestListener) Listen() error {
+	handler, ok := l.ShadowServer().(http.Handler)
+	if !ok {
+		return errors.New("shadow server is not a http.Handler")
+	}
+	l.server = httptest.NewServer(handler)
+	close(l.started)
+	<-l.closed
+	return http.ErrServerClosed
+}
+
+func (l *HttptestListener) Close(_ context.Context) error {
+	l.once.Do(func() {
+		select {
+		case <-l.started:
+			l.server.Close()
+		default:
+		}
+		close(l.closed)
+	})
+	return nil
+}
+
+// Started 返回监听已启动的通知Channel
+func (l *HttptestListener) Started() <-chan struct{} {
+	return l.started
+}
+
+// URL 返回httptest.Server的访问地址；未启动时返回空字符串；
+func (l *HttptestListener) URL() string {
+	select {
+	case <-l.started:
+		return l.server.URL
+	default:
+		return ""
+	}
+}
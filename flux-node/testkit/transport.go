@@ -0,0 +1,156 @@
+package testkit
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/backend"
+	"net/http"
+	"sync"
+)
+
+const (
+	// ProtoFake 测试后端协议；Endpoint的Service属性 rpcproto 设置为此协议时，请求由FakeTransport处理
+	ProtoFake = "FAKE"
+)
+
+var (
+	_ flux.BackendTransport = new(FakeTransport)
+)
+
+// Invocation 记录一次后端服务调用
+type Invocation struct {
+	RequestId string
+	Service   flux.BackendService
+	// Arguments 按参数名称索引的已解析参数值
+	Arguments map[string]interface{}
+	// Attachments 调用时的Context属性，与Dubbo等协议的Attachment一致
+	Attachments map[string]interface{}
+}
+
+// Argument 返回指定名称的已解析参数值
+func (i Invocation) Argument(name string) (interface{}, bool) {
+	v, ok := i.Arguments[name]
+	return v, ok
+}
+
+// Responder 根据调用返回后端响应
+type Responder func(inv *Invocation) (*flux.BackendResponse, *flux.ServeError)
+
+// FakeTransport 记录调用并返回预设响应的后端协议实现
+type FakeTransport struct {
+	invocations []Invocation
+	responder   Responder
+	mutex       sync.RWMutex
+}
+
+func NewFakeTransport() *FakeTransport {
+	return &FakeTransport{
+		invocations: make([]Invocation, 0, 8),
+		responder:   DefaultResponder,
+	}
+}
+
+// DefaultResponder 默认响应：返回已解析的参数值
+func DefaultResponder(inv *Invocation) (*flux.BackendResponse, *flux.ServeError) {
+	return &flux.BackendResponse{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{},
+		Body:       inv.Arguments,
+	}, nil
+}
+
+// SetResponder 设置响应函数
+func (f *FakeTransport) SetResponder(responder Responder) {
+	f.mutex.Lock()
+	f.responder = responder
+	f.mutex.Unlock()
+}
+
+// Respond 设置固定的状态码和响应数据
+func (f *FakeTransport) Respond(status int, headers http.Header, body interface{}) {
+	if nil == headers {
+		headers = http.Header{}
+	}
+	f.SetResponder(func(*Invocation) (*flux.BackendResponse, *flux.ServeError) {
+		return &flux.BackendResponse{StatusCode: status, Headers: headers, Body: body}, nil
+	})
+}
+
+// Invocations 返回全部调用记录
+func (f *FakeTransport) Invocations() []Invocation {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	out := make([]Invocation, len(f.invocations))
+	copy(out, f.invocations)
+	return out
+}
+
+// LastInvocation 返回最后一次调用记录
+func (f *FakeTransport) LastInvocation() (Invocation, bool) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	if len(f.invocations) == 0 {
+		return Invocation{}, false
+	}
+	return f.invocations[len(f.invocations)-1], true
+}
+
+// Reset 清除调用记录，恢复默认响应
+func (f *FakeTransport) Reset() {
+	f.mutex.Lock()
+	f.invocations = f.invocations[:0]
+	f.responder = DefaultResponder
+	f.mutex.Unlock()
+}
+
+func (f *FakeTransport) Exchange(ctx flux.Context) *flux.ServeError {
+	return backend.DoExchangeTransport(ctx, f)
+}
+
+func (f *FakeTransport) Invoke(ctx flux.Context, service flux.BackendService) (interface{}, *flux.ServeError) {
+	args := make(map[string]interface{}, len(service.Arguments))
+	for _, arg := range service.Arguments {
+		value, err := arg.Resolve(ctx)
+		if nil != err {
+			return nil, &flux.ServeError{
+				StatusCode: flux.StatusBadRequest,
+				ErrorCode:  flux.ErrorCodeRequestInvalid,
+				Message:    "BACKEND:FAKE:ASSEMBLE",
+				CauseError: err,
+			}
+		}
+		args[arg.Name] = value
+	}
+	attachments := make(map[string]interface{}, len(ctx.Attributes()))
+	for k, v := range ctx.Attributes() {
+		attachments[k] = v
+	}
+	inv := Invocation{
+		RequestId:   ctx.RequestId(),
+		Service:     service,
+		Arguments:   args,
+		Attachments: attachments,
+	}
+	f.mutex.Lock()
+	f.invocations = append(f.invocations, inv)
+	responder := f.responder
+	f.mutex.Unlock()
+	resp, err := responder(&inv)
+	if nil != err {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (f *FakeTransport) InvokeCodec(ctx flux.Context, service flux.BackendService) (*flux.BackendResponse, *flux.ServeError) {
+	resp, err := f.Invoke(ctx, service)
+	if nil != err {
+		return nil, err
+	}
+	return resp.(*flux.BackendResponse), nil
+}
+
+func (f *FakeTransport) GetResponseCodecFunc() flux.BackendResponseCodecFunc {
+	return func(ctx flux.Context, raw interface{}) (*flux.BackendResponse, error) {
+		return raw.(*flux.BackendResponse), nil
+	}
+}
@@ -0,0 +1,222 @@
+package transform
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/spf13/cast"
+	"io"
+	"strings"
+)
+
+// Endpoint定义的响应转换规则扩展信息（Extensions）
+const (
+	ExtensionTransform = "transform"
+)
+
+// 响应转换规则的配置键
+const (
+	KeyStripClass = "stripClass" // 是否移除Hessian序列化的 class 字段
+	KeyUnwrap     = "unwrap"     // 取出嵌套字段作为响应数据，多级字段使用 . 分隔，例如：data.result
+	KeyInclude    = "include"    // 字段白名单，只保留指定的字段
+	KeyExclude    = "exclude"    // 字段黑名单，移除指定的字段
+	KeyRename     = "rename"     // 字段重命名，格式：原字段名 -> 新字段名
+	KeyWrap       = "wrap"       // 将响应数据包装到指定字段
+	KeyEnvelope   = "envelope"   // 包装响应数据时，附加的固定字段
+)
+
+const (
+	// Hessian序列化Java对象时附加的类型字段
+	hessianClassKey = "class"
+)
+
+// Rules 响应数据转换规则；按以下顺序执行：
+// 1. 将 map[interface{}]interface{} 转换为 map[string]interface{}，并按 StripClass 移除 class 字段；
+// 2. Unwrap 取出嵌套字段；
+// 3. Include/Exclude 过滤字段，Rename 重命名字段；响应数据为列表时，作用于列表的每个元素；
+// 4. Wrap 包装到信封字段，并附加 Envelope 定义的固定字段；
+type Rules struct {
+	StripClass bool
+	Unwrap     []string
+	Include    []string
+	Exclude    []string
+	Rename     map[string]string
+	Wrap       string
+	Envelope   map[string]interface{}
+}
+
+// ParseRules 解析Endpoint定义的响应转换规则；未定义时返回nil
+func ParseRules(endpoint flux.Endpoint) (*Rules, error) {
+	define, ok := endpoint.GetValue(ExtensionTransform)
+	if !ok || nil == define {
+		return nil, nil
+	}
+	values, err := cast.ToStringMapE(define)
+	if nil != err {
+		return nil, fmt.Errorf("invalid transform extension, error: %w", err)
+	}
+	out := &Rules{}
+	if v, ok := values[KeyStripClass]; ok {
+		if out.StripClass, err = cast.ToBoolE(v); nil != err {
+			return nil, fmt.Errorf("invalid transform %s: %v", KeyStripClass, v)
+		}
+	}
+	if v := strings.TrimSpace(cast.ToString(values[KeyUnwrap])); "" != v {
+		out.Unwrap = strings.Split(v, ".")
+	}
+	if v, ok := values[KeyInclude]; ok {
+		if out.Include, err = cast.ToStringSliceE(v); nil != err {
+			return nil, fmt.Errorf("invalid transform %s: %v", KeyInclude, v)
+		}
+	}
+	if v, ok := values[KeyExclude]; ok {
+		if out.Exclude, err = cast.ToStringSliceE(v); nil != err {
+			return nil, fmt.Errorf("invalid transform %s: %v", KeyExclude, v)
+		}
+	}
+	if len(out.Include) > 0 && len(out.Exclude) > 0 {
+		return nil, fmt.Errorf("transform %s and %s are exclusive", KeyInclude, KeyExclude)
+	}
+	if v, ok := values[KeyRename]; ok {
+		if out.Rename, err = cast.ToStringMapStringE(v); nil != err {
+			return nil, fmt.Errorf("invalid transform %s: %v", KeyRename, v)
+		}
+	}
+	out.Wrap = strings.TrimSpace(cast.ToString(values[KeyWrap]))
+	if v, ok := values[KeyEnvelope]; ok {
+		if out.Envelope, err = cast.ToStringMapE(v); nil != err {
+			return nil, fmt.Errorf("invalid transform %s: %v", KeyEnvelope, v)
+		}
+		if "" == out.Wrap && len(out.Envelope) > 0 {
+			return nil, fmt.Errorf("transform %s requires %s", KeyEnvelope, KeyWrap)
+		}
+	}
+	return out, nil
+}
+
+// Apply 按规则转换响应数据；原始数据（[]byte、string、io.Reader、流式响应）不做转换；
+// Unwrap 的字段不存在时，返回空Map；
+func (r *Rules) Apply(body interface{}) interface{} {
+	if nil == r || IsRawBody(body) {
+		return body
+	}
+	out := Normalize(body, r.StripClass)
+	if len(r.Unwrap) > 0 {
+		out = unwrap(out, r.Unwrap)
+		if nil == out {
+			out = make(map[string]interface{}, 0)
+		}
+	}
+	out = r.fields(out)
+	if "" != r.Wrap {
+		wrapped := make(map[string]interface{}, len(r.Envelope)+1)
+		for k, v := range r.Envelope {
+			wrapped[k] = v
+		}
+		wrapped[r.Wrap] = out
+		out = wrapped
+	}
+	return out
+}
+
+// fields 过滤和重命名字段；响应数据为列表时，作用于列表的每个元素
+func (r *Rules) fields(body interface{}) interface{} {
+	if len(r.Include) == 0 && len(r.Exclude) == 0 && len(r.Rename) == 0 {
+		return body
+	}
+	switch v := body.(type) {
+	case map[string]interface{}:
+		return r.fieldsOfMap(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				out[i] = r.fieldsOfMap(m)
+			} else {
+				out[i] = item
+			}
+		}
+		return out
+	default:
+		return body
+	}
+}
+
+func (r *Rules) fieldsOfMap(in map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(in))
+	if len(r.Include) > 0 {
+		for _, k := range r.Include {
+			if v, ok := in[k]; ok {
+				out[k] = v
+			}
+		}
+	} else {
+		for k, v := range in {
+			out[k] = v
+		}
+		for _, k := range r.Exclude {
+			delete(out, k)
+		}
+	}
+	for from, to := range r.Rename {
+		if v, ok := out[from]; ok {
+			delete(out, from)
+			out[to] = v
+		}
+	}
+	return out
+}
+
+// Normalize 将Hessian等反序列化产生的 map[interface{}]interface{} 递归转换为 map[string]interface{}；
+// stripClass 为true时，移除Map中的 class 字段；
+func Normalize(value interface{}, stripClass bool) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, iv := range v {
+			key := cast.ToString(k)
+			if stripClass && hessianClassKey == key {
+				continue
+			}
+			out[key] = Normalize(iv, stripClass)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, iv := range v {
+			if stripClass && hessianClassKey == k {
+				continue
+			}
+			out[k] = Normalize(iv, stripClass)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, iv := range v {
+			out[i] = Normalize(iv, stripClass)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// IsRawBody 判断响应数据是否为无需转换的原始数据
+func IsRawBody(body interface{}) bool {
+	switch body.(type) {
+	case []byte, string, io.Reader, *flux.StreamBody:
+		return true
+	default:
+		return false
+	}
+}
+
+func unwrap(body interface{}, path []string) interface{} {
+	for _, key := range path {
+		m, ok := body.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		body = m[key]
+	}
+	return body
+}
@@ -0,0 +1,118 @@
+package transform
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newEndpoint(define interface{}) flux.Endpoint {
+	return flux.Endpoint{
+		EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: map[string]interface{}{
+			ExtensionTransform: define,
+		}},
+	}
+}
+
+func TestRules_Apply(t *testing.T) {
+	// Hessian反序列化的Java对象
+	user := func() interface{} {
+		return map[interface{}]interface{}{
+			"class":    "com.foo.User",
+			"id":       int64(1),
+			"userName": "flux",
+			"password": "secret",
+			"address":  map[interface{}]interface{}{"class": "com.foo.Address", "city": "SZ"},
+		}
+	}
+	result := func(data interface{}) interface{} {
+		return map[interface{}]interface{}{"class": "com.foo.Result", "code": 0, "data": data}
+	}
+	cases := []struct {
+		name     string
+		define   interface{}
+		body     interface{}
+		expected interface{}
+	}{
+		{
+			name:   "normalize",
+			define: map[string]interface{}{},
+			body:   user(),
+			expected: map[string]interface{}{"class": "com.foo.User", "id": int64(1), "userName": "flux", "password": "secret",
+				"address": map[string]interface{}{"class": "com.foo.Address", "city": "SZ"}},
+		},
+		{
+			name:   "strip class and exclude",
+			define: map[string]interface{}{"stripClass": true, "exclude": []string{"password"}},
+			body:   user(),
+			expected: map[string]interface{}{"id": int64(1), "userName": "flux",
+				"address": map[string]interface{}{"city": "SZ"}},
+		},
+		{
+			name:     "unwrap, include and rename",
+			define:   map[interface{}]interface{}{"unwrap": "data", "include": []interface{}{"id", "userName"}, "rename": map[interface{}]interface{}{"userName": "name"}},
+			body:     result(user()),
+			expected: map[string]interface{}{"id": int64(1), "name": "flux"},
+		},
+		{
+			name:     "unwrap nested list",
+			define:   map[string]interface{}{"unwrap": "data.items", "include": []string{"id"}},
+			body:     result(map[string]interface{}{"items": []interface{}{user(), user()}}),
+			expected: []interface{}{map[string]interface{}{"id": int64(1)}, map[string]interface{}{"id": int64(1)}},
+		},
+		{
+			name:     "unwrap missing",
+			define:   map[string]interface{}{"unwrap": "data.items"},
+			body:     result(nil),
+			expected: map[string]interface{}{},
+		},
+		{
+			name:     "wrap with envelope",
+			define:   map[string]interface{}{"stripClass": true, "include": []string{"id"}, "wrap": "data", "envelope": map[string]interface{}{"code": 0, "msg": "ok"}},
+			body:     user(),
+			expected: map[string]interface{}{"code": 0, "msg": "ok", "data": map[string]interface{}{"id": int64(1)}},
+		},
+		{
+			name:     "raw body",
+			define:   map[string]interface{}{"wrap": "data"},
+			body:     []byte("raw"),
+			expected: []byte("raw"),
+		},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		rules, err := ParseRules(newEndpoint(tcase.define))
+		if !assert.NoError(err, tcase.name) {
+			continue
+		}
+		assert.Equal(tcase.expected, rules.Apply(tcase.body), tcase.name)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	cases := []struct {
+		name   string
+		define interface{}
+		error  bool
+	}{
+		{name: "undefined", define: nil},
+		{name: "invalid define", define: "stripClass", error: true},
+		{name: "invalid stripClass", define: map[string]interface{}{"stripClass": "yes"}, error: true},
+		{name: "include and exclude", define: map[string]interface{}{"include": []string{"id"}, "exclude": []string{"id"}}, error: true},
+		{name: "envelope without wrap", define: map[string]interface{}{"envelope": map[string]interface{}{"code": 0}}, error: true},
+	}
+	assert := assert2.New(t)
+	for _, tcase := range cases {
+		rules, err := ParseRules(newEndpoint(tcase.define))
+		if tcase.error {
+			assert.Error(err, tcase.name)
+		} else {
+			assert.NoError(err, tcase.name)
+			assert.Nil(rules, tcase.name)
+		}
+	}
+	// 未定义转换规则时，不转换响应数据
+	var rules *Rules
+	body := map[interface{}]interface{}{"class": "x"}
+	assert.Equal(body, rules.Apply(body))
+}
@@ -0,0 +1,375 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	// 注册内置的参数值类型解析函数
+	_ "github.com/bytepowered/flux/flux-node/common"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/transform"
+	"strings"
+	"time"
+)
+
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+// 校验规则名称
+const (
+	RuleRequired        = "required"
+	RuleHttpScope       = "http-scope"
+	RuleArgumentClass   = "argument-class"
+	RuleDuplicateArg    = "duplicate-argument"
+	RuleRpcProto        = "rpc-proto"
+	RuleRpcTimeout      = "rpc-timeout"
+	RulePermissionId    = "permission-id"
+	RulePathVariable    = "path-variable"
+	RuleHttpMethod      = "http-method"
+	RuleDuplicateDefine = "duplicate-definition"
+	RuleTransform       = "transform"
+)
+
+var (
+	// 参数值域，与参数查找函数支持的值域一致
+	knownHttpScopes = map[string]struct{}{
+		flux.ScopePath: {}, flux.ScopePathMap: {},
+		flux.ScopeQuery: {}, flux.ScopeQueryMulti: {}, flux.ScopeQueryMap: {},
+		flux.ScopeForm: {}, flux.ScopeFormMulti: {}, flux.ScopeFormMap: {},
+		flux.ScopeParam: {}, flux.ScopeHeader: {}, flux.ScopeHeaderMap: {},
+		flux.ScopeAttr: {}, flux.ScopeAttrs: {},
+		flux.ScopeBody: {}, flux.ScopeRequest: {}, flux.ScopeAuto: {},
+	}
+	defaultProtocols = []string{flux.ProtoDubbo, flux.ProtoGRPC, flux.ProtoHttp, flux.ProtoEcho, flux.ProtoMock}
+	knownHttpMethods = []string{"GET", "POST", "DELETE", "PUT", "HEAD", "OPTIONS", "PATCH", "TRACE"}
+)
+
+// Issue 校验发现的问题
+type Issue struct {
+	Level   string `json:"level"`
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("[%s] %s: %s (%s)", i.Level, i.Field, i.Message, i.Rule)
+}
+
+// Issues 校验结果
+type Issues []Issue
+
+// HasErrors 判断是否存在错误级别的问题
+func (s Issues) HasErrors() bool {
+	for _, i := range s {
+		if i.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors 返回错误级别的问题；没有错误时返回nil；
+func (s Issues) Errors() error {
+	msgs := make([]string, 0, len(s))
+	for _, i := range s {
+		if i.Level == LevelError {
+			msgs = append(msgs, i.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+type (
+	// Option 配置函数
+	Option func(v *Validator)
+)
+
+// Validator 校验Endpoint和Service定义；网关的Discovery、Admin接口和命令行工具使用相同的校验规则，
+// 在定义发布之前发现配置错误；
+type Validator struct {
+	protocols     map[string]struct{}
+	classResolver func(class string) bool
+	serviceLookup func(serviceId string) bool
+}
+
+// WithProtocols 追加支持的rpcproto协议；内置协议总是支持的；
+func WithProtocols(protos ...string) Option {
+	return func(v *Validator) {
+		for _, p := range protos {
+			v.protocols[p] = struct{}{}
+		}
+	}
+}
+
+// WithClassResolver 指定判断参数类型是否可解析的函数；默认查找已注册的值类型解析函数；
+func WithClassResolver(f func(class string) bool) Option {
+	return func(v *Validator) {
+		v.classResolver = f
+	}
+}
+
+// WithServiceLookup 指定判断权限服务ID是否存在的函数；未指定时不检查权限服务是否存在；
+func WithServiceLookup(f func(serviceId string) bool) Option {
+	return func(v *Validator) {
+		v.serviceLookup = f
+	}
+}
+
+func NewValidatorWith(opts ...Option) *Validator {
+	v := &Validator{
+		protocols:     make(map[string]struct{}, len(defaultProtocols)),
+		classResolver: ext.HasMTValueResolver,
+	}
+	WithProtocols(defaultProtocols...)(v)
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// ValidateEndpoint 校验Endpoint定义，包括后端服务和权限服务定义
+func (v *Validator) ValidateEndpoint(endpoint *flux.Endpoint) Issues {
+	issues := make(Issues, 0)
+	add := func(level, rule, field, msg string) {
+		issues = append(issues, Issue{Level: level, Rule: rule, Field: field, Message: msg})
+	}
+	if "" == endpoint.HttpPattern {
+		add(LevelError, RuleRequired, "httpPattern", "httpPattern is required")
+	}
+	if method := strings.ToUpper(endpoint.HttpMethod); "" == method {
+		add(LevelError, RuleRequired, "httpMethod", "httpMethod is required")
+	} else if !containsString(knownHttpMethods, method) {
+		add(LevelError, RuleHttpMethod, "httpMethod", "unsupported http method: "+endpoint.HttpMethod)
+	}
+	issues = append(issues, v.validateService("service", &endpoint.Service)...)
+	if endpoint.Permission.IsValid() {
+		issues = append(issues, v.validateService("permission", &endpoint.Permission)...)
+	}
+	// 权限服务ID
+	for i, id := range endpoint.Permissions {
+		field := fmt.Sprintf("permissions[%d]", i)
+		if "" == strings.TrimSpace(id) {
+			add(LevelError, RulePermissionId, field, "permission service id is empty")
+		} else if nil != v.serviceLookup && !v.serviceLookup(id) {
+			// 权限服务可能晚于Endpoint发布，只作为警告
+			add(LevelWarning, RulePermissionId, field, "permission service not found: "+id)
+		}
+	}
+	// 响应转换规则
+	if _, err := transform.ParseRules(*endpoint); nil != err {
+		add(LevelError, RuleTransform, "extensions."+transform.ExtensionTransform, err.Error())
+	}
+	// 路径参数：ScopePath参数必须在HttpPattern中定义
+	if "" != endpoint.HttpPattern {
+		vars := PathVariables(endpoint.HttpPattern)
+		for _, ref := range pathArguments("service.arguments", endpoint.Service.Arguments) {
+			if !containsString(vars, ref.name) {
+				add(LevelError, RulePathVariable, ref.field,
+					fmt.Sprintf("path variable '%s' not defined in httpPattern: %s", ref.name, endpoint.HttpPattern))
+			}
+		}
+	}
+	return issues
+}
+
+// ValidateService 校验Service定义
+func (v *Validator) ValidateService(service *flux.BackendService) Issues {
+	return v.validateService("", service)
+}
+
+func (v *Validator) validateService(prefix string, service *flux.BackendService) Issues {
+	issues := make(Issues, 0)
+	field := func(name string) string {
+		if "" == prefix {
+			return name
+		}
+		return prefix + "." + name
+	}
+	add := func(level, rule, name, msg string) {
+		issues = append(issues, Issue{Level: level, Rule: rule, Field: field(name), Message: msg})
+	}
+	if "" == service.Interface {
+		add(LevelError, RuleRequired, "interface", "interface is required")
+	}
+	if "" == service.Method {
+		add(LevelError, RuleRequired, "method", "method is required")
+	}
+	if proto := service.AttrRpcProto(); "" == proto {
+		add(LevelError, RuleRpcProto, "attributes.rpcproto", "rpcproto is required")
+	} else if _, ok := v.protocols[proto]; !ok {
+		add(LevelError, RuleRpcProto, "attributes.rpcproto", "unknown rpcproto: "+proto)
+	}
+	if timeout := service.AttrRpcTimeout(); "" != timeout {
+		if d, err := time.ParseDuration(timeout); nil != err || d <= 0 {
+			add(LevelError, RuleRpcTimeout, "attributes.rpctimeout", "invalid rpctimeout duration: "+timeout)
+		}
+	}
+	return append(issues, v.validateArguments(field("arguments"), service.Arguments)...)
+}
+
+func (v *Validator) validateArguments(prefix string, args []flux.Argument) Issues {
+	issues := make(Issues, 0)
+	names := make(map[string]struct{}, len(args))
+	for i, arg := range args {
+		field := fmt.Sprintf("%s[%d]", prefix, i)
+		add := func(level, rule, msg string) {
+			issues = append(issues, Issue{Level: level, Rule: rule, Field: field, Message: msg})
+		}
+		if "" == arg.Name {
+			add(LevelError, RuleRequired, "argument name is required")
+		} else if _, ok := names[arg.Name]; ok {
+			add(LevelError, RuleDuplicateArg, "duplicate argument name: "+arg.Name)
+		} else {
+			names[arg.Name] = struct{}{}
+		}
+		// 参数类型：未注册解析函数的原始类型，将被错误地按复杂对象解析
+		if "" == arg.Class {
+			add(LevelError, RuleArgumentClass, "argument class is required")
+		} else if strings.EqualFold(flux.ArgumentTypePrimitive, arg.Type) && !v.classResolver(arg.Class) {
+			add(LevelError, RuleArgumentClass, "unresolvable argument class: "+arg.Class)
+		}
+		if len(arg.Fields) > 0 {
+			issues = append(issues, v.validateArguments(field+".fields", arg.Fields)...)
+			continue
+		}
+		if "" == arg.HttpScope {
+			add(LevelError, RuleHttpScope, "httpScope is required")
+		} else if _, ok := knownHttpScopes[strings.ToUpper(arg.HttpScope)]; !ok {
+			add(LevelError, RuleHttpScope, "unknown httpScope: "+arg.HttpScope)
+		}
+	}
+	return issues
+}
+
+type pathArgument struct {
+	field string
+	name  string
+}
+
+func pathArguments(prefix string, args []flux.Argument) []pathArgument {
+	out := make([]pathArgument, 0)
+	for i, arg := range args {
+		field := fmt.Sprintf("%s[%d]", prefix, i)
+		if len(arg.Fields) > 0 {
+			out = append(out, pathArguments(field+".fields", arg.Fields)...)
+		} else if strings.EqualFold(flux.ScopePath, arg.HttpScope) {
+			out = append(out, pathArgument{field: field, name: arg.HttpName})
+		}
+	}
+	return out
+}
+
+// PathVariables 解析HttpPattern中定义的路径参数名称；支持 /users/:id 和 /users/{id} 两种格式；
+func PathVariables(pattern string) []string {
+	vars := make([]string, 0, 2)
+	for _, seg := range strings.Split(pattern, "/") {
+		switch {
+		case strings.HasPrefix(seg, ":") && len(seg) > 1:
+			vars = append(vars, seg[1:])
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") && len(seg) > 2:
+			vars = append(vars, seg[1:len(seg)-1])
+		}
+	}
+	return vars
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Definition 单个Endpoint或者Service定义的校验结果
+type Definition struct {
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+	Issues Issues `json:"issues"`
+}
+
+// Report 一组定义的校验结果
+type Report struct {
+	Valid       bool         `json:"valid"`
+	Errors      int          `json:"errors"`
+	Warnings    int          `json:"warnings"`
+	Definitions []Definition `json:"definitions"`
+}
+
+const (
+	KindEndpoint = "endpoint"
+	KindService  = "service"
+)
+
+// ValidateResources 校验一组Endpoint和Service定义；权限服务ID可以引用同一组定义中的Service；
+// 只返回存在问题的定义；
+func (v *Validator) ValidateResources(endpoints []flux.Endpoint, services []flux.BackendService) Report {
+	defined := make(map[string]struct{}, len(services))
+	for _, srv := range services {
+		defined[srv.ServiceID()] = struct{}{}
+		if "" != srv.ServiceId {
+			defined[srv.ServiceId] = struct{}{}
+		}
+	}
+	lookup := v.serviceLookup
+	scoped := &Validator{
+		protocols:     v.protocols,
+		classResolver: v.classResolver,
+		serviceLookup: func(id string) bool {
+			if _, ok := defined[id]; ok {
+				return true
+			}
+			return nil != lookup && lookup(id)
+		},
+	}
+	report := Report{Definitions: make([]Definition, 0)}
+	collect := func(kind, key string, issues Issues) {
+		if len(issues) == 0 {
+			return
+		}
+		for _, i := range issues {
+			if i.Level == LevelError {
+				report.Errors++
+			} else {
+				report.Warnings++
+			}
+		}
+		report.Definitions = append(report.Definitions, Definition{Kind: kind, Key: key, Issues: issues})
+	}
+	keys := make(map[string]struct{}, len(endpoints))
+	for i := range endpoints {
+		ep := &endpoints[i]
+		key := strings.ToUpper(ep.HttpMethod) + "#" + ep.HttpPattern + "#" + ep.Version
+		issues := scoped.ValidateEndpoint(ep)
+		if _, ok := keys[key]; ok {
+			issues = append(issues, Issue{Level: LevelError, Rule: RuleDuplicateDefine, Field: "httpPattern",
+				Message: "duplicate endpoint definition: " + key})
+		}
+		keys[key] = struct{}{}
+		collect(KindEndpoint, key, issues)
+	}
+	ids := make(map[string]struct{}, len(services))
+	for i := range services {
+		srv := &services[i]
+		key := srv.ServiceId
+		if "" == key {
+			key = srv.ServiceID()
+		}
+		issues := scoped.ValidateService(srv)
+		if _, ok := ids[key]; ok {
+			issues = append(issues, Issue{Level: LevelError, Rule: RuleDuplicateDefine, Field: "serviceId",
+				Message: "duplicate service definition: " + key})
+		}
+		ids[key] = struct{}{}
+		collect(KindService, key, issues)
+	}
+	report.Valid = report.Errors == 0
+	return report
+}
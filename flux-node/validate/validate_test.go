@@ -0,0 +1,148 @@
+package validate
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestService(attrs ...flux.Attribute) flux.BackendService {
+	return flux.BackendService{
+		Interface: "com.foo.UserService", Method: "get",
+		EmbeddedAttributes: flux.EmbeddedAttributes{
+			Attributes: append([]flux.Attribute{{Name: flux.ServiceAttrTagRpcProto, Value: flux.ProtoDubbo}}, attrs...),
+		},
+	}
+}
+
+func rulesOf(issues Issues) []string {
+	rules := make([]string, 0, len(issues))
+	for _, i := range issues {
+		rules = append(rules, i.Rule)
+	}
+	return rules
+}
+
+func TestValidator_ValidateService(t *testing.T) {
+	cases := []struct {
+		name    string
+		service func() flux.BackendService
+		rules   []string
+	}{
+		{
+			name:    "valid",
+			service: func() flux.BackendService { return newTestService() },
+			rules:   []string{},
+		},
+		{
+			name: "unknown rpcproto",
+			service: func() flux.BackendService {
+				s := newTestService()
+				s.Attributes[0].Value = "THRIFT"
+				return s
+			},
+			rules: []string{RuleRpcProto},
+		},
+		{
+			name: "invalid rpctimeout",
+			service: func() flux.BackendService {
+				return newTestService(flux.Attribute{Name: flux.ServiceAttrTagRpcTimeout, Value: "10"})
+			},
+			rules: []string{RuleRpcTimeout},
+		},
+		{
+			name: "arguments",
+			service: func() flux.BackendService {
+				s := newTestService()
+				s.Arguments = []flux.Argument{
+					{Name: "id", Type: flux.ArgumentTypePrimitive, Class: "int", HttpName: "id", HttpScope: "query"},
+					{Name: "id", Type: flux.ArgumentTypePrimitive, Class: "java.lang.Integer", HttpName: "id", HttpScope: "QUERY"},
+					{Name: "name", Type: flux.ArgumentTypePrimitive, Class: "x.y.Unknown", HttpName: "name", HttpScope: "COOKIE"},
+					{Name: "user", Type: flux.ArgumentTypeComplex, Class: "x.y.User", Fields: []flux.Argument{
+						{Name: "age", Type: flux.ArgumentTypePrimitive, Class: "", HttpName: "age", HttpScope: "QUERY"},
+					}},
+				}
+				return s
+			},
+			rules: []string{RuleDuplicateArg, RuleArgumentClass, RuleHttpScope, RuleArgumentClass},
+		},
+	}
+	validator := NewValidatorWith()
+	for _, tc := range cases {
+		service := tc.service()
+		assert2.Equal(t, tc.rules, rulesOf(validator.ValidateService(&service)), tc.name)
+	}
+}
+
+func TestValidator_ValidateEndpoint(t *testing.T) {
+	assert := assert2.New(t)
+	validator := NewValidatorWith(WithServiceLookup(func(id string) bool {
+		return id == "auth"
+	}))
+	endpoint := flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/users/:id/orders/{orderId}",
+		Service:     newTestService(),
+		Permissions: []string{"auth", "missing", ""},
+	}
+	endpoint.Service.Arguments = []flux.Argument{
+		{Name: "id", Type: flux.ArgumentTypePrimitive, Class: "long", HttpName: "id", HttpScope: flux.ScopePath},
+		{Name: "orderId", Type: flux.ArgumentTypePrimitive, Class: "string", HttpName: "orderId", HttpScope: flux.ScopePath},
+		{Name: "shop", Type: flux.ArgumentTypePrimitive, Class: "string", HttpName: "shopId", HttpScope: flux.ScopePath},
+	}
+	issues := validator.ValidateEndpoint(&endpoint)
+	assert.Equal([]string{RulePermissionId, RulePermissionId, RulePathVariable}, rulesOf(issues))
+	assert.Equal(LevelWarning, issues[0].Level)
+	assert.Equal(LevelError, issues[1].Level)
+	assert.Equal("service.arguments[2]", issues[2].Field)
+	assert.True(issues.HasErrors())
+	assert.Error(issues.Errors())
+}
+
+func TestValidator_ValidateResources(t *testing.T) {
+	assert := assert2.New(t)
+	validator := NewValidatorWith(WithProtocols("CUSTOM"))
+	auth := newTestService()
+	auth.ServiceId = "auth"
+	auth.Attributes[0].Value = "CUSTOM"
+	endpoints := []flux.Endpoint{
+		{HttpMethod: "GET", HttpPattern: "/a", Service: newTestService(), Permissions: []string{"auth"}},
+		{HttpMethod: "get", HttpPattern: "/a", Service: newTestService()},
+	}
+	report := validator.ValidateResources(endpoints, []flux.BackendService{auth})
+	assert.False(report.Valid)
+	assert.Equal(1, report.Errors)
+	assert.Equal(0, report.Warnings)
+	assert.Equal(1, len(report.Definitions))
+	assert.Equal(KindEndpoint, report.Definitions[0].Kind)
+	assert.Equal("GET#/a#", report.Definitions[0].Key)
+	assert.Equal(RuleDuplicateDefine, report.Definitions[0].Issues[0].Rule)
+}
+
+func TestPathVariables(t *testing.T) {
+	cases := []struct {
+		pattern string
+		vars    []string
+	}{
+		{pattern: "/users", vars: []string{}},
+		{pattern: "/users/:id", vars: []string{"id"}},
+		{pattern: "/users/{id}/orders/:orderId", vars: []string{"id", "orderId"}},
+		{pattern: "/users/:/{}", vars: []string{}},
+	}
+	for _, tc := range cases {
+		assert2.Equal(t, tc.vars, PathVariables(tc.pattern), tc.pattern)
+	}
+}
+
+func TestValidator_ValidateEndpointTransform(t *testing.T) {
+	assert := assert2.New(t)
+	endpoint := flux.Endpoint{
+		HttpMethod: "GET", HttpPattern: "/users",
+		Service: newTestService(),
+		EmbeddedExtensions: flux.EmbeddedExtensions{Extensions: map[string]interface{}{
+			"transform": map[string]interface{}{"include": []string{"id"}, "exclude": []string{"password"}},
+		}},
+	}
+	issues := NewValidatorWith().ValidateEndpoint(&endpoint)
+	assert.Equal([]string{RuleTransform}, rulesOf(issues))
+	assert.Equal("extensions.transform", issues[0].Field)
+}
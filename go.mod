@@ -7,6 +7,8 @@ require (
 	github.com/apache/dubbo-go v1.5.1
 	github.com/apache/dubbo-go-hessian2 v1.7.0
 	github.com/dubbogo/go-zookeeper v1.0.1
+	github.com/golang/protobuf v1.3.2
+	github.com/gorilla/websocket v1.4.2
 	github.com/jinzhu/copier v0.0.0-20190924061706-b57f9002281a // indirect
 	github.com/json-iterator/go v1.1.9
 	github.com/labstack/echo/v4 v4.1.16